@@ -0,0 +1,54 @@
+package main
+
+// Computes PageRank over the link graph recorded by the crawler, and HostRank
+// over the same graph collapsed into hosts, and writes out both sets of scores.
+// The crawler reads them when it starts to decide which URLs to fetch first.
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+	"webcrawler/internal/pkg/linkgraph"
+)
+
+func main() {
+	graphDir := flag.String("graph", "internal/pkg/administrator/data/linkgraph", "directory of the link graph recorded by the crawler")
+	pagesPath := flag.String("pages", "internal/pkg/administrator/data/pagerank.tsv", "where to write the page scores, empty to skip PageRank")
+	hostsPath := flag.String("hosts", "internal/pkg/administrator/data/hostrank.tsv", "where to write the host scores, empty to skip HostRank")
+	damping := flag.Float64("damping", linkgraph.DefaultOptions().Damping, "chance of following a link rather than jumping to a random page")
+	iterations := flag.Int("iterations", linkgraph.DefaultOptions().MaxIterations, "most power iterations to run")
+	flag.Parse()
+
+	options := linkgraph.DefaultOptions()
+	options.Damping = *damping
+	options.MaxIterations = *iterations
+	if options.Damping <= 0 || options.Damping >= 1 {
+		log.Fatalf("Damping must be between 0 and 1, got %v", options.Damping)
+	}
+
+	graph, err := linkgraph.Load(*graphDir)
+	if err != nil {
+		log.Fatalf("Failed to load link graph: %v", err)
+	}
+	fmt.Printf("Loaded %d pages and %d links from %s\n", len(graph.Names), graph.Edges(), *graphDir)
+
+	if *pagesPath != "" {
+		rank(graph, options, *pagesPath, "PageRank")
+	}
+	if *hostsPath != "" {
+		hosts := graph.Hosts()
+		fmt.Printf("Collapsed into %d hosts and %d links between them\n", len(hosts.Names), hosts.Edges())
+		rank(hosts, options, *hostsPath, "HostRank")
+	}
+}
+
+// Ranks the nodes of a graph and writes their scores
+func rank(graph *linkgraph.Graph, options linkgraph.Options, path, name string) {
+	start := time.Now()
+	scores, iterations := linkgraph.Rank(graph, options)
+	if err := linkgraph.WriteScores(path, graph.Names, scores); err != nil {
+		log.Fatalf("Failed to write %s scores: %v", name, err)
+	}
+	fmt.Printf("%s: %d iterations in %v, scores written to %s\n", name, iterations, time.Since(start).Round(time.Millisecond), path)
+}
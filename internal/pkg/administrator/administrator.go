@@ -6,17 +6,30 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	//"encoding/json"
 	"webcrawler/internal/pkg/fetcher/fetcher"
 	workerPool "webcrawler/internal/pkg/fetcher/pool"
 	bloomfilter "webcrawler/internal/pkg/filter"
+	"webcrawler/internal/pkg/identity"
+	"webcrawler/internal/pkg/linkgraph"
+	"webcrawler/internal/pkg/policy"
+	"webcrawler/internal/pkg/proxy"
 	"webcrawler/internal/pkg/queue"
+	"webcrawler/internal/pkg/ratelimit"
+	"webcrawler/internal/pkg/redirects"
+	"webcrawler/internal/pkg/resolver"
+	"webcrawler/internal/pkg/retry"
+	"webcrawler/internal/pkg/robots"
+	"webcrawler/internal/pkg/types"
 	"webcrawler/internal/pkg/utils"
 )
 
@@ -27,6 +40,18 @@ const (
 	queueCapacity     = 10000
 	domainLimit       = 100 // Note: Limit is doubled for .org and .edu domains
 	maxSleepMs        = 100000
+	retryPumpInterval = 1 * time.Second
+	deadLetterPath    = "internal/pkg/administrator/data/dead_letters.tsv"
+	robotsCachePath   = "internal/pkg/administrator/data/robots_cache.json"
+	redirectsPath     = "internal/pkg/administrator/data/redirects.tsv"
+	rejectionsPath    = "internal/pkg/administrator/data/rejections.tsv"
+	linkGraphPath     = "internal/pkg/administrator/data/linkgraph"
+	pageRanksPath     = "internal/pkg/administrator/data/pagerank.tsv" // Written by cmd/linkrank
+	hostRanksPath     = "internal/pkg/administrator/data/hostrank.tsv"
+	maxGraphNodes     = 5000000 // URLs the link graph gives node IDs to
+	maxRedirects      = 1000000 // Permanent redirects remembered
+	maxCanonicals     = 100000  // Canonical URLs remembered until they are fetched
+	maxRedirectHops   = 5       // Redirects between hosts followed for one URL
 )
 
 type Administrator struct {
@@ -42,6 +67,34 @@ type Administrator struct {
 	fetcherPool   *workerPool.WorkerPool
 	domainVisits  map[string]int
 	domainMutex   sync.Mutex
+	fetchFailures map[types.ErrorCategory]int
+	failureMutex  sync.Mutex
+	retryPolicy   retry.Policy
+	retryQueue    *retry.Scheduler
+	retryAttempts map[string]int // URLs with a retry pending or in flight
+	retryMutex    sync.Mutex
+	deadLetters   *retry.DeadLetterFile
+	rejections    *policy.RejectionLog // Pages the content policy dropped
+	hostLimiter   *ratelimit.HostLimiter
+	ipLimiter     *ratelimit.IPLimiter
+	robots        *robots.Service
+	proxies       *proxy.Pool
+	resolver      *resolver.Resolver
+	redirects     *redirects.Store
+	linkGraph     *linkgraph.Store    // Links between crawled pages, for ranking
+	ranks         *linkgraph.Ranks    // Page and host scores that order the queue
+	hreflangs     []string            // Alternate languages to enqueue
+	assetTypes    map[string]struct{} // Types of asset to enqueue, see fetcher.EnvAssets
+	canonicals    map[string]struct{} // Canonical URLs enqueued in place of a duplicate
+	canonMutex    sync.Mutex
+	wireBytes     atomic.Int64 // Response bytes received, before decompression
+	decodedBytes  atomic.Int64 // Response bytes after decompression
+	fetches       atomic.Int64 // Responses received from the fetcher processes
+	dnsTime       atomic.Int64 // Nanoseconds the fetcher processes spent resolving hosts
+	dnsCached     atomic.Int64 // Fetches whose host was already resolved
+	connReused    atomic.Int64 // Fetches sent over an existing connection
+	http2Fetches  atomic.Int64 // Fetches answered over HTTP/2
+	noindexPages  atomic.Int64 // Pages fetched but not written out because of their robots directives
 }
 
 // Creates a new Administrator instance
@@ -53,6 +106,13 @@ func NewAdministrator(progressFilePath string) *Administrator {
 		panic(fmt.Sprintf("Failed to create queue: %v", err))
 	}
 
+	// Fetcher processes read the identity from the environment they inherit
+	crawlerIdentity, err := identity.FromEnvironment()
+	if err != nil {
+		panic(fmt.Sprintf("Invalid crawler identity: %v", err))
+	}
+	crawlerIdentity.Export()
+
 	err = fetcher.Init()
 	if err != nil {
 		panic(err)
@@ -70,15 +130,85 @@ func NewAdministrator(progressFilePath string) *Administrator {
 		panic(fmt.Sprintf("Failed to create bloom filter: %v", err))
 	}
 
+	rateLimits := ratelimit.DefaultConfig()
+
+	// Hosts are resolved here before dispatch, prefetched as they are queued,
+	// and the answers handed to the fetcher processes with each request
+	dnsResolver := resolver.NewResolver(resolver.DefaultConfig())
+	ipLimiter := ratelimit.NewIPLimiter(rateLimits.MaxConnectionsPerIP)
+	ipLimiter.SetLookup(dnsResolver.LookupHost)
+
+	// Robots.txt is fetched and enforced here, before URLs reach the fetcher processes
+	robotsConfig := robots.DefaultConfig()
+	robotsConfig.CachePath = robotsCachePath
+	robotsConfig.UserAgent = crawlerIdentity.UserAgent() // Never rotated, even when page requests are
+	robotsConfig.ProductToken = crawlerIdentity.ProductToken
+
+	// Robots.txt follows the same proxy routes as the pages it governs
+	proxyConfig, err := proxy.FromEnvironment()
+	if err != nil {
+		panic(fmt.Sprintf("Invalid proxy configuration: %v", err))
+	}
+	robotsTransport := http.DefaultTransport.(*http.Transport).Clone()
+	robotsTransport.DialContext = dnsResolver.DialContext
+	proxies, err := proxy.NewPool(proxyConfig, robotsTransport)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to create proxy pool: %v", err))
+	}
+	proxies.Start()
+	robotsConfig.Client = &http.Client{Timeout: 10 * time.Second, Transport: proxies}
+	robotsService, err := robots.NewService(robotsConfig)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to create robots.txt service: %v", err))
+	}
+
+	// Every fetched page's links are recorded for ranking by cmd/linkrank
+	linkGraph, err := linkgraph.Open(linkGraphPath, maxGraphNodes)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to open link graph: %v", err))
+	}
+
+	// Scores from the last ranking run, if any, put important URLs first
+	ranks, err := linkgraph.LoadRanks(pageRanksPath, hostRanksPath)
+	if err != nil {
+		log.Printf("Failed to load link scores, the queue is first in first out: %v", err)
+	}
+
+	// Validated by fetcher.Init above
+	assetTypes, _ := fetcher.AssetTypesFromEnvironment()
+
+	// Links to URLs that moved permanently are rewritten before they are queued
+	redirectStore, err := redirects.NewStore(redirectsPath, maxRedirects)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load redirects: %v", err))
+	}
+
 	return &Administrator{
-		context:      context,
-		cancel:       cancel,
-		urlChan:      make(chan string, 50), // TODO: Investigate buffer size requirements
-		progressFile: progressFilePath,
-		bloomFilter:  filter,
-		urlQueue:     q,
-		fetcherPool:  fetcherWorkerPool,
-		domainVisits: make(map[string]int),
+		context:       context,
+		cancel:        cancel,
+		urlChan:       make(chan string, 50), // TODO: Investigate buffer size requirements
+		progressFile:  progressFilePath,
+		bloomFilter:   filter,
+		urlQueue:      q,
+		fetcherPool:   fetcherWorkerPool,
+		domainVisits:  make(map[string]int),
+		fetchFailures: make(map[types.ErrorCategory]int),
+		retryPolicy:   retry.DefaultPolicy(),
+		retryQueue:    retry.NewScheduler(),
+		retryAttempts: make(map[string]int),
+		deadLetters:   retry.NewDeadLetterFile(deadLetterPath),
+		rejections:    policy.NewRejectionLog(rejectionsPath),
+		hostLimiter:   ratelimit.NewHostLimiter(rateLimits),
+		ipLimiter:     ipLimiter,
+		robots:        robotsService,
+		proxies:       proxies,
+		resolver:      dnsResolver,
+		redirects:     redirectStore,
+		linkGraph:     linkGraph,
+		ranks:         ranks,
+		hreflangs:     hreflangsFromEnvironment(),
+		assetTypes:    assetTypes,
+		canonicals:    make(map[string]struct{}),
 	}
 }
 
@@ -98,6 +228,10 @@ func (admin *Administrator) Run() {
 		go admin.queueConsumer(i)
 	}
 
+	// Moves URLs whose retry backoff has elapsed back into the queue
+	admin.waitGroup.Add(1)
+	go admin.retryPump()
+
 	// Continuous loop
 	for {
 		select {
@@ -152,6 +286,7 @@ func (admin *Administrator) readerWorker(id int) {
 			if !ok {
 				return // channel closed
 			}
+			url = admin.redirects.Resolve(url)
 			if admin.bloomFilter.IsVisited(url) {
 				continue // URL already visited
 			}
@@ -161,11 +296,12 @@ func (admin *Administrator) readerWorker(id int) {
 				if retryTime > 10 {
 					break
 				}
-				err := admin.urlQueue.Insert(url)
+				err := admin.enqueue(url)
 				if err == nil { // Successfully inserted
 					if domain, err := utils.GetDomainFromURL(url); err == nil {
 						admin.incrementDomainVisitCount(domain)
 					}
+					admin.prefetchHost(url)
 					break
 				} else {
 					// Queue full, wait a bit and retry
@@ -201,7 +337,9 @@ func (admin *Administrator) queueConsumer(id int) {
 		}
 
 		if admin.bloomFilter.IsVisited(url) {
-			continue
+			if !admin.isRetryPending(url) {
+				continue
+			}
 		} else {
 			if domain, err := utils.GetDomainFromURL(url); err == nil {
 				if fullUrl, err := utils.BuildFullUrl(url); err == nil {
@@ -216,21 +354,45 @@ func (admin *Administrator) queueConsumer(id int) {
 			}
 		}
 
-		context, cancel := context.WithTimeout(admin.context, 30*time.Second)
-		response, err := admin.fetcherPool.FetchURL(context, url)
-		cancel()
+		response, err := admin.fetchFollowingRedirects(url)
 		if err != nil {
 			log.Printf("[queueConsumer %d] error in call to fetchURL %s: %v\n", id, url, err)
+			admin.handleFetchFailure(id, url, poolErrorResult(url, err))
 			continue
 		} else {
-			if response.FetchError == "" {
-				fmt.Printf("queueConsumer Worker %d fetched URL: [%s] | Title: [%s] \n", id, response.PageData.URL, response.PageData.Title)
-				jsonData, err := json.Marshal(response.PageData)
-				if err != nil {
-					log.Printf("Error marshalling page data: %v", err)
+			admin.markRedirectsVisited(response.FetchResult)
+			if response.FetchResult.OK() {
+				admin.clearRetry(url)
+				if admin.isNonCanonical(response.PageData) {
+					result := response.FetchResult
+					result.ErrorCategory = types.ErrorFiltered
+					result.Error = "duplicate of canonical URL " + response.PageData.CanonicalURL
+					admin.handleFetchFailure(id, url, result)
+					continue
 				}
-				fmt.Printf("Page Data: %s\n", jsonData)
-				admin.enqueueExtractedURLs(url, response.PageData.InternalLinks, response.PageData.ExternalLinks)
+				pageData := response.PageData
+				internalLinks := admin.resolveRedirects(followableLinks(pageData, pageData.InternalLinks))
+				externalLinks := admin.resolveRedirects(followableLinks(pageData, pageData.ExternalLinks))
+				if err := admin.linkGraph.AddPage(pageData.URL, slices.Concat(internalLinks, externalLinks)); err != nil {
+					log.Printf("Error recording links of %s: %v", pageData.URL, err)
+				}
+				if applyOutputDirectives(&pageData, time.Now()) {
+					fmt.Printf("queueConsumer Worker %d fetched URL: [%s] | Title: [%s] \n", id, pageData.URL, pageData.Title)
+					jsonData, err := json.Marshal(pageData)
+					if err != nil {
+						log.Printf("Error marshalling page data: %v", err)
+					}
+					fmt.Printf("Page Data: %s\n", jsonData)
+				} else {
+					admin.noindexPages.Add(1)
+				}
+				admin.enqueueExtractedURLs(pageData.URL, internalLinks, externalLinks)
+				if !pageData.Robots.NoFollow && !pageData.PolicyNoFollow {
+					admin.enqueueAlternates(pageData.Alternates)
+					admin.enqueueAssets(pageData.Assets)
+				}
+			} else {
+				admin.handleFetchFailure(id, url, response.FetchResult)
 			}
 		}
 	}
@@ -277,12 +439,20 @@ func (admin *Administrator) updateProgress(scanner *bufio.Scanner) error {
 
 // Shuts down the administrator
 func (admin *Administrator) ShutDown() {
-	fmt.Printf("Shutting down administrator. Current Crawler Status: {\nQueue Usage: %v\n, Domain Visits: %v\n, Line Number: %v\n, Bloom Filter: %v\n}\n\n\n", admin.getQueueUsage(), admin.domainVisits, admin.lineNumber, admin.bloomFilter)
+	fmt.Printf("Shutting down administrator. Current Crawler Status: {\nQueue Usage: %v\n, Domain Visits: %v\n, Fetch Failures: %v\n, Transfer: %v\n, DNS: %v\n, Connections: %v\n, Redirects Remembered: %v\n, Link Graph Nodes: %v\n, Noindex Pages: %v\n, Line Number: %v\n, Bloom Filter: %v\n}\n\n\n", admin.getQueueUsage(), admin.domainVisits, admin.fetchFailures, admin.transferSummary(), admin.dnsSummary(), admin.connectionSummary(), admin.redirects.Len(), admin.linkGraph.Nodes(), admin.noindexPages.Load(), admin.lineNumber, admin.bloomFilter)
 	fmt.Printf("Shutting down administrator...\n")
 	admin.cancel()
 	admin.waitGroup.Wait()
 	if admin.fetcherPool != nil {
 		admin.fetcherPool.Shutdown()
 	}
+	if err := admin.linkGraph.Close(); err != nil {
+		log.Printf("Error closing link graph: %v", err)
+	}
+	if err := admin.robots.Close(); err != nil {
+		log.Printf("Error saving robots cache: %v", err)
+	}
+	admin.proxies.Close()
+	admin.resolver.Close()
 	fmt.Println("\n\n\nShutdown complete.")
 }
@@ -0,0 +1,179 @@
+package administrator
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"webcrawler/internal/pkg/robots"
+	"webcrawler/internal/pkg/types"
+	"webcrawler/internal/pkg/utils"
+)
+
+// Comma separated hreflang values whose alternates are enqueued, e.g.
+// "en,x-default". A bare language such as "en" also matches "en-gb".
+const EnvHreflang = "WEBCRAWLER_HREFLANG"
+
+// Reads the hreflang values to enqueue from the environment
+func hreflangsFromEnvironment() []string {
+	var languages []string
+	for _, language := range strings.Split(os.Getenv(EnvHreflang), ",") {
+		if language = strings.ToLower(strings.TrimSpace(language)); language != "" {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// Reports whether an hreflang value is one of the selected languages
+func (admin *Administrator) wantsHreflang(hreflang string) bool {
+	for _, language := range admin.hreflangs {
+		if hreflang == language || strings.HasPrefix(hreflang, language+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether a page names another URL as its canonical version, in which
+// case it is skipped as a duplicate and the canonical URL is enqueued instead.
+// A page enqueued as another's canonical is always kept, so pages naming each
+// other cannot both be skipped. Canonicals that are never fetched would stay
+// remembered for the whole crawl, so at most maxCanonicals are; beyond that a
+// canonical is still enqueued, just without the guarantee.
+func (admin *Administrator) isNonCanonical(pageData types.PageData) bool {
+	canonical := pageData.CanonicalURL
+	if !strings.HasPrefix(canonical, "http") || sameDocument(canonical, pageData.URL) || sameDocument(canonical, pageData.RequestedURL) {
+		return false
+	}
+
+	admin.canonMutex.Lock()
+	_, chosen := admin.canonicals[pageData.RequestedURL]
+	delete(admin.canonicals, pageData.RequestedURL)
+	admin.canonMutex.Unlock()
+	if chosen {
+		return false
+	}
+
+	if !admin.bloomFilter.IsVisited(canonical) && admin.enqueueDiscoveredURL(canonical) {
+		admin.canonMutex.Lock()
+		if len(admin.canonicals) < maxCanonicals {
+			admin.canonicals[canonical] = struct{}{}
+		}
+		admin.canonMutex.Unlock()
+	}
+	return true
+}
+
+// Enqueues the alternates of a page in the selected languages
+func (admin *Administrator) enqueueAlternates(alternates []types.Alternate) {
+	for _, alternate := range alternates {
+		if admin.wantsHreflang(alternate.Language) {
+			admin.enqueueDiscoveredURL(admin.redirects.Resolve(alternate.URL))
+		}
+	}
+}
+
+// Enqueues the assets of a page whose types were selected for fetching
+func (admin *Administrator) enqueueAssets(assets []types.Asset) {
+	if len(admin.assetTypes) == 0 {
+		return
+	}
+	for _, asset := range assets {
+		if _, wanted := admin.assetTypes[asset.Type]; wanted {
+			admin.enqueueDiscoveredURL(admin.redirects.Resolve(asset.URL))
+		}
+	}
+}
+
+// Enqueues a URL found outside the page's links unless it was already seen
+// or its domain has used up its budget
+func (admin *Administrator) enqueueDiscoveredURL(url string) bool {
+	if admin.bloomFilter.IsVisited(url) {
+		return false
+	}
+	domain, err := utils.GetDomainFromURL(url)
+	if err != nil || admin.getDomainVisitCount(domain) >= domainVisitLimit(domain) {
+		return false
+	}
+	if err := admin.enqueue(url); err != nil {
+		log.Printf("Failed to enqueue %s: %v", url, err)
+		return false
+	}
+	admin.incrementDomainVisitCount(domain)
+	admin.prefetchHost(url)
+	return true
+}
+
+// Drops the links a page asks crawlers not to follow, either all of them with
+// a nofollow directive or single links marked nofollow, ugc or sponsored. A
+// URL is kept when any of the links to it may be followed. Pages matched by a
+// nofollow rule of the content policy have none followed.
+func followableLinks(pageData types.PageData, links []string) []string {
+	if pageData.Robots.NoFollow || pageData.PolicyNoFollow {
+		return nil
+	}
+	followed := make(map[string]bool) // Whether any link to the URL may be followed
+	for _, outlink := range pageData.Outlinks {
+		followed[outlink.URL] = followed[outlink.URL] || !outlink.NoFollow()
+	}
+	followable := make([]string, 0, len(links))
+	for _, link := range links {
+		if allowed, linked := followed[link]; allowed || !linked {
+			followable = append(followable, link)
+		}
+	}
+	return followable
+}
+
+// Trims what is written out for a page to what its robots directives allow.
+// Returns false if the page must not be written out at all.
+func applyOutputDirectives(pageData *types.PageData, now time.Time) bool {
+	directives := pageData.Robots
+	if robots.Unavailable(directives, now) {
+		return false
+	}
+	if directives.NoArchive || directives.NoSnippet {
+		pageData.VisibleText = "" // The page's text is effectively a cached copy
+		pageData.MainText = ""
+	}
+	if directives.NoSnippet {
+		pageData.MetaDescription = ""
+	} else if directives.MaxSnippet != nil && *directives.MaxSnippet >= 0 {
+		pageData.MetaDescription = truncateRunes(pageData.MetaDescription, *directives.MaxSnippet)
+		pageData.VisibleText = truncateRunes(pageData.VisibleText, *directives.MaxSnippet)
+		pageData.MainText = truncateRunes(pageData.MainText, *directives.MaxSnippet)
+	}
+	return true
+}
+
+// Cuts text down to at most limit characters
+func truncateRunes(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit])
+}
+
+// Reports whether two URLs name the same document, ignoring fragments, the
+// host's case and an empty path
+func sameDocument(first, second string) bool {
+	firstParsed, err := url.Parse(first)
+	if err != nil {
+		return false
+	}
+	secondParsed, err := url.Parse(second)
+	if err != nil {
+		return false
+	}
+	for _, parsed := range []*url.URL{firstParsed, secondParsed} {
+		parsed.Fragment = ""
+		parsed.Host = strings.ToLower(parsed.Host)
+		if parsed.Path == "" {
+			parsed.Path = "/"
+		}
+	}
+	return firstParsed.String() == secondParsed.String()
+}
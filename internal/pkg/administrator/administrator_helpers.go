@@ -1,9 +1,18 @@
 package administrator
 
 import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
 	"math"
-    "strings"
+    "net/http"
+    "strconv"
 	"time"
+    workerPool "webcrawler/internal/pkg/fetcher/pool"
+    "webcrawler/internal/pkg/policy"
+    "webcrawler/internal/pkg/robots"
+    "webcrawler/internal/pkg/types"
     "webcrawler/internal/pkg/utils"
 )
 
@@ -32,6 +41,189 @@ func (admin *Administrator) getDomainVisitCount(domain string) int {
     return admin.domainVisits[domain]
 }
 
+// Uses up a domain's visit budget so no more of its URLs are enqueued
+func (admin *Administrator) exhaustDomain(domain string) {
+    admin.domainMutex.Lock()
+    admin.domainVisits[domain] = max(admin.domainVisits[domain], domainLimit * 2)
+    admin.domainMutex.Unlock()
+}
+
+// Checks a URL against its host's robots.txt, returning the host's Crawl-delay,
+// or the result to report if robots.txt does not let the URL be fetched
+func (admin *Administrator) checkRobots(url, fullURL string) (time.Duration, *types.FetchResult, error) {
+    crawlDelay, err := admin.robots.Check(admin.context, fullURL)
+    switch {
+    case errors.Is(err, robots.ErrCrawlingDisallowed):
+        return 0, &types.FetchResult{RequestedURL: url, FinalURL: fullURL, ErrorCategory: types.ErrorRobots, Error: err.Error()}, nil
+    case errors.Is(err, robots.ErrRobotsUnavailable):
+        // The whole site is off limits until robots.txt is fetched again, so
+        // retry no sooner than that. Check returns how long that is.
+        seconds := int((crawlDelay + time.Second - 1) / time.Second)
+        return 0, &types.FetchResult{
+            RequestedURL:  url,
+            FinalURL:      fullURL,
+            Header:        http.Header{"Retry-After": []string{strconv.Itoa(seconds)}},
+            ErrorCategory: types.ErrorRobotsUnavailable,
+            Error:         err.Error(),
+        }, nil
+    case err != nil:
+        return 0, nil, err
+    }
+    return crawlDelay, nil, nil
+}
+
+// Fetches a URL through the worker pool once robots.txt, the host's adaptive
+// delay, no shorter than its Crawl-delay, and the per-IP connection ceiling
+// allow it, then feeds the outcome back
+func (admin *Administrator) fetchWithLimits(url string) (workerPool.WorkerResponse, error) {
+    domain, err := utils.GetDomainFromURL(url)
+    if err != nil {
+        return workerPool.WorkerResponse{}, err
+    }
+    hostname, _ := utils.GetHostnameFromURL(url)
+    fullURL, err := utils.BuildFullUrl(url)
+    if err != nil {
+        return workerPool.WorkerResponse{}, err
+    }
+
+    crawlDelay, refused, err := admin.checkRobots(url, fullURL)
+    if err != nil {
+        return workerPool.WorkerResponse{}, err
+    }
+    if refused != nil {
+        return workerPool.WorkerResponse{FetchResult: *refused}, nil
+    }
+
+    // The Crawl-delay is a floor of the adaptive delay, so the larger of the two applies
+    admin.hostLimiter.SetFloor(domain, crawlDelay)
+    if err := admin.hostLimiter.Wait(admin.context, domain); err != nil {
+        return workerPool.WorkerResponse{}, err
+    }
+    release, err := admin.ipLimiter.Acquire(admin.context, hostname)
+    if err != nil {
+        return workerPool.WorkerResponse{}, err
+    }
+    defer release()
+
+    // The IP limiter has just resolved the host, so this is answered from the cache
+    var addresses []string
+    var expires time.Time
+    if admin.proxies.IsDirect(hostname) {
+        if resolved, err := admin.resolver.Resolve(admin.context, hostname); err == nil {
+            for _, ip := range resolved.IPs {
+                addresses = append(addresses, ip.String())
+            }
+            expires = resolved.Expires
+        }
+    }
+
+    context, cancel := context.WithTimeout(admin.context, 30 * time.Second)
+    defer cancel()
+    response, err := admin.fetcherPool.FetchURLWithAddresses(context, url, addresses, expires)
+    if err != nil {
+        admin.hostLimiter.Observe(domain, poolErrorResult(url, err))
+    } else {
+        admin.hostLimiter.Observe(domain, response.FetchResult)
+        admin.wireBytes.Add(response.FetchResult.WireBytes)
+        admin.decodedBytes.Add(response.FetchResult.BytesRead)
+        admin.fetches.Add(1)
+        admin.dnsTime.Add(int64(response.FetchResult.DNSDuration))
+        if response.FetchResult.DNSCached {
+            admin.dnsCached.Add(1)
+        }
+        if response.FetchResult.ConnReused {
+            admin.connReused.Add(1)
+        }
+        if response.FetchResult.Protocol == "HTTP/2.0" {
+            admin.http2Fetches.Add(1)
+        }
+    }
+    return response, err
+}
+
+// Summarises how well connections to hosts are being reused
+func (admin *Administrator) connectionSummary() string {
+    fetches := admin.fetches.Load()
+    reuseRate, http2Rate := 0.0, 0.0
+    if fetches > 0 {
+        reuseRate = float64(admin.connReused.Load()) / float64(fetches) * 100
+        http2Rate = float64(admin.http2Fetches.Load()) / float64(fetches) * 100
+    }
+    affinityRate := 0.0
+    if admin.fetcherPool != nil {
+        affinityRate = admin.fetcherPool.AffinityRate() * 100
+    }
+    return fmt.Sprintf("%.1f%% of %d fetches reused a connection, %.1f%% over HTTP/2, %.1f%% sent to the host's own worker",
+        reuseRate, fetches, http2Rate, affinityRate)
+}
+
+// Resolves a queued URL's host in the background so it is cached by the time the URL is fetched
+func (admin *Administrator) prefetchHost(url string) {
+    if hostname, err := utils.GetHostnameFromURL(url); err == nil && admin.proxies.IsDirect(hostname) {
+        admin.resolver.Prefetch(hostname)
+    }
+}
+
+// Summarises the administrator's resolver and the DNS time spent by the fetcher processes
+func (admin *Administrator) dnsSummary() string {
+    fetches := admin.fetches.Load()
+    average := time.Duration(0)
+    if fetches > 0 {
+        average = time.Duration(admin.dnsTime.Load() / fetches)
+    }
+    return fmt.Sprintf("resolver: %v; fetchers: %d of %d hosts already resolved, %v average DNS time per fetch",
+        admin.resolver.Stats(), admin.dnsCached.Load(), fetches, average.Round(time.Microsecond))
+}
+
+// Summarises bytes received on the wire against bytes after decompression
+func (admin *Administrator) transferSummary() string {
+    wire, decoded := admin.wireBytes.Load(), admin.decodedBytes.Load()
+    ratio := 0.0
+    if wire > 0 {
+        ratio = float64(decoded) / float64(wire)
+    }
+    return fmt.Sprintf("%d bytes on wire, %d bytes decoded (%.2fx)", wire, decoded, ratio)
+}
+
+// Records a failed fetch and reacts according to its category
+func (admin *Administrator) handleFetchFailure(id int, url string, result types.FetchResult) {
+    admin.failureMutex.Lock()
+    admin.fetchFailures[result.ErrorCategory]++
+    admin.failureMutex.Unlock()
+
+    if admin.scheduleRetry(id, url, result) {
+        return
+    }
+
+    switch result.ErrorCategory {
+    case types.ErrorRobots:
+        // Expected outcome, nothing to do
+    case types.ErrorFiltered:
+        // Pages dropped by the content policy are logged with the rule that dropped them
+        if result.PolicyRule != "" {
+            rejection := policy.Rejection{URL: url, Rule: result.PolicyRule, RejectedAt: time.Now()}
+            if err := admin.rejections.Write(rejection); err != nil {
+                log.Printf("Error writing rejection for %s: %v", url, err)
+            }
+        }
+    case types.ErrorDNS:
+        // The host does not resolve, so stop queueing anything else from it
+        if domain, err := utils.GetDomainFromURL(url); err == nil {
+            admin.exhaustDomain(domain)
+        }
+        log.Printf("[queueConsumer %d] DNS failure for %s, skipping domain: %s", id, url, result.Error)
+    case types.ErrorHTTP4xx, types.ErrorHTTP5xx:
+        log.Printf("[queueConsumer %d] HTTP %d for %s", id, result.StatusCode, url)
+    default:
+        log.Printf("[queueConsumer %d] %s error fetching %s: %s", id, result.ErrorCategory, url, result.Error)
+    }
+}
+
+// Inserts a URL into the queue, ahead of URLs whose page or host was ranked lower
+func (admin *Administrator) enqueue(url string) error {
+    return admin.urlQueue.InsertWithPriority(url, admin.ranks.Priority(url))
+}
+
 // Gets a decimal representation of how full the queue is from 0 to 1
 func (admin *Administrator) getQueueUsage() float64 {
     return float64(admin.urlQueue.Length()) / float64(queueCapacity)
@@ -65,9 +257,7 @@ func (admin *Administrator) enqueueExtractedURLs(sourceURL    string,
     visitLimit := domainLimit
     
     // Double the limit for .org, .edu or .ac.uk domains
-    if domainParseErr == nil && (strings.HasSuffix(currentDomain, ".org") || 
-                                 strings.HasSuffix(currentDomain, ".edu") || 
-                                 strings.HasSuffix(currentDomain, ".ac.uk")) {                   
+    if domainParseErr == nil && domainVisitLimit(currentDomain) > domainLimit {
         enqueueLimit = enqueueLimit * 2 
         visitLimit   = domainLimit  * 2
     }
@@ -88,9 +278,10 @@ func (admin *Administrator) enqueueExtractedURLs(sourceURL    string,
             }
 
             if internalIdx < len(internalURLs) {
-                err := admin.urlQueue.Insert(internalURLs[internalIdx])
+                err := admin.enqueue(internalURLs[internalIdx])
                 if err == nil { // Success, increment domain visit count and break out of the loop.
                     admin.incrementDomainVisitCount(currentDomain)
+                    admin.prefetchHost(internalURLs[internalIdx])
                     totalLinksEnqueued++
                 }
                 internalIdx++
@@ -107,9 +298,10 @@ func (admin *Administrator) enqueueExtractedURLs(sourceURL    string,
         if externalIdx < len(externalURLs) {
             domain, err := utils.GetDomainFromURL(externalURLs[externalIdx])
             if err == nil && admin.getDomainVisitCount(domain) < visitLimit {
-                err := admin.urlQueue.Insert(externalURLs[externalIdx])
+                err := admin.enqueue(externalURLs[externalIdx])
                 if err == nil {
                     admin.incrementDomainVisitCount(domain)
+                    admin.prefetchHost(externalURLs[externalIdx])
                     totalLinksEnqueued++
                 }
             }
@@ -0,0 +1,109 @@
+package administrator
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	workerPool "webcrawler/internal/pkg/fetcher/pool"
+	"webcrawler/internal/pkg/types"
+	"webcrawler/internal/pkg/utils"
+)
+
+// Gets how many URLs may be enqueued for a domain. The limit is doubled for
+// .org, .edu and .ac.uk domains.
+func domainVisitLimit(domain string) int {
+	if strings.HasSuffix(domain, ".org") || strings.HasSuffix(domain, ".edu") || strings.HasSuffix(domain, ".ac.uk") {
+		return domainLimit * 2
+	}
+	return domainLimit
+}
+
+// Fetches a URL, following the redirects to other hosts and meta refreshes
+// that the fetcher hands back. Each new host is checked against the bloom filter and its domain
+// budget here, and against robots.txt by fetchWithLimits, before it is
+// requested. The returned result carries the whole chain.
+func (admin *Administrator) fetchFollowingRedirects(url string) (workerPool.WorkerResponse, error) {
+	response, err := admin.fetchWithLimits(url)
+	var chain []types.Redirect
+	seen := map[string]bool{}
+	for hops := 0; err == nil && response.FetchResult.ErrorCategory == types.ErrorPendingRedirect; hops++ {
+		chain = append(chain, response.FetchResult.RedirectChain...)
+		admin.rememberRedirects(response.FetchResult.RedirectChain)
+
+		target := response.FetchResult.RedirectTarget
+		if hops >= maxRedirectHops || seen[target] {
+			return refusedRedirect(url, target, chain, types.ErrorRedirect, "too many redirects between hosts"), nil
+		}
+		seen[target] = true
+
+		// A retried URL redirects to a target it has already marked as visited
+		if admin.bloomFilter.IsVisited(target) && !admin.isRetryPending(url) {
+			return refusedRedirect(url, target, chain, types.ErrorFiltered, "redirect target already crawled"), nil
+		}
+		domain, domainErr := utils.GetDomainFromURL(target)
+		if domainErr != nil {
+			return refusedRedirect(url, target, chain, types.ErrorRedirect, fmt.Sprintf("invalid redirect target: %v", domainErr)), nil
+		}
+		if admin.getDomainVisitCount(domain) >= domainVisitLimit(domain) {
+			return refusedRedirect(url, target, chain, types.ErrorFiltered, "redirect target's domain budget exhausted"), nil
+		}
+		admin.bloomFilter.MarkVisited(target)
+		admin.incrementDomainVisitCount(domain)
+
+		response, err = admin.fetchWithLimits(target)
+	}
+	if err != nil {
+		return response, err
+	}
+	admin.rememberRedirects(response.FetchResult.RedirectChain)
+	if len(chain) == 0 {
+		return response, nil
+	}
+
+	chain = append(chain, response.FetchResult.RedirectChain...)
+	response.FetchResult.RequestedURL = url
+	response.FetchResult.RedirectChain = chain
+	if response.FetchResult.OK() {
+		response.PageData.RequestedURL = chain[0].URL
+		response.PageData.RedirectChain = chain
+	}
+	return response, nil
+}
+
+// Saves the permanent hops of a redirect chain so links to the old URLs are rewritten
+func (admin *Administrator) rememberRedirects(chain []types.Redirect) {
+	if err := admin.redirects.RememberChain(chain); err != nil {
+		log.Printf("Error saving redirects: %v", err)
+	}
+}
+
+// Rewrites links to URLs known to redirect permanently
+func (admin *Administrator) resolveRedirects(urls []string) []string {
+	for i, url := range urls {
+		urls[i] = admin.redirects.Resolve(url)
+	}
+	return urls
+}
+
+// Marks where a fetch ended up, and every URL it passed through, as visited
+func (admin *Administrator) markRedirectsVisited(result types.FetchResult) {
+	for _, hop := range result.RedirectChain {
+		admin.bloomFilter.MarkVisited(hop.URL)
+	}
+	if result.FinalURL != "" {
+		admin.bloomFilter.MarkVisited(result.FinalURL)
+	}
+}
+
+// Builds the result for a redirect the administrator would not follow
+func refusedRedirect(url string, target string, chain []types.Redirect, category types.ErrorCategory, reason string) workerPool.WorkerResponse {
+	result := types.FetchResult{
+		RequestedURL:   url,
+		FinalURL:       target,
+		RedirectChain:  chain,
+		RedirectTarget: target,
+		ErrorCategory:  category,
+		Error:          fmt.Sprintf("%s: %s", reason, target),
+	}
+	return workerPool.WorkerResponse{FetchResult: result}
+}
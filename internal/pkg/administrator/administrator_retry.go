@@ -0,0 +1,108 @@
+package administrator
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"webcrawler/internal/pkg/retry"
+	"webcrawler/internal/pkg/types"
+)
+
+// Reports whether a URL is being retried, so the bloom filter check is skipped for it
+func (admin *Administrator) isRetryPending(url string) bool {
+	admin.retryMutex.Lock()
+	defer admin.retryMutex.Unlock()
+	_, pending := admin.retryAttempts[url]
+	return pending
+}
+
+// Forgets any retry state for a URL
+func (admin *Administrator) clearRetry(url string) {
+	admin.retryMutex.Lock()
+	delete(admin.retryAttempts, url)
+	admin.retryMutex.Unlock()
+}
+
+// Schedules a retry for a transient failure, or dead-letters the URL once it
+// has used up its attempts. Returns false if the failure is not retryable.
+func (admin *Administrator) scheduleRetry(id int, url string, result types.FetchResult) bool {
+	retryable, retryAfter := retry.Classify(result)
+	if !retryable {
+		admin.clearRetry(url)
+		return false
+	}
+
+	admin.retryMutex.Lock()
+	attempts := admin.retryAttempts[url] + 1
+	if attempts >= admin.retryPolicy.MaxAttempts {
+		delete(admin.retryAttempts, url)
+		admin.retryMutex.Unlock()
+
+		log.Printf("[queueConsumer %d] giving up on %s after %d attempts: %s", id, url, attempts, result.Error)
+		letter := retry.DeadLetter{
+			URL:        url,
+			Category:   result.ErrorCategory,
+			StatusCode: result.StatusCode,
+			Attempts:   attempts,
+			FailedAt:   time.Now(),
+			Error:      result.Error,
+		}
+		if err := admin.deadLetters.Write(letter); err != nil {
+			log.Printf("Error writing dead letter for %s: %v", url, err)
+		}
+		return true
+	}
+	admin.retryAttempts[url] = attempts
+	admin.retryMutex.Unlock()
+
+	delay := admin.retryPolicy.Backoff(attempts, retryAfter)
+	admin.retryQueue.Schedule(url, attempts + 1, delay)
+	log.Printf("[queueConsumer %d] %s error for %s, retry %d in %v", id, result.ErrorCategory, url, attempts, delay.Round(time.Second))
+	return true
+}
+
+// Periodically moves URLs whose backoff has elapsed back into the queue
+func (admin *Administrator) retryPump() {
+	defer admin.waitGroup.Done()
+	ticker := time.NewTicker(retryPumpInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-admin.context.Done():
+			return
+		case now := <-ticker.C:
+			for _, entry := range admin.retryQueue.Ready(now) {
+				if err := admin.enqueue(entry.URL); err != nil {
+					// Queue full, try again shortly without counting an attempt
+					admin.retryQueue.Schedule(entry.URL, entry.Attempt, 5 * retryPumpInterval)
+				}
+			}
+		}
+	}
+}
+
+// Re-injects every dead-lettered URL with a fresh set of attempts
+func (admin *Administrator) ReinjectDeadLetters() (int, error) {
+	letters, err := admin.deadLetters.Drain()
+	if err != nil {
+		return 0, err
+	}
+	for _, letter := range letters {
+		admin.retryMutex.Lock()
+		admin.retryAttempts[letter.URL] = 0
+		admin.retryMutex.Unlock()
+		admin.retryQueue.Schedule(letter.URL, 1, 0)
+	}
+	return len(letters), nil
+}
+
+// Builds a fetch result for a request that failed inside the worker pool
+func poolErrorResult(url string, err error) types.FetchResult {
+	category := types.ErrorUnknown
+	if errors.Is(err, context.DeadlineExceeded) {
+		category = types.ErrorTimeout
+	}
+	return types.FetchResult{RequestedURL: url, ErrorCategory: category, Error: err.Error()}
+}
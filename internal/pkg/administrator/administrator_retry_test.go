@@ -0,0 +1,84 @@
+package administrator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+	"webcrawler/internal/pkg/retry"
+	"webcrawler/internal/pkg/robots"
+	"webcrawler/internal/pkg/types"
+)
+
+// TestRobotsUnavailableRetriedAfterRecovery tests that URLs of a host whose
+// robots.txt is failing are retried once robots.txt is fetched again, rather
+// than used up against the cached failure and dead-lettered.
+func TestRobotsUnavailableRetriedAfterRecovery(t *testing.T) {
+	var robotsFetches atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if robotsFetches.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("User-agent: *\nDisallow: /private"))
+	}))
+	defer testServer.Close()
+
+	robotsConfig := robots.DefaultConfig()
+	robotsConfig.UnavailableRetry = time.Second
+	robotsService, err := robots.NewService(robotsConfig)
+	if err != nil {
+		t.Fatalf("Failed to create robots.txt service: %v", err)
+	}
+	admin := &Administrator{
+		context: context.Background(),
+		robots:  robotsService,
+		retryPolicy: retry.Policy{
+			MaxAttempts:   4,
+			BaseDelay:     10 * time.Millisecond,
+			MaxDelay:      time.Second,
+			MaxRetryAfter: time.Minute,
+		},
+		retryQueue:    retry.NewScheduler(),
+		retryAttempts: make(map[string]int),
+		deadLetters:   retry.NewDeadLetterFile(filepath.Join(t.TempDir(), "dead_letters.tsv")),
+	}
+
+	url := testServer.URL + "/page"
+	start := time.Now()
+	deadline := start.Add(10 * time.Second)
+	for {
+		_, refused, err := admin.checkRobots(url, url)
+		if err != nil {
+			t.Fatalf("Unexpected error checking robots.txt: %v", err)
+		}
+		if refused == nil {
+			break
+		}
+		if refused.ErrorCategory != types.ErrorRobotsUnavailable {
+			t.Fatalf("Expected %s, got %s", types.ErrorRobotsUnavailable, refused.ErrorCategory)
+		}
+		if !admin.scheduleRetry(0, url, *refused) || !admin.isRetryPending(url) {
+			t.Fatalf("Expected a retry to be scheduled, the URL was given up on after %d robots.txt fetches", robotsFetches.Load())
+		}
+		for len(admin.retryQueue.Ready(time.Now())) == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("Timed out waiting for the retry")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < robotsConfig.UnavailableRetry {
+		t.Errorf("Expected the retry to wait for robots.txt to be fetched again, took %v", elapsed)
+	}
+	if fetches := robotsFetches.Load(); fetches != 2 {
+		t.Errorf("Expected 2 robots.txt fetches, got %d", fetches)
+	}
+	if letters, _ := admin.deadLetters.Drain(); len(letters) != 0 {
+		t.Errorf("Expected no dead letters, got %v", letters)
+	}
+}
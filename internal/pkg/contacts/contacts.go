@@ -0,0 +1,175 @@
+package contacts
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"webcrawler/internal/pkg/types"
+)
+
+// Path of a JSON social catalogue replacing the built-in one
+const EnvSocial = "WEBCRAWLER_SOCIAL"
+
+// Country calling code, e.g. "44", of national numbers in tel: links. Those
+// numbers are dropped when it is unset.
+const EnvCallingCode = "WEBCRAWLER_CALLING_CODE"
+
+// Pulls emails, phone numbers, postal addresses and social profiles out of
+// extracted pages
+type Extractor struct {
+	catalogue   *Catalogue
+	callingCode string
+}
+
+// Creates an extractor with a catalogue and a calling code for national numbers
+func NewExtractor(catalogue *Catalogue, callingCode string) *Extractor {
+	return &Extractor{catalogue: catalogue, callingCode: callingCode}
+}
+
+// Creates an extractor with the built-in catalogue that only takes
+// international phone numbers
+func DefaultExtractor() *Extractor {
+	return NewExtractor(DefaultCatalogue(), "")
+}
+
+// Creates an extractor from the catalogue and calling code named by the environment
+func FromEnvironment() (*Extractor, error) {
+	callingCode, err := ParseCallingCode(os.Getenv(EnvCallingCode))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", EnvCallingCode, err)
+	}
+	catalogue, err := LoadCatalogue(os.Getenv(EnvSocial))
+	if err != nil {
+		return nil, err
+	}
+	return NewExtractor(catalogue, callingCode), nil
+}
+
+// Returns the links that are on a platform of the catalogue
+func (extractor *Extractor) SocialLinks(links []string) []string {
+	social := make([]string, 0, 5)
+	for _, link := range links {
+		if extractor.catalogue.Covers(link) {
+			social = append(social, link)
+		}
+	}
+	return social
+}
+
+// Records the addresses of a mailto: link or the number of a tel: link.
+// Reports whether the link was one of those.
+func (extractor *Extractor) AddLink(contacts *types.Contacts, href string) bool {
+	scheme, _, _ := strings.Cut(strings.TrimSpace(href), ":")
+	switch strings.ToLower(scheme) {
+	case "mailto":
+		for _, email := range FromMailto(href) {
+			contacts.Emails = appendUnique(contacts.Emails, email)
+		}
+	case "tel":
+		if phone, ok := FromTel(href, extractor.callingCode); ok {
+			contacts.Phones = appendUnique(contacts.Phones, phone)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// Adds the contacts found in a page's visible text, links and structured
+// data to those already taken from its mailto: and tel: links
+func (extractor *Extractor) Extract(pageData *types.PageData) {
+	contacts := &pageData.Contacts
+	for _, email := range Emails(pageData.VisibleText) {
+		contacts.Emails = appendUnique(contacts.Emails, email)
+	}
+	for _, phone := range Phones(pageData.VisibleText) {
+		contacts.Phones = appendUnique(contacts.Phones, phone)
+	}
+
+	// Outlinks carry rel="me", but only HTML pages have them
+	for _, outlink := range pageData.Outlinks {
+		if profile, ok := extractor.catalogue.Profile(outlink.URL, slices.Contains(outlink.Rels, "me")); ok {
+			addProfile(contacts, profile)
+		}
+	}
+	links := pageData.ExternalLinks
+	if pageData.Organization != nil {
+		links = append(links[:len(links):len(links)], pageData.Organization.SameAs...)
+	}
+	for _, link := range links {
+		if profile, ok := extractor.catalogue.Profile(link, false); ok {
+			addProfile(contacts, profile)
+		}
+	}
+	for _, profile := range extractor.catalogue.Handles(pageData.VisibleText) {
+		addProfile(contacts, profile)
+	}
+
+	for _, entity := range pageData.Entities {
+		collectAddresses(contacts, &entity)
+	}
+}
+
+// Adds a profile unless the same account is already recorded
+func addProfile(contacts *types.Contacts, profile types.SocialProfile) {
+	for _, existing := range contacts.Profiles {
+		if existing.Platform == profile.Platform && strings.EqualFold(existing.Handle, profile.Handle) {
+			return
+		}
+	}
+	contacts.Profiles = append(contacts.Profiles, profile)
+}
+
+// Records the PostalAddress entities within an entity
+func collectAddresses(contacts *types.Contacts, entity *types.Entity) {
+	for _, entityType := range entity.Types {
+		if entityType == "PostalAddress" {
+			if address := formatAddress(entity); address != "" {
+				contacts.Addresses = appendUnique(contacts.Addresses, address)
+			}
+			return
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(entity.Properties)) { // In a stable order
+		for _, value := range entity.Properties[name] {
+			if value.Entity != nil {
+				collectAddresses(contacts, value.Entity)
+			}
+		}
+	}
+}
+
+// Writes a PostalAddress on one line: street, locality, region and postal
+// code, country
+func formatAddress(entity *types.Entity) string {
+	region := strings.TrimSpace(propertyText(entity, "addressRegion") + " " + propertyText(entity, "postalCode"))
+	var parts []string
+	for _, part := range []string{
+		propertyText(entity, "streetAddress"),
+		propertyText(entity, "addressLocality"),
+		region,
+		propertyText(entity, "addressCountry"),
+	} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Returns the first text of a property, or the name of the entity it holds,
+// as with an addressCountry given as a Country
+func propertyText(entity *types.Entity, name string) string {
+	for _, value := range entity.Properties[name] {
+		text := value.Text
+		if value.Entity != nil {
+			text = propertyText(value.Entity, "name")
+		}
+		if text = strings.Join(strings.Fields(text), " "); text != "" {
+			return text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package contacts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"webcrawler/internal/pkg/types"
+)
+
+// TestExtract tests that contacts from links, text, the organization and
+// nested addresses are merged without repeats.
+func TestExtract(t *testing.T) {
+	country := &types.Entity{Types: []string{"Country"}, Properties: map[string][]types.Property{"name": {{Text: "Ireland"}}}}
+	address := &types.Entity{Types: []string{"PostalAddress"}, Properties: map[string][]types.Property{
+		"streetAddress":   {{Text: "1 Main  Street"}},
+		"addressLocality": {{Text: "Dublin"}},
+		"addressRegion":   {{Text: "D02"}},
+		"addressCountry":  {{Entity: country}},
+	}}
+	pageData := types.PageData{
+		VisibleText:   "Email hello@example.ie or call +353 1 234 5678.",
+		ExternalLinks: []string{"https://github.com/Acme", "https://example.org/"},
+		Outlinks:      []types.Outlink{{URL: "https://github.com/acme"}, {URL: "https://pleroma.example/@acme", Rels: []string{"me"}}},
+		Organization:  &types.Organization{Name: "Acme", SameAs: []string{"https://www.instagram.com/acme/"}},
+		Entities:      []types.Entity{{Types: []string{"Organization"}, Properties: map[string][]types.Property{"address": {{Entity: address}}}}},
+	}
+	extractor := NewExtractor(DefaultCatalogue(), "353")
+	if !extractor.AddLink(&pageData.Contacts, "tel:01 234 5678") || extractor.AddLink(&pageData.Contacts, "https://example.org/") {
+		t.Errorf("Expected only the tel: link to be taken")
+	}
+	extractor.Extract(&pageData)
+
+	expected := types.Contacts{
+		Emails:    []string{"hello@example.ie"},
+		Phones:    []string{"+35312345678"},
+		Addresses: []string{"1 Main Street, Dublin, D02, Ireland"},
+		Profiles: []types.SocialProfile{
+			{Platform: "github", Handle: "acme", URL: "https://github.com/acme"},
+			{Platform: "mastodon", Handle: "acme@pleroma.example", URL: "https://pleroma.example/@acme"},
+			{Platform: "instagram", Handle: "acme", URL: "https://instagram.com/acme"},
+		},
+	}
+	if !reflect.DeepEqual(pageData.Contacts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, pageData.Contacts)
+	}
+	if len(pageData.Organization.SameAs) != 1 || len(pageData.ExternalLinks) != 2 {
+		t.Errorf("Expected the page's links to be left alone")
+	}
+}
+
+// TestFromEnvironment tests that the catalogue and calling code are read from the environment.
+func TestFromEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "social.json")
+	if err := os.WriteFile(path, []byte(`{"platforms": [{"name": "forge", "domains": ["forge.example"], "subdomains": true}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvSocial, path)
+	t.Setenv(EnvCallingCode, "+49")
+	extractor, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if extractor.callingCode != "49" || len(extractor.SocialLinks([]string{"https://acme.forge.example/", "https://github.com/acme"})) != 1 {
+		t.Errorf("Expected the settings from the environment, got %q", extractor.callingCode)
+	}
+
+	t.Setenv(EnvCallingCode, "0")
+	if _, err := FromEnvironment(); err == nil {
+		t.Errorf("Expected an error for an invalid calling code")
+	}
+	t.Setenv(EnvCallingCode, "")
+	t.Setenv(EnvSocial, filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := FromEnvironment(); err == nil {
+		t.Errorf("Expected an error for a missing catalogue")
+	}
+}
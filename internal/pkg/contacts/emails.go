@@ -0,0 +1,93 @@
+package contacts
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@(?:[A-Za-z0-9](?:[A-Za-z0-9\-]*[A-Za-z0-9])?\.)+[A-Za-z]{2,24}`)
+
+	// Spelled out separators such as "name [at] example [dot] com" or
+	// "name(at)example.com", and the shouted "name AT example DOT com"
+	obfuscatedAt  = regexp.MustCompile(`(?i)\s*[\[\(\{<]\s*at\s*[\]\)\}>]\s*`)
+	obfuscatedDot = regexp.MustCompile(`(?i)\s*[\[\(\{<]\s*dot\s*[\]\)\}>]\s*`)
+	shoutedEmail  = regexp.MustCompile(`\b([A-Za-z0-9._%+\-]+)\s+AT\s+([A-Za-z0-9\-]+(?:\s+DOT\s+[A-Za-z0-9\-]+)+)\b`)
+	shoutedDot    = regexp.MustCompile(`\s+DOT\s+`)
+)
+
+// Endings of file names, which look like top level domains in "logo@2x.png"
+var fileExtensions = map[string]struct{}{
+	"png": {}, "jpg": {}, "jpeg": {}, "gif": {}, "webp": {}, "svg": {}, "avif": {},
+	"css": {}, "js": {}, "json": {}, "html": {}, "php": {}, "pdf": {},
+}
+
+// Rewrites spelled out email addresses into their usual form
+func Deobfuscate(text string) string {
+	text = obfuscatedAt.ReplaceAllString(text, "@")
+	text = obfuscatedDot.ReplaceAllString(text, ".")
+	return shoutedEmail.ReplaceAllStringFunc(text, func(match string) string {
+		parts := shoutedEmail.FindStringSubmatch(match)
+		return parts[1] + "@" + shoutedDot.ReplaceAllString(parts[2], ".")
+	})
+}
+
+// Finds the email addresses in text, including obfuscated ones, lower cased
+// and without repeats. Fediverse handles such as "@alice@example.social" are
+// not addresses and are left out.
+func Emails(text string) []string {
+	text = Deobfuscate(text)
+	var emails []string
+	for _, span := range emailPattern.FindAllStringIndex(text, -1) {
+		if span[0] > 0 && text[span[0] - 1] == '@' {
+			continue
+		}
+		if email, ok := normalizeEmail(text[span[0]:span[1]]); ok {
+			emails = appendUnique(emails, email)
+		}
+	}
+	return emails
+}
+
+// Reads the addresses of a mailto: link, such as
+// "mailto:a@example.com,b@example.com?subject=Hello"
+func FromMailto(href string) []string {
+	scheme, rest, found := strings.Cut(strings.TrimSpace(href), ":")
+	if !found || !strings.EqualFold(scheme, "mailto") {
+		return nil
+	}
+	rest, _, _ = strings.Cut(rest, "?")
+	if unescaped, err := url.PathUnescape(rest); err == nil {
+		rest = unescaped
+	}
+	var emails []string
+	for _, address := range strings.Split(rest, ",") {
+		if email, ok := normalizeEmail(address); ok {
+			emails = appendUnique(emails, email)
+		}
+	}
+	return emails
+}
+
+// Lower cases an address, checking it is one
+func normalizeEmail(address string) (string, bool) {
+	address = strings.ToLower(strings.Trim(strings.TrimSpace(address), "."))
+	local, domain, found := strings.Cut(address, "@")
+	if !found || local == "" || emailPattern.FindString(address) != address {
+		return "", false
+	}
+	if _, file := fileExtensions[domain[strings.LastIndex(domain, ".") + 1:]]; file {
+		return "", false
+	}
+	return address, true
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,32 @@
+package contacts
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestEmails tests that plain and obfuscated addresses are found, and file
+// names and fediverse handles are not.
+func TestEmails(t *testing.T) {
+	text := "Mail Info@Example.com, sales [at] example [dot] co [dot] uk or jobs(at)example.org. " +
+		"Press: PRESS AT EXAMPLE DOT COM. Logo: logo@2x.png. Follow @alice@example.social. Again: info@example.com."
+	expected := []string{"info@example.com", "sales@example.co.uk", "jobs@example.org", "press@example.com"}
+	if emails := Emails(text); !reflect.DeepEqual(emails, expected) {
+		t.Errorf("Expected %v, got %v", expected, emails)
+	}
+}
+
+// TestFromMailto tests that every address of a mailto: link is read and its query dropped.
+func TestFromMailto(t *testing.T) {
+	for href, expected := range map[string][]string{
+		"mailto:Info@Example.com":                         {"info@example.com"},
+		"MAILTO:a@example.com,%20b@example.com?cc=c@x.io": {"a@example.com", "b@example.com"},
+		"mailto:?subject=Hello":                           nil,
+		"mailto:not-an-address":                           nil,
+		"https://example.com/":                            nil,
+	} {
+		if emails := FromMailto(href); !reflect.DeepEqual(emails, expected) {
+			t.Errorf("%s: expected %v, got %v", href, expected, emails)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package contacts
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// E.164 numbers have at most 15 digits with the country code. The shortest
+// in use have 7.
+const (
+	minPhoneDigits = 7
+	maxPhoneDigits = 15
+)
+
+var (
+	// Numbers written in international form, "+44 20 7946 0000" or
+	// "+1 (415) 555-0100". National numbers in text are too easily confused
+	// with other figures to be taken.
+	phonePattern = regexp.MustCompile(`\+\s?[1-9][\d\s().\-/]{5,24}\d`)
+
+	// The "(0)" that some write between the country code and the number
+	nationalPrefix = regexp.MustCompile(`\(\s*0\s*\)`)
+
+	callingCodePattern = regexp.MustCompile(`^[1-9]\d{0,2}$`)
+)
+
+// Checks a country calling code, such as "44", given without the plus sign
+func ParseCallingCode(code string) (string, error) {
+	code = strings.TrimPrefix(strings.TrimSpace(code), "+")
+	if code != "" && !callingCodePattern.MatchString(code) {
+		return "", fmt.Errorf("%q is not a country calling code", code)
+	}
+	return code, nil
+}
+
+// Finds the phone numbers written in international form in text, in E.164
+// form and without repeats
+func Phones(text string) []string {
+	var phones []string
+	for _, match := range phonePattern.FindAllString(text, -1) {
+		if phone, ok := NormalizePhone(match, ""); ok {
+			phones = appendUnique(phones, phone)
+		}
+	}
+	return phones
+}
+
+// Reads the number of a tel: link, such as "tel:+44-20-7946-0000;ext=12".
+// National numbers are taken to be in the country of the calling code, and
+// are dropped when it is empty.
+func FromTel(href, callingCode string) (string, bool) {
+	scheme, rest, found := strings.Cut(strings.TrimSpace(href), ":")
+	if !found || !strings.EqualFold(scheme, "tel") {
+		return "", false
+	}
+	rest, _, _ = strings.Cut(rest, ";")
+	if unescaped, err := url.PathUnescape(rest); err == nil {
+		rest = unescaped
+	}
+	return NormalizePhone(rest, callingCode)
+}
+
+// Converts a phone number to E.164. Numbers starting with "+" or the "00"
+// international prefix keep their country code; others get the given calling
+// code, losing the trunk prefix the country dials before national numbers.
+func NormalizePhone(number, callingCode string) (string, bool) {
+	number = nationalPrefix.ReplaceAllString(strings.TrimSpace(number), "")
+	international := strings.HasPrefix(number, "+")
+	var builder strings.Builder
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			builder.WriteRune(r)
+		}
+	}
+	digits := builder.String()
+
+	switch {
+	case international:
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	case callingCode == "":
+		return "", false
+	case callingCode == "1":
+		// North American numbers are dialled with a leading 1 rather than 0
+		digits = "1" + strings.TrimPrefix(digits, "1")
+	case callingCode == "39":
+		digits = callingCode + digits // Italian numbers keep their leading 0
+	default:
+		digits = callingCode + strings.TrimPrefix(digits, "0")
+	}
+	if len(digits) < minPhoneDigits || len(digits) > maxPhoneDigits || digits[0] == '0' {
+		return "", false
+	}
+	return "+" + digits, true
+}
@@ -0,0 +1,51 @@
+package contacts
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestPhones tests that only international numbers are taken from text.
+func TestPhones(t *testing.T) {
+	text := "Call +44 (0)20 7946 0000 or +1 (415) 555-0100, fax 020 7946 0001. Founded 1999, order 123456789."
+	expected := []string{"+442079460000", "+14155550100"}
+	if phones := Phones(text); !reflect.DeepEqual(phones, expected) {
+		t.Errorf("Expected %v, got %v", expected, phones)
+	}
+}
+
+// TestFromTel tests international, 00 prefixed and national numbers in tel: links.
+func TestFromTel(t *testing.T) {
+	for _, test := range []struct {
+		href, callingCode, expected string
+	}{
+		{"tel:+44-20-7946-0000;ext=12", "", "+442079460000"},
+		{"tel:0044%2020%207946%200000", "", "+442079460000"},
+		{"tel:020 7946 0000", "", ""},
+		{"tel:020 7946 0000", "44", "+442079460000"},
+		{"tel:(415) 555-0100", "1", "+14155550100"},
+		{"tel:1-415-555-0100", "1", "+14155550100"},
+		{"tel:06 1234 5678", "39", "+390612345678"},
+		{"tel:+12", "", ""},
+		{"mailto:a@example.com", "44", ""},
+	} {
+		phone, ok := FromTel(test.href, test.callingCode)
+		if phone != test.expected || ok != (test.expected != "") {
+			t.Errorf("%s with %q: expected %q, got %q", test.href, test.callingCode, test.expected, phone)
+		}
+	}
+}
+
+// TestParseCallingCode tests that calling codes are checked and lose any plus sign.
+func TestParseCallingCode(t *testing.T) {
+	for code, expected := range map[string]string{"": "", "44": "44", "+1": "1", " 353 ": "353"} {
+		if parsed, err := ParseCallingCode(code); err != nil || parsed != expected {
+			t.Errorf("%q: expected %q, got %q (%v)", code, expected, parsed, err)
+		}
+	}
+	for _, code := range []string{"044", "1234", "uk"} {
+		if _, err := ParseCallingCode(code); err == nil {
+			t.Errorf("Expected an error for %q", code)
+		}
+	}
+}
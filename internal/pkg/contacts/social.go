@@ -0,0 +1,272 @@
+package contacts
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"webcrawler/internal/pkg/types"
+)
+
+//go:embed social.json
+var defaultCatalogue []byte
+
+// A social platform of the catalogue. A link is on the platform when its host
+// is one of the domains or a subdomain of one, and is a profile when its path
+// matches one of the patterns. Patterns capture the profile's handle in a
+// group named "handle".
+type Platform struct {
+	Name       string   `json:"name"`
+	Domains    []string `json:"domains"`
+	Patterns   []string `json:"patterns,omitempty"`   // Regular expressions matched against the path
+	Exclude    []string `json:"exclude,omitempty"`    // Handles that are pages of the platform itself, such as "share"
+	Subdomains bool     `json:"subdomains,omitempty"` // Profiles also live on subdomains, as in alice.tumblr.com
+	Fediverse  bool     `json:"fediverse,omitempty"`  // Also names "@user@host" handles and rel="me" links to /@user on any host
+}
+
+// The contents of a catalogue file
+type CatalogueFile struct {
+	Platforms []Platform `json:"platforms"`
+}
+
+// A platform ready to be matched
+type compiledPlatform struct {
+	Platform
+	patterns []*regexp.Regexp
+	exclude  map[string]struct{}
+}
+
+// The social platforms known to the extractor, parsed and compiled. It is
+// safe for concurrent use.
+type Catalogue struct {
+	platforms []compiledPlatform
+	domains   map[string]int // Platform of each domain
+	fediverse int            // Platform of fediverse handles, -1 if none
+}
+
+var (
+	// A path naming a fediverse account, as Mastodon and its kin use
+	fediversePath = regexp.MustCompile(`^/@(?P<handle>[A-Za-z0-9_]+)/?$`)
+
+	// A fediverse handle written out, "@alice@example.social"
+	fediverseHandle = regexp.MustCompile(`@([A-Za-z0-9_]{1,30})@((?:[A-Za-z0-9](?:[A-Za-z0-9\-]*[A-Za-z0-9])?\.)+[A-Za-z]{2,24})`)
+)
+
+// Parses and compiles a catalogue file
+func ParseCatalogue(data []byte) (*Catalogue, error) {
+	var file CatalogueFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid social catalogue: %w", err)
+	}
+	return CompileCatalogue(file)
+}
+
+// Compiles a catalogue, checking every platform
+func CompileCatalogue(file CatalogueFile) (*Catalogue, error) {
+	catalogue := &Catalogue{domains: make(map[string]int), fediverse: -1}
+	for i, platform := range file.Platforms {
+		if platform.Name == "" {
+			return nil, fmt.Errorf("platform %d has no name", i + 1)
+		}
+		compiled, err := compilePlatform(platform)
+		if err != nil {
+			return nil, fmt.Errorf("platform %q: %w", platform.Name, err)
+		}
+		index := len(catalogue.platforms)
+		for _, domain := range compiled.Domains {
+			if _, taken := catalogue.domains[domain]; taken {
+				return nil, fmt.Errorf("platform %q: domain %s is listed twice", platform.Name, domain)
+			}
+			catalogue.domains[domain] = index
+		}
+		if platform.Fediverse && catalogue.fediverse < 0 {
+			catalogue.fediverse = index
+		}
+		catalogue.platforms = append(catalogue.platforms, compiled)
+	}
+	return catalogue, nil
+}
+
+// Returns the built-in catalogue
+func DefaultCatalogue() *Catalogue {
+	catalogue, err := ParseCatalogue(defaultCatalogue)
+	if err != nil {
+		panic(err)
+	}
+	return catalogue
+}
+
+// Reads a catalogue file, or returns the built-in catalogue if the path is empty
+func LoadCatalogue(path string) (*Catalogue, error) {
+	if path == "" {
+		return DefaultCatalogue(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading social catalogue: %v", err)
+	}
+	catalogue, err := ParseCatalogue(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return catalogue, nil
+}
+
+func compilePlatform(platform Platform) (compiledPlatform, error) {
+	compiled := compiledPlatform{Platform: platform, exclude: make(map[string]struct{})}
+	compiled.Domains = nil
+	for _, domain := range platform.Domains {
+		domain = strings.Trim(normalizeHost(domain), ".")
+		if domain != "" {
+			compiled.Domains = append(compiled.Domains, domain)
+		}
+	}
+	if len(compiled.Domains) == 0 {
+		return compiledPlatform{}, fmt.Errorf("no domains")
+	}
+	if len(platform.Patterns) == 0 && !platform.Subdomains {
+		return compiledPlatform{}, fmt.Errorf("needs patterns or subdomains")
+	}
+	for _, pattern := range platform.Patterns {
+		expression, err := regexp.Compile(pattern)
+		if err != nil {
+			return compiledPlatform{}, fmt.Errorf("invalid pattern: %v", err)
+		}
+		if expression.SubexpIndex("handle") < 0 {
+			return compiledPlatform{}, fmt.Errorf("pattern %q has no handle group", pattern)
+		}
+		compiled.patterns = append(compiled.patterns, expression)
+	}
+	for _, handle := range platform.Exclude {
+		compiled.exclude[strings.ToLower(handle)] = struct{}{}
+	}
+	return compiled, nil
+}
+
+// Reports whether a link is on any platform of the catalogue, profile or not
+func (catalogue *Catalogue) Covers(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	_, _, found := catalogue.platformOf(normalizeHost(parsed.Hostname()))
+	return found
+}
+
+// Returns the profile a link points to, if it points to one. Links to /@user
+// on hosts outside the catalogue are fediverse profiles when the page marks
+// them rel="me".
+func (catalogue *Catalogue) Profile(rawURL string, relMe bool) (types.SocialProfile, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return types.SocialProfile{}, false
+	}
+	host := normalizeHost(parsed.Hostname())
+	index, subdomain, found := catalogue.platformOf(host)
+	if !found {
+		if relMe && catalogue.fediverse >= 0 {
+			return catalogue.fediverseProfile(host, parsed.Path)
+		}
+		return types.SocialProfile{}, false
+	}
+	platform := catalogue.platforms[index]
+
+	if subdomain != "" && platform.Subdomains {
+		if strings.Contains(subdomain, ".") || platform.excluded(subdomain) || strings.Trim(parsed.Path, "/") != "" {
+			return types.SocialProfile{}, false
+		}
+		return types.SocialProfile{Platform: platform.Name, Handle: subdomain, URL: "https://" + host}, true
+	}
+	if platform.Fediverse {
+		return catalogue.fediverseProfile(host, parsed.Path)
+	}
+	for _, pattern := range platform.patterns {
+		match := pattern.FindStringSubmatch(parsed.Path)
+		if match == nil {
+			continue
+		}
+		handle := match[pattern.SubexpIndex("handle")]
+		if handle == "" || platform.excluded(handle) {
+			return types.SocialProfile{}, false
+		}
+		return types.SocialProfile{
+			Platform: platform.Name,
+			Handle:   handle,
+			URL:      "https://" + host + strings.TrimSuffix(parsed.Path, "/"),
+		}, true
+	}
+	return types.SocialProfile{}, false
+}
+
+// Finds the fediverse handles written in text, such as "@alice@example.social"
+func (catalogue *Catalogue) Handles(text string) []types.SocialProfile {
+	if catalogue.fediverse < 0 {
+		return nil
+	}
+	var profiles []types.SocialProfile
+	for _, span := range fediverseHandle.FindAllStringSubmatchIndex(text, -1) {
+		if span[0] > 0 && isHandleByte(text[span[0] - 1]) {
+			continue // Part of a longer word, path or address
+		}
+		user, host := text[span[2]:span[3]], normalizeHost(text[span[4]:span[5]])
+		profiles = append(profiles, types.SocialProfile{
+			Platform: catalogue.platforms[catalogue.fediverse].Name,
+			Handle:   user + "@" + host,
+			URL:      "https://" + host + "/@" + user,
+		})
+	}
+	return profiles
+}
+
+// Reads a /@user path as a fediverse profile on a host
+func (catalogue *Catalogue) fediverseProfile(host, path string) (types.SocialProfile, bool) {
+	match := fediversePath.FindStringSubmatch(path)
+	if match == nil || catalogue.fediverse < 0 {
+		return types.SocialProfile{}, false
+	}
+	user := match[fediversePath.SubexpIndex("handle")]
+	return types.SocialProfile{
+		Platform: catalogue.platforms[catalogue.fediverse].Name,
+		Handle:   user + "@" + host,
+		URL:      "https://" + host + "/@" + user,
+	}, true
+}
+
+// Finds the platform of a host, trying the host and then each of its parent
+// domains. Returns the part of the host left of the platform's domain.
+func (catalogue *Catalogue) platformOf(host string) (int, string, bool) {
+	for domain := host; domain != ""; {
+		if index, found := catalogue.domains[domain]; found {
+			return index, strings.TrimSuffix(strings.TrimSuffix(host, domain), "."), true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return 0, "", false
+}
+
+func (platform compiledPlatform) excluded(handle string) bool {
+	_, excluded := platform.exclude[strings.ToLower(handle)]
+	return excluded
+}
+
+// Lower cases a host and drops the "www." and mobile prefixes that serve the
+// same profiles
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	for _, prefix := range []string{"www.", "m.", "mobile."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	return host
+}
+
+func isHandleByte(b byte) bool {
+	return b == '@' || b == '/' || b == '.' || b == '_' || b == '-' ||
+		(b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
@@ -0,0 +1,77 @@
+package contacts
+
+import (
+	"reflect"
+	"testing"
+	"webcrawler/internal/pkg/types"
+)
+
+// TestProfile tests profile links on patterned, subdomain and fediverse platforms.
+func TestProfile(t *testing.T) {
+	catalogue := DefaultCatalogue()
+	for link, expected := range map[string]types.SocialProfile{
+		"https://www.facebook.com/acme/":           {Platform: "facebook", Handle: "acme", URL: "https://facebook.com/acme"},
+		"https://mobile.twitter.com/acme?lang=en":  {Platform: "twitter", Handle: "acme", URL: "https://twitter.com/acme"},
+		"https://uk.linkedin.com/company/acme-ltd": {Platform: "linkedin", Handle: "acme-ltd", URL: "https://uk.linkedin.com/company/acme-ltd"},
+		"https://www.youtube.com/channel/UC123":    {Platform: "youtube", Handle: "UC123", URL: "https://youtube.com/channel/UC123"},
+		"https://www.tiktok.com/@acme":             {Platform: "tiktok", Handle: "acme", URL: "https://tiktok.com/@acme"},
+		"https://acme.tumblr.com/":                 {Platform: "tumblr", Handle: "acme", URL: "https://acme.tumblr.com"},
+		"https://hachyderm.io/@acme":               {Platform: "mastodon", Handle: "acme@hachyderm.io", URL: "https://hachyderm.io/@acme"},
+	} {
+		if profile, ok := catalogue.Profile(link, false); !ok || profile != expected {
+			t.Errorf("%s: expected %+v, got %+v", link, expected, profile)
+		}
+	}
+	for _, link := range []string{
+		"https://www.facebook.com/sharer/sharer.php?u=x",
+		"https://twitter.com/intent",
+		"https://github.com/acme/project",
+		"https://acme.tumblr.com/post/1",
+		"https://www.tumblr.com/",
+		"https://social.example/@acme",
+		"mailto:acme@github.com",
+	} {
+		if profile, ok := catalogue.Profile(link, false); ok {
+			t.Errorf("%s: expected no profile, got %+v", link, profile)
+		}
+	}
+	if profile, ok := catalogue.Profile("https://social.example/@acme", true); !ok || profile.Handle != "acme@social.example" {
+		t.Errorf("Expected a rel=me link to be a fediverse profile, got %+v", profile)
+	}
+}
+
+// TestHandles tests that fediverse handles are found in text, but not inside addresses or paths.
+func TestHandles(t *testing.T) {
+	profiles := DefaultCatalogue().Handles("Follow @alice@Example.Social or bob@@x.io, see /@carol@host.example or mail dave@example.com")
+	expected := []types.SocialProfile{{Platform: "mastodon", Handle: "alice@example.social", URL: "https://example.social/@alice"}}
+	if !reflect.DeepEqual(profiles, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, profiles)
+	}
+}
+
+// TestParseCatalogue tests that broken catalogues are rejected.
+func TestParseCatalogue(t *testing.T) {
+	for name, data := range map[string]string{
+		"no name":         `{"platforms": [{"domains": ["a.example"], "patterns": ["^/(?P<handle>\\w+)$"]}]}`,
+		"no domains":      `{"platforms": [{"name": "a", "patterns": ["^/(?P<handle>\\w+)$"]}]}`,
+		"no patterns":     `{"platforms": [{"name": "a", "domains": ["a.example"]}]}`,
+		"no handle":       `{"platforms": [{"name": "a", "domains": ["a.example"], "patterns": ["^/\\w+$"]}]}`,
+		"bad pattern":     `{"platforms": [{"name": "a", "domains": ["a.example"], "patterns": ["("]}]}`,
+		"repeated domain": `{"platforms": [{"name": "a", "domains": ["a.example"], "subdomains": true}, {"name": "b", "domains": ["www.a.example"], "subdomains": true}]}`,
+		"not json":        `{`,
+	} {
+		if _, err := ParseCatalogue([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	catalogue, err := ParseCatalogue([]byte(`{"platforms": [{"name": "forge", "domains": ["forge.example"], "patterns": ["^/~(?P<handle>\\w+)$"]}]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if profile, ok := catalogue.Profile("https://forge.example/~acme", false); !ok || profile.Handle != "acme" {
+		t.Errorf("Expected the custom platform to match, got %+v", profile)
+	}
+	if catalogue.Covers("https://github.com/acme") || len(catalogue.Handles("@alice@example.social")) != 0 {
+		t.Errorf("Expected a custom catalogue to replace the built-in platforms")
+	}
+}
@@ -0,0 +1,162 @@
+package dates
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts tried in order once a date has been cleaned up. Numeric dates with
+// slashes are read the American way first, so only days past the 12th are
+// read as day first.
+var layouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04Z0700",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04 -0700",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	"2006.01.02",
+	"20060102",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"01/02/2006",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"02/01/2006",
+}
+
+// Layouts naming the month, each tried with both the short and the long
+// month name. A leading weekday is removed before these are tried.
+var monthLayouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006 3:04 PM -0700",
+	"2 Jan 2006 3:04 PM",
+	"2 Jan 2006",
+	"2-Jan-06 15:04:05 -0700",
+	"2-Jan-06 15:04:05 MST",
+	"2-Jan-2006",
+	"Jan 2, 2006 15:04:05 -0700",
+	"Jan 2, 2006 15:04:05",
+	"Jan 2, 2006 3:04 PM -0700",
+	"Jan 2, 2006 3:04 PM",
+	"Jan 2, 2006 15:04 -0700",
+	"Jan 2, 2006 15:04",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	"Jan _2 15:04:05 2006",
+	"Jan _2 15:04:05 -0700 2006",
+	"Jan _2 15:04:05 MST 2006",
+	"2006 Jan 2",
+}
+
+// Offsets of time zone abbreviations, which time.Parse only understands for
+// the local zone and UTC
+var zoneOffsets = map[string]string{
+	"UT": "+0000", "UTC": "+0000", "GMT": "+0000", "Z": "+0000",
+	"EST": "-0500", "EDT": "-0400", "CST": "-0600", "CDT": "-0500",
+	"MST": "-0700", "MDT": "-0600", "PST": "-0800", "PDT": "-0700",
+	"AKST": "-0900", "AKDT": "-0800", "HST": "-1000",
+	"WET": "+0000", "WEST": "+0100", "BST": "+0100", "CET": "+0100", "CEST": "+0200",
+	"MET": "+0100", "MEST": "+0200", "EET": "+0200", "EEST": "+0300", "MSK": "+0300",
+	"IST": "+0530", "SGT": "+0800", "HKT": "+0800", "JST": "+0900", "KST": "+0900",
+	"AEST": "+1000", "AEDT": "+1100", "ACST": "+0930", "AWST": "+0800",
+	"NZST": "+1200", "NZDT": "+1300",
+}
+
+var (
+	leadingWeekday = regexp.MustCompile(`(?i)^(mon|tue|wed|thu|fri|sat|sun)[a-z]*\.?,?\s+`)
+	ordinalSuffix  = regexp.MustCompile(`(?i)\b(\d{1,2})(st|nd|rd|th)\b`)
+	meridiem       = regexp.MustCompile(`(?i)(\d)\s*([ap])\.?m\.?(\s|$)`)
+	monthPeriod    = regexp.MustCompile(`(?i)\b(jan|feb|mar|apr|jun|jul|aug|sep|sept|oct|nov|dec)\.`)
+	septemberShort = regexp.MustCompile(`(?i)\bsept\b`)
+	atSeparator    = regexp.MustCompile(`(?i)\s+at\s+|\s*,\s*(\d{1,2}:)`)
+	zoneSuffix     = regexp.MustCompile(`\s*\(([A-Za-z]+)\)$`)
+)
+
+// Parses a date or date and time in any of the formats commonly found on web
+// pages, in feeds and in HTTP headers, including Unix timestamps. Dates
+// without a zone are taken to be in UTC.
+func Parse(value string) (time.Time, bool) {
+	value = clean(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if isDigits(value) && (len(value) == 10 || len(value) == 13) {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		if len(value) == 13 {
+			return time.UnixMilli(seconds).UTC(), true
+		}
+		return time.Unix(seconds, 0).UTC(), true
+	}
+
+	for _, layout := range layouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, true
+		}
+	}
+	withoutWeekday := leadingWeekday.ReplaceAllString(value, "")
+	for _, layout := range monthLayouts {
+		for _, variant := range []string{layout, strings.Replace(layout, "Jan", "January", 1)} {
+			if parsed, err := time.Parse(variant, withoutWeekday); err == nil {
+				return parsed, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// Tidies a date so fewer layouts are needed: ordinals, abbreviated months,
+// "at" between the date and the time, spelled out meridiems and zone
+// abbreviations are rewritten
+func clean(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	value = strings.TrimSuffix(value, ".")
+	value = zoneSuffix.ReplaceAllString(value, " $1")
+	value = ordinalSuffix.ReplaceAllString(value, "$1")
+	value = monthPeriod.ReplaceAllString(value, "$1")
+	value = septemberShort.ReplaceAllString(value, "Sep")
+	value = atSeparator.ReplaceAllString(value, " $1")
+	value = meridiem.ReplaceAllStringFunc(value, func(match string) string {
+		submatches := meridiem.FindStringSubmatch(match)
+		return submatches[1] + " " + strings.ToUpper(submatches[2]) + "M" + submatches[3]
+	})
+
+	fields := strings.Fields(value)
+	for i, field := range fields {
+		if offset, known := zoneOffsets[field]; known && i > 0 {
+			fields[i] = offset
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParse tests the layouts found on pages, in feeds and in headers, with their zones.
+func TestParse(t *testing.T) {
+	march5 := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	tenAM := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	for value, expected := range map[string]time.Time{
+		"2024-03-05":                         march5,
+		"2024-03-05T10:00:00Z":               tenAM,
+		"2024-03-05T11:00:00+01:00":          tenAM,
+		"2024-03-05T11:00:00.123+0100":       tenAM.Add(123 * time.Millisecond),
+		"2024-03-05 10:00":                   tenAM,
+		"2024/03/05":                         march5,
+		"20240305":                           march5,
+		"05.03.2024":                         march5,
+		"03/05/2024":                         march5,
+		"13/03/2024":                         time.Date(2024, time.March, 13, 0, 0, 0, 0, time.UTC),
+		"Tue, 05 Mar 2024 10:00:00 GMT":      tenAM,
+		"Tue, 05 Mar 2024 05:00:00 EST":      tenAM,
+		"Tuesday, 05-Mar-24 10:00:00 UTC":    tenAM,
+		"Tue Mar  5 10:00:00 2024":           tenAM,
+		"March 5, 2024":                      march5,
+		"March 5th, 2024 at 2:00 a.m. PST":   tenAM,
+		"Mar. 5, 2024, 10:00 AM":             tenAM,
+		"5 March 2024":                       march5,
+		"Tuesday 5th March 2024 11:00 (CET)": tenAM,
+		"5 Sept 2024":                        time.Date(2024, time.September, 5, 0, 0, 0, 0, time.UTC),
+		"1709632800":                         tenAM,
+		"1709632800000":                      tenAM,
+	} {
+		parsed, ok := Parse(value)
+		if !ok {
+			t.Errorf("%q: not parsed", value)
+			continue
+		}
+		if !parsed.Equal(expected) {
+			t.Errorf("%q: expected %v, got %v", value, expected, parsed)
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "2024-13-45", "Posted by Jane"} {
+		if parsed, ok := Parse(value); ok {
+			t.Errorf("%q: expected no date, got %v", value, parsed)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package dates
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"webcrawler/internal/pkg/types"
+)
+
+// Where a date was found. Dates from structured data carry the source of
+// their entity instead.
+const (
+	SourceMeta   = "meta"
+	SourceFeed   = "feed"
+	SourceTime   = "time" // A <time> element
+	SourceURL    = "url"
+	SourceHeader = "header" // HTTP Last-Modified
+)
+
+// Which of a page's dates a candidate is for
+type Kind int
+
+const (
+	Unknown Kind = iota
+	Published
+	Modified
+)
+
+// A date found on a page, with where it was found and how much it is trusted
+// from 0 to 1
+type Candidate struct {
+	Time       time.Time
+	Source     string
+	Confidence float64
+}
+
+// A meta tag naming one of the page's dates
+type metaDate struct {
+	kind       Kind
+	confidence float64
+}
+
+// Meta tags carrying dates, by their lower cased name, property or itemprop.
+// Tags that say which date they hold are trusted more than a bare "date".
+var metaDates = map[string]metaDate{
+	"article:published_time":    {Published, 0.9},
+	"og:published_time":         {Published, 0.9},
+	"datepublished":             {Published, 0.9},
+	"article.published":         {Published, 0.85},
+	"publish-date":              {Published, 0.85},
+	"publish_date":              {Published, 0.85},
+	"publishdate":               {Published, 0.85},
+	"pubdate":                   {Published, 0.85},
+	"date_published":            {Published, 0.85},
+	"parsely-pub-date":          {Published, 0.85},
+	"sailthru.date":             {Published, 0.8},
+	"citation_publication_date": {Published, 0.85},
+	"citation_date":             {Published, 0.8},
+	"dc.date.issued":            {Published, 0.8},
+	"dcterms.issued":            {Published, 0.8},
+	"dcterms.created":           {Published, 0.75},
+	"dc.date.created":           {Published, 0.75},
+	"datecreated":               {Published, 0.75},
+	"date":                      {Published, 0.6},
+	"dc.date":                   {Published, 0.6},
+	"dcterms.date":              {Published, 0.6},
+	"article:modified_time":     {Modified, 0.9},
+	"og:updated_time":           {Modified, 0.85},
+	"datemodified":              {Modified, 0.9},
+	"article.updated":           {Modified, 0.85},
+	"date_modified":             {Modified, 0.85},
+	"last-modified":             {Modified, 0.7},
+	"dcterms.modified":          {Modified, 0.8},
+	"dc.date.modified":          {Modified, 0.8},
+}
+
+var (
+	urlDay   = regexp.MustCompile(`/((?:19|20)\d{2})[/-](0?[1-9]|1[0-2])[/-](0?[1-9]|[12]\d|3[01])(?:[/-]|$)`)
+	urlMonth = regexp.MustCompile(`/((?:19|20)\d{2})/(0?[1-9]|1[0-2])/`)
+)
+
+// The web is younger than this, so earlier dates are mistakes
+var earliestDate = time.Date(1991, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Reads a date from a meta tag, given its name, property or itemprop
+func FromMeta(key, content string) (Kind, Candidate, bool) {
+	meta, known := metaDates[strings.ToLower(strings.TrimSpace(key))]
+	if !known {
+		return Unknown, Candidate{}, false
+	}
+	parsed, ok := Parse(content)
+	if !ok {
+		return Unknown, Candidate{}, false
+	}
+	return meta.kind, Candidate{Time: parsed, Source: SourceMeta, Confidence: meta.confidence}, true
+}
+
+// Reads a publication date from a URL path such as /2024/03/05/ or
+// /2024-03-05-title. A path naming only the year and month gives the first of
+// the month and is trusted less.
+func FromURL(rawURL string) (Candidate, bool) {
+	if match := urlDay.FindStringSubmatch(rawURL); match != nil {
+		if date, ok := dateOf(match[1], match[2], match[3]); ok {
+			return Candidate{Time: date, Source: SourceURL, Confidence: 0.6}, true
+		}
+	}
+	if match := urlMonth.FindStringSubmatch(rawURL); match != nil {
+		if date, ok := dateOf(match[1], match[2], "1"); ok {
+			return Candidate{Time: date, Source: SourceURL, Confidence: 0.3}, true
+		}
+	}
+	return Candidate{}, false
+}
+
+// Records a candidate as the page's published or modified date if it is
+// plausible and trusted more than the date already recorded. Returns whether
+// it was taken.
+func Offer(pageData *types.PageData, kind Kind, candidate Candidate) bool {
+	if candidate.Time.Before(earliestDate) || candidate.Time.After(time.Now().Add(24 * time.Hour)) {
+		return false
+	}
+	date, origin := &pageData.DatePublished, &pageData.PublishedFrom
+	switch kind {
+	case Published:
+	case Modified:
+		date, origin = &pageData.DateModified, &pageData.ModifiedFrom
+	default:
+		return false
+	}
+	if !date.IsZero() && candidate.Confidence <= origin.Confidence {
+		return false
+	}
+	*date = candidate.Time
+	*origin = types.DateSource{Source: candidate.Source, Confidence: candidate.Confidence}
+	return true
+}
+
+// Builds a date from its parts, rejecting days the month does not have
+func dateOf(year, month, day string) (time.Time, bool) {
+	y, _ := strconv.Atoi(year)
+	m, _ := strconv.Atoi(month)
+	d, _ := strconv.Atoi(day)
+	date := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if date.Day() != d {
+		return time.Time{}, false
+	}
+	return date, true
+}
@@ -0,0 +1,74 @@
+package dates
+
+import (
+	"testing"
+	"time"
+	"webcrawler/internal/pkg/types"
+)
+
+// TestFromMeta tests that meta tags are matched by any of their names and trusted by how specific they are.
+func TestFromMeta(t *testing.T) {
+	kind, candidate, ok := FromMeta("Article:Published_Time", "2024-03-05T10:00:00Z")
+	if !ok || kind != Published || candidate.Source != SourceMeta || candidate.Confidence != 0.9 {
+		t.Errorf("Unexpected result %v %+v %v", kind, candidate, ok)
+	}
+	if kind, _, ok := FromMeta("dateModified", "March 6, 2024"); !ok || kind != Modified {
+		t.Errorf("Expected the itemprop dateModified to give a modified date, got %v %v", kind, ok)
+	}
+	_, generic, _ := FromMeta("date", "2024-03-05")
+	if generic.Confidence >= candidate.Confidence {
+		t.Errorf("Expected a bare date to be trusted less, got %v", generic.Confidence)
+	}
+	if _, _, ok := FromMeta("description", "2024-03-05"); ok {
+		t.Error("Expected other meta tags to be ignored")
+	}
+}
+
+// TestFromURL tests dates in URL paths, with and without the day.
+func TestFromURL(t *testing.T) {
+	for rawURL, expected := range map[string]time.Time{
+		"https://example.com/2024/03/05/bridge-opens":  time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC),
+		"https://example.com/news/2024-3-5-bridge":     time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC),
+		"https://example.com/blog/2024/03/bridge.html": time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		candidate, ok := FromURL(rawURL)
+		if !ok || !candidate.Time.Equal(expected) || candidate.Source != SourceURL {
+			t.Errorf("%s: expected %v, got %+v", rawURL, expected, candidate)
+		}
+	}
+	for _, rawURL := range []string{"https://example.com/products/2024", "https://example.com/2024/02/30/", "https://example.com/item/12345678"} {
+		if candidate, ok := FromURL(rawURL); ok && candidate.Confidence > 0.3 {
+			t.Errorf("%s: expected no dated path, got %+v", rawURL, candidate)
+		}
+	}
+}
+
+// TestOffer tests that the most trusted plausible date wins.
+func TestOffer(t *testing.T) {
+	var pageData types.PageData
+	march5 := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	march6 := march5.AddDate(0, 0, 1)
+
+	if !Offer(&pageData, Published, Candidate{Time: march5, Source: SourceURL, Confidence: 0.6}) {
+		t.Error("Expected the first date to be taken")
+	}
+	if Offer(&pageData, Published, Candidate{Time: march6, Source: SourceTime, Confidence: 0.5}) {
+		t.Error("Expected a less trusted date to be refused")
+	}
+	if !Offer(&pageData, Published, Candidate{Time: march6, Source: SourceMeta, Confidence: 0.9}) || !pageData.DatePublished.Equal(march6) {
+		t.Errorf("Expected a more trusted date to replace it, got %v", pageData.DatePublished)
+	}
+	if pageData.PublishedFrom != (types.DateSource{Source: SourceMeta, Confidence: 0.9}) {
+		t.Errorf("Unexpected source %+v", pageData.PublishedFrom)
+	}
+
+	if Offer(&pageData, Modified, Candidate{Time: time.Now().AddDate(1, 0, 0), Confidence: 1}) {
+		t.Error("Expected a date in the future to be refused")
+	}
+	if Offer(&pageData, Modified, Candidate{Time: time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC), Confidence: 1}) {
+		t.Error("Expected a date before the web to be refused")
+	}
+	if !pageData.DateModified.IsZero() {
+		t.Errorf("Expected no modified date, got %v", pageData.DateModified)
+	}
+}
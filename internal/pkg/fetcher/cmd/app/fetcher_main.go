@@ -8,17 +8,20 @@ import (
     "context"
     "webcrawler/internal/pkg/fetcher/fetcher"
     "webcrawler/internal/pkg/types"
+    "webcrawler/internal/pkg/utils"
 )
 
 type Request struct {
-    RequestID string
-    URL       string
+    RequestID       string
+    URL             string
+    Addresses       []string
+    AddressesExpire time.Time
 }
 type Response struct {
-    RequestID  string
-    PageData   types.PageData
-    FetchError string
-    FetchTime  time.Duration
+    RequestID   string
+    PageData    types.PageData
+    FetchResult types.FetchResult
+    FetchTime   time.Duration
 }
 
 // Entry point for the fetcher worker.
@@ -40,19 +43,29 @@ func main() {
             return // worker exit
         }
 
+        if len(request.Addresses) > 0 {
+            if hostname, err := utils.GetHostnameFromURL(request.URL); err == nil {
+                fetcher.SeedAddresses(hostname, request.Addresses, request.AddressesExpire)
+            }
+        }
+
         start := time.Now()
         ctx, cancel := context.WithTimeout(context.Background(), 30 * time.Second)
-        pageData, err := fetcher.Fetch(ctx, request.URL)
+        pageData, fetchResult, err := fetcher.Fetch(ctx, request.URL)
         cancel()
         elapsed := time.Since(start)
 
-        response := Response{
-            RequestID: request.RequestID,
-            PageData:  pageData,
-            FetchTime: elapsed,
+        if err != nil && fetchResult.OK() {
+            // Should not happen, but never report a failed fetch as a success
+            fetchResult.ErrorCategory = types.ErrorUnknown
+            fetchResult.Error = err.Error()
         }
-        if err != nil {
-            response.FetchError = err.Error()
+
+        response := Response{
+            RequestID:   request.RequestID,
+            PageData:    pageData,
+            FetchResult: fetchResult,
+            FetchTime:   elapsed,
         }
 
         if err := enc.Encode(response); err != nil {
@@ -0,0 +1,190 @@
+package fetcher
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"webcrawler/internal/pkg/types"
+	"golang.org/x/net/html"
+)
+
+// Link types that name an icon of the page
+var iconRels = toSet("icon", "apple-touch-icon", "apple-touch-icon-precomposed", "mask-icon")
+
+// Media types of feeds announced with <link rel="alternate">
+var feedMediaTypes = toSet(
+	"application/rss+xml", "application/atom+xml", "application/rdf+xml",
+	"application/feed+json", "application/json+feed",
+)
+
+// Records an image with its source, the other candidates of its srcset, its
+// declared size and alt text. Images given only a srcset take its first
+// candidate as their source.
+func collectImage(node *html.Node, pageData *types.PageData, base *url.URL) {
+	candidates := resolveAll(base, srcsetURLs(getAttribute(node, "srcset")))
+	src := resolveAsset(base, getAttribute(node, "src"))
+	if src == "" && len(candidates) > 0 {
+		src, candidates = candidates[0], candidates[1:]
+	}
+	if src == "" {
+		return
+	}
+	addAsset(pageData, types.Asset{
+		URL:    src,
+		Type:   types.AssetImage,
+		Alt:    normalizeText(getAttribute(node, "alt")),
+		Width:  dimension(getAttribute(node, "width")),
+		Height: dimension(getAttribute(node, "height")),
+		Srcset: withoutURL(candidates, src),
+	})
+}
+
+// Records the source of a <video> or <audio> element, and a video's poster image
+func collectMedia(node *html.Node, pageData *types.PageData, base *url.URL) {
+	assetType := types.AssetVideo
+	if node.Data == "audio" {
+		assetType = types.AssetAudio
+	}
+	if src := resolveAsset(base, getAttribute(node, "src")); src != "" {
+		addAsset(pageData, types.Asset{
+			URL:    src,
+			Type:   assetType,
+			Width:  dimension(getAttribute(node, "width")),
+			Height: dimension(getAttribute(node, "height")),
+		})
+	}
+	if poster := resolveAsset(base, getAttribute(node, "poster")); poster != "" && assetType == types.AssetVideo {
+		addAsset(pageData, types.Asset{URL: poster, Type: types.AssetImage})
+	}
+}
+
+// Records a <source> element: a media source inside <video> or <audio>, or
+// an image candidate inside <picture>
+func collectSource(node *html.Node, pageData *types.PageData, base *url.URL) {
+	if node.Parent == nil {
+		return
+	}
+	declared := strings.ToLower(strings.TrimSpace(getAttribute(node, "type")))
+	switch node.Parent.Data {
+	case "video", "audio":
+		if src := resolveAsset(base, getAttribute(node, "src")); src != "" {
+			assetType := types.AssetVideo
+			if node.Parent.Data == "audio" {
+				assetType = types.AssetAudio
+			}
+			addAsset(pageData, types.Asset{URL: src, Type: assetType, MediaType: declared})
+		}
+	case "picture":
+		candidates := resolveAll(base, srcsetURLs(getAttribute(node, "srcset")))
+		if len(candidates) == 0 {
+			return
+		}
+		addAsset(pageData, types.Asset{
+			URL:       candidates[0],
+			Type:      types.AssetImage,
+			MediaType: declared,
+			Width:     dimension(getAttribute(node, "width")),
+			Height:    dimension(getAttribute(node, "height")),
+			Srcset:    withoutURL(candidates[1:], candidates[0]),
+		})
+	}
+}
+
+// Records icons, feeds and stylesheets declared with <link>
+func collectLinkAsset(node *html.Node, pageData *types.PageData, base *url.URL) {
+	href := resolveAsset(base, getAttribute(node, "href"))
+	if href == "" {
+		return
+	}
+	declared := strings.ToLower(strings.TrimSpace(getAttribute(node, "type")))
+	rels := linkRels(getAttribute(node, "rel"))
+	for _, rel := range rels {
+		if _, icon := iconRels[rel]; icon {
+			width, height := iconSize(getAttribute(node, "sizes"))
+			addAsset(pageData, types.Asset{URL: href, Type: types.AssetIcon, MediaType: declared, Width: width, Height: height})
+			return
+		}
+	}
+	switch {
+	case slices.Contains(rels, "stylesheet"):
+		addAsset(pageData, types.Asset{URL: href, Type: types.AssetStylesheet, MediaType: declared})
+	case slices.Contains(rels, "alternate"):
+		if _, feed := feedMediaTypes[declared]; feed {
+			addAsset(pageData, types.Asset{URL: href, Type: types.AssetFeed, MediaType: declared, Title: normalizeText(getAttribute(node, "title"))})
+		}
+	}
+}
+
+// Records the file an external <script> loads
+func collectScript(node *html.Node, pageData *types.PageData, base *url.URL) {
+	if src := resolveAsset(base, getAttribute(node, "src")); src != "" {
+		declared := strings.ToLower(strings.TrimSpace(getAttribute(node, "type")))
+		addAsset(pageData, types.Asset{URL: src, Type: types.AssetScript, MediaType: declared})
+	}
+}
+
+// Adds an asset unless one of the same type and URL is already recorded
+func addAsset(pageData *types.PageData, asset types.Asset) {
+	for _, existing := range pageData.Assets {
+		if existing.Type == asset.Type && existing.URL == asset.URL {
+			return
+		}
+	}
+	pageData.Assets = append(pageData.Assets, asset)
+}
+
+// Resolves an asset's URL, returning an empty string for missing or unusable ones
+func resolveAsset(base *url.URL, href string) string {
+	if strings.TrimSpace(href) == "" {
+		return ""
+	}
+	resolved, ok := resolveLink(base, href)
+	if !ok {
+		return ""
+	}
+	return resolved.String()
+}
+
+// Resolves every URL in a list, dropping the unusable ones
+func resolveAll(base *url.URL, hrefs []string) []string {
+	resolved := make([]string, 0, len(hrefs))
+	for _, href := range hrefs {
+		if asset := resolveAsset(base, href); asset != "" {
+			resolved = append(resolved, asset)
+		}
+	}
+	return resolved
+}
+
+// Returns the URLs other than the given one
+func withoutURL(urls []string, skip string) []string {
+	var kept []string
+	for _, candidate := range urls {
+		if candidate != skip {
+			kept = append(kept, candidate)
+		}
+	}
+	return kept
+}
+
+// Reads a width or height attribute, which browsers accept with a "px" suffix
+func dimension(value string) int {
+	value = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), "px")
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 {
+		return 0
+	}
+	return size
+}
+
+// Reads the first size of an icon's sizes attribute, such as "32x32 64x64"
+func iconSize(sizes string) (int, int) {
+	for _, size := range strings.Fields(strings.ToLower(sizes)) {
+		width, height, found := strings.Cut(size, "x")
+		if found && dimension(width) > 0 && dimension(height) > 0 {
+			return dimension(width), dimension(height)
+		}
+	}
+	return 0, 0
+}
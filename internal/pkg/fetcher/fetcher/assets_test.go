@@ -0,0 +1,86 @@
+package fetcher
+
+import (
+	"testing"
+	"webcrawler/internal/pkg/types"
+)
+
+// Checks that images, media, icons, feeds, stylesheets and scripts are inventoried with resolved URLs.
+func TestExtractAssets(t *testing.T) {
+	content := `<html><head>
+		<link rel="icon" href="/favicon.png" sizes="32x32 64x64" type="image/png">
+		<link rel="apple-touch-icon" href="/touch.png">
+		<link rel="stylesheet" href="css/site.css">
+		<link rel="alternate" type="application/rss+xml" title=" News  feed " href="/feed.xml">
+		<link rel="alternate" hreflang="fr" href="/fr/">
+		<script src="https://cdn.example/app.js" type="module"></script>
+		<script>var inline = true;</script>
+	</head><body>
+		<img src="/photo.jpg" srcset="/photo.jpg 1x, /photo@2x.jpg 2x" width="640px" height="480" alt="Harbour">
+		<img srcset="/only-srcset.jpg 1x, /only-srcset@2x.jpg 2x">
+		<img src="/photo.jpg" alt="Again">
+		<picture><source srcset="/hero.avif 1x, /hero@2x.avif 2x" type="image/avif"><img src="/hero.jpg"></picture>
+		<video src="/clip.mp4" poster="/poster.jpg" width="1280"><source src="/clip.webm" type="video/webm"></video>
+		<audio><source src="/song.mp3" type="audio/mpeg"></audio>
+		<img src="data:image/png;base64,AAAA">
+	</body></html>`
+	pd, err := traverseAndExtractPageContent(content, "https://example.com/page/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	byURL := make(map[string]types.Asset)
+	for _, asset := range pd.Assets {
+		byURL[asset.URL] = asset
+	}
+	expected := map[string]types.Asset{
+		"https://example.com/favicon.png":       {Type: types.AssetIcon, MediaType: "image/png", Width: 32, Height: 32},
+		"https://example.com/touch.png":         {Type: types.AssetIcon},
+		"https://example.com/page/css/site.css": {Type: types.AssetStylesheet},
+		"https://example.com/feed.xml":          {Type: types.AssetFeed, MediaType: "application/rss+xml", Title: "News feed"},
+		"https://cdn.example/app.js":            {Type: types.AssetScript, MediaType: "module"},
+		"https://example.com/photo.jpg":         {Type: types.AssetImage, Alt: "Harbour", Width: 640, Height: 480},
+		"https://example.com/only-srcset.jpg":   {Type: types.AssetImage},
+		"https://example.com/hero.avif":         {Type: types.AssetImage, MediaType: "image/avif"},
+		"https://example.com/hero.jpg":          {Type: types.AssetImage},
+		"https://example.com/clip.mp4":          {Type: types.AssetVideo, Width: 1280},
+		"https://example.com/poster.jpg":        {Type: types.AssetImage},
+		"https://example.com/clip.webm":         {Type: types.AssetVideo, MediaType: "video/webm"},
+		"https://example.com/song.mp3":          {Type: types.AssetAudio, MediaType: "audio/mpeg"},
+	}
+	if len(pd.Assets) != len(expected) {
+		t.Errorf("expected %d assets, got %d: %+v", len(expected), len(pd.Assets), pd.Assets)
+	}
+	for link, want := range expected {
+		got, found := byURL[link]
+		if !found {
+			t.Errorf("missing asset %s", link)
+			continue
+		}
+		if got.Type != want.Type || got.MediaType != want.MediaType || got.Alt != want.Alt || got.Title != want.Title ||
+			got.Width != want.Width || got.Height != want.Height {
+			t.Errorf("unexpected asset for %s: %+v", link, got)
+		}
+	}
+	if srcset := byURL["https://example.com/photo.jpg"].Srcset; len(srcset) != 1 || srcset[0] != "https://example.com/photo@2x.jpg" {
+		t.Errorf("unexpected srcset %v", srcset)
+	}
+	if srcset := byURL["https://example.com/only-srcset.jpg"].Srcset; len(srcset) != 1 || srcset[0] != "https://example.com/only-srcset@2x.jpg" {
+		t.Errorf("unexpected srcset %v", srcset)
+	}
+	if _, found := byURL["https://example.com/fr/"]; found {
+		t.Error("expected hreflang alternates not to be assets")
+	}
+}
+
+// Checks that width, height and icon sizes are read leniently.
+func TestAssetDimensions(t *testing.T) {
+	for value, expected := range map[string]int{"640": 640, " 480px ": 480, "50%": 0, "-1": 0, "": 0} {
+		if size := dimension(value); size != expected {
+			t.Errorf("expected %q to give %d, got %d", value, expected, size)
+		}
+	}
+	if width, height := iconSize("any 16X24"); width != 16 || height != 24 {
+		t.Errorf("expected 16x24, got %dx%d", width, height)
+	}
+}
@@ -0,0 +1,189 @@
+package fetcher
+
+import (
+	"bytes"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/saintfish/chardet"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Where the character encoding of a page was determined from
+const (
+	charsetSourceBOM         = "bom"
+	charsetSourceHeader      = "header"
+	charsetSourceMeta        = "meta"
+	charsetSourceXML         = "xml_declaration"
+	charsetSourceStatistical = "statistical"
+	charsetSourceDefault     = "default"
+)
+
+const (
+	charsetPrescanBytes    = 1024 // How much of the document is searched for <meta charset>
+	minDetectionConfidence = 30   // Lowest chardet confidence (0-100) that is trusted
+	defaultCharset         = "windows-1252"
+)
+
+var xmlDeclarationEncoding = regexp.MustCompile(`^<\?xml[^>]*\sencoding\s*=\s*["']([^"']+)["']`)
+
+var byteOrderMarks = []struct {
+	bom  []byte
+	name string
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, "utf-8"},
+	{[]byte{0xFE, 0xFF}, "utf-16be"},
+	{[]byte{0xFF, 0xFE}, "utf-16le"},
+}
+
+// Decodes a response body to UTF-8. The encoding is taken from, in order of
+// precedence, a byte order mark, the Content-Type header, an XML declaration,
+// a <meta> prescan of the first 1024 bytes and finally statistical detection,
+// following the HTML standard's encoding sniffing algorithm. Returns the
+// content along with the canonical name of the encoding used and where it
+// came from.
+func decodeBody(body []byte, contentType string) (string, string, string) {
+	encoding, name, source := determineCharset(body, contentType)
+	if source == charsetSourceBOM {
+		for _, mark := range byteOrderMarks {
+			if mark.name == name {
+				body = body[len(mark.bom):]
+				break
+			}
+		}
+	}
+	return transcode(body, encoding, name), name, source
+}
+
+// Works out which encoding a body uses, see decodeBody.
+func determineCharset(body []byte, contentType string) (encoding.Encoding, string, string) {
+	for _, mark := range byteOrderMarks {
+		if bytes.HasPrefix(body, mark.bom) {
+			encoding, name := lookupCharset(mark.name)
+			return encoding, name, charsetSourceBOM
+		}
+	}
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if encoding, name := lookupCharset(params["charset"]); encoding != nil {
+			return encoding, name, charsetSourceHeader
+		}
+	}
+
+	if match := xmlDeclarationEncoding.FindSubmatch(bytes.TrimLeft(body, " \t\r\n")); match != nil {
+		if encoding, name := lookupCharset(string(match[1])); encoding != nil {
+			return encoding, name, charsetSourceXML
+		}
+	}
+
+	if encoding, name := prescanMetaCharset(body); encoding != nil {
+		return encoding, name, charsetSourceMeta
+	}
+
+	if encoding, name := detectCharset(body); encoding != nil {
+		return encoding, name, charsetSourceStatistical
+	}
+
+	encoding, name := lookupCharset(defaultCharset)
+	return encoding, name, charsetSourceDefault
+}
+
+// Looks up an encoding by any of its WHATWG labels, e.g. "latin1" or "sjis".
+// Returns nil if the label is unknown.
+func lookupCharset(label string) (encoding.Encoding, string) {
+	label = strings.Trim(strings.TrimSpace(label), `"'`)
+	if label == "" {
+		return nil, ""
+	}
+	return charset.Lookup(label)
+}
+
+// Searches the start of the document for <meta charset> or
+// <meta http-equiv="Content-Type" content="...; charset=...">.
+func prescanMetaCharset(body []byte) (encoding.Encoding, string) {
+	if len(body) > charsetPrescanBytes {
+		body = body[:charsetPrescanBytes]
+	}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return nil, ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data != "meta" {
+				continue
+			}
+			var charsetAttr, httpEquiv, content string
+			for _, attr := range token.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "charset":
+					charsetAttr = attr.Val
+				case "http-equiv":
+					httpEquiv = strings.ToLower(strings.TrimSpace(attr.Val))
+				case "content":
+					content = attr.Val
+				}
+			}
+			label := charsetAttr
+			if label == "" && httpEquiv == "content-type" {
+				if _, params, err := mime.ParseMediaType(content); err == nil {
+					label = params["charset"]
+				}
+			}
+			encoding, name := lookupCharset(label)
+			if encoding == nil {
+				continue
+			}
+			// A document that could be read as ASCII to find this tag is not UTF-16
+			if strings.HasPrefix(name, "utf-16") {
+				return lookupCharset("utf-8")
+			}
+			return encoding, name
+		}
+	}
+}
+
+// Guesses the encoding from the byte distribution when nothing declares it.
+// Valid UTF-8 is accepted as-is since other encodings rarely produce it.
+func detectCharset(body []byte) (encoding.Encoding, string) {
+	if utf8.Valid(body) {
+		return lookupCharset("utf-8")
+	}
+
+	result, err := chardet.NewHtmlDetector().DetectBest(body)
+	if err != nil || result.Confidence < minDetectionConfidence {
+		return nil, ""
+	}
+	if encoding, name := lookupCharset(result.Charset); encoding != nil {
+		return encoding, name
+	}
+	// chardet names a few encodings differently, e.g. "GB-18030"
+	return lookupCharset(strings.ReplaceAll(result.Charset, "-", ""))
+}
+
+// Converts a body to UTF-8, replacing any invalid sequences with U+FFFD.
+func transcode(body []byte, bodyEncoding encoding.Encoding, name string) string {
+	if name == "utf-8" {
+		return strings.ToValidUTF8(string(body), string(utf8.RuneError))
+	}
+	if name == "utf-16be" || name == "utf-16le" {
+		endianness := unicode.BigEndian
+		if name == "utf-16le" {
+			endianness = unicode.LittleEndian
+		}
+		bodyEncoding = unicode.UTF16(endianness, unicode.IgnoreBOM)
+	}
+	decoded, err := bodyEncoding.NewDecoder().Bytes(body)
+	if err != nil {
+		// Decoders only fail on internal errors, fall back to replacing invalid bytes
+		return strings.ToValidUTF8(string(body), string(utf8.RuneError))
+	}
+	return string(decoded)
+}
@@ -0,0 +1,102 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Encodes UTF-8 text for use as a test response body.
+func mustEncode(t *testing.T, target encoding.Encoding, text string) []byte {
+	t.Helper()
+	encoded, err := target.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatalf("failed to encode test body: %v", err)
+	}
+	return encoded
+}
+
+// Checks each source of charset information and its precedence.
+func TestDecodeBody(t *testing.T) {
+	japaneseText := strings.Repeat("日本語のテキストです。これは文字コードの検出テストです。", 10)
+	shiftJIS := mustEncode(t, japanese.ShiftJIS, japaneseText)
+	latin := mustEncode(t, charmap.Windows1252, "Café crème brûlée")
+
+	tests := []struct {
+		name           string
+		body           []byte
+		contentType    string
+		expectedText   string
+		expectedName   string
+		expectedSource string
+	}{
+		{"header", shiftJIS, "text/html; charset=Shift_JIS", japaneseText, "shift_jis", charsetSourceHeader},
+		{"header label alias", latin, "text/html; charset=ISO-8859-1", "Café crème brûlée", "windows-1252", charsetSourceHeader},
+		{"utf-8 bom beats header", append([]byte{0xEF, 0xBB, 0xBF}, "Café"...), "text/html; charset=windows-1252", "Café", "utf-8", charsetSourceBOM},
+		{"utf-16le bom", mustEncode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), "Café"), "", "Café", "utf-16le", charsetSourceBOM},
+		{"meta charset", append([]byte(`<html><head><meta charset="windows-1252"></head><body>`), latin...), "text/html", `<html><head><meta charset="windows-1252"></head><body>Café crème brûlée`, "windows-1252", charsetSourceMeta},
+		{"meta http-equiv", append([]byte(`<meta http-equiv="Content-Type" content="text/html; charset=shift_jis">`), shiftJIS...), "", `<meta http-equiv="Content-Type" content="text/html; charset=shift_jis">` + japaneseText, "shift_jis", charsetSourceMeta},
+		{"meta utf-16 means utf-8", []byte(`<meta charset="utf-16">Café`), "", `<meta charset="utf-16">Café`, "utf-8", charsetSourceMeta},
+		{"unknown header label ignored", []byte("Café"), "text/html; charset=bogus", "Café", "utf-8", charsetSourceStatistical},
+		{"statistical shift_jis", shiftJIS, "text/html", japaneseText, "shift_jis", charsetSourceStatistical},
+		{"plain ascii", []byte("<p>Hello</p>"), "text/html", "<p>Hello</p>", "utf-8", charsetSourceStatistical},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			text, name, source := decodeBody(tc.body, tc.contentType)
+			if text != tc.expectedText {
+				t.Errorf("expected text %q, got %q", tc.expectedText, text)
+			}
+			if name != tc.expectedName {
+				t.Errorf("expected charset %q, got %q", tc.expectedName, name)
+			}
+			if source != tc.expectedSource {
+				t.Errorf("expected source %q, got %q", tc.expectedSource, source)
+			}
+		})
+	}
+}
+
+// Checks that invalid bytes in a declared UTF-8 page are replaced rather than rejected.
+func TestDecodeBodyInvalidUTF8(t *testing.T) {
+	text, name, _ := decodeBody([]byte("ok \xff\xfe done"), "text/html; charset=utf-8")
+	if name != "utf-8" {
+		t.Errorf("expected utf-8, got %q", name)
+	}
+	if text != "ok � done" {
+		t.Errorf("expected invalid bytes to be replaced, got %q", text)
+	}
+}
+
+// Checks that a Windows-1252 page is transcoded and its charset reported in PageData.
+func TestFetchTranscodesToUTF8(t *testing.T) {
+	Init()
+	body := mustEncode(t, charmap.Windows1252, `<html lang="en"><head><title>Café Menu</title><meta charset="iso-8859-1"></head><body><p>Crème brûlée</p></body></html>`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	pageData, result, err := Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch returned unexpected error: %v", err)
+	}
+	if pageData.Title != "Café Menu" {
+		t.Errorf("expected transcoded title %q, got %q", "Café Menu", pageData.Title)
+	}
+	if pageData.Charset != "windows-1252" {
+		t.Errorf("expected PageData.Charset windows-1252, got %q", pageData.Charset)
+	}
+	if result.CharsetSource != charsetSourceMeta {
+		t.Errorf("expected charset from meta, got %q", result.CharsetSource)
+	}
+}
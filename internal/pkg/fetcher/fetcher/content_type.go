@@ -0,0 +1,144 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+	"webcrawler/internal/pkg/types"
+)
+
+const (
+	headCheckTimeout = 5 * time.Second
+	sniffBytes       = 512 // Same amount http.DetectContentType looks at
+)
+
+var ErrUnwantedContentType = errors.New("unwanted content type")
+
+var (
+	// URLs ending in these are never requested since no handler accepts them
+	skipExtensions = toSet(
+		".jpg", ".jpeg", ".png", ".gif", ".webp", ".svg", ".ico", ".bmp", ".tif", ".tiff", ".avif",
+		".mp3", ".wav", ".ogg", ".flac", ".m4a", ".mp4", ".m4v", ".mov", ".avi", ".mkv", ".webm", ".wmv",
+		".zip", ".gz", ".tgz", ".bz2", ".xz", ".7z", ".rar", ".tar", ".exe", ".msi", ".dmg", ".iso", ".apk", ".bin", ".deb", ".rpm",
+		".woff", ".woff2", ".ttf", ".otf", ".eot",
+		".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx",
+		".json", ".csv", ".js", ".css",
+	)
+
+	// URLs ending in these are fetched straight away. Anything else gets a
+	// HEAD request first so large unwanted downloads are avoided.
+	fetchExtensions = toSet(
+		"", ".html", ".htm", ".xhtml", ".shtml", ".php", ".asp", ".aspx", ".jsp", ".cgi", ".pl",
+		".txt", ".xml", ".rss", ".atom", ".rdf", ".pdf",
+	)
+)
+
+// Builds a lookup set from a list of strings
+func toSet(values ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+	return set
+}
+
+// Returns the lower-cased media type of a Content-Type header, without parameters
+func mediaTypeOf(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// Fall back to everything before the first parameter
+		mediaType = strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	}
+	return strings.ToLower(mediaType)
+}
+
+// Reports whether the header gives no useful type, so the body must be sniffed
+func isGenericMediaType(mediaType string) bool {
+	return mediaType == "" || mediaType == "application/octet-stream" || mediaType == "binary/octet-stream"
+}
+
+// Guesses the media type from the start of the body
+func sniffMediaType(body []byte) string {
+	if len(body) > sniffBytes {
+		body = body[:sniffBytes]
+	}
+	trimmed := bytes.TrimLeft(body, "\xef\xbb\xbf \t\r\n")
+	if bytes.HasPrefix(trimmed, []byte("<?xml")) {
+		// DetectContentType reports all XML as text/xml, including XHTML
+		if bytes.Contains(bytes.ToLower(trimmed), []byte("<html")) {
+			return "application/xhtml+xml"
+		}
+		return "text/xml"
+	}
+	return mediaTypeOf(http.DetectContentType(body))
+}
+
+// Reports whether a media type is text that should be transcoded to UTF-8
+func isTextMediaType(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "text/") || mediaType == "application/xml" ||
+		strings.HasSuffix(mediaType, "+xml")
+}
+
+// Decides from the URL alone whether to skip it or to check it with HEAD first.
+// Assets of the types being fetched are requested straight away.
+func extensionPolicy(fullURL string) (skip bool, headCheck bool) {
+	parsed, err := url.Parse(fullURL)
+	if err != nil {
+		return false, false
+	}
+	extension := strings.ToLower(path.Ext(parsed.Path))
+	if fetchesAsset(assetExtensions[extension]) {
+		return false, false
+	}
+	if _, exists := skipExtensions[extension]; exists {
+		return true, false
+	}
+	_, exists := fetchExtensions[extension]
+	return false, !exists
+}
+
+// Sends a HEAD request and reports the media type the server would return.
+// Returns an empty string if the server does not answer HEAD usefully.
+func headMediaType(context context.Context, fullURL string) string {
+	request, err := http.NewRequestWithContext(context, http.MethodHead, fullURL, nil)
+	if err != nil {
+		return ""
+	}
+	setIdentityHeaders(request)
+
+	client := *httpClient
+	client.Timeout = headCheckTimeout
+	response, err := client.Do(request)
+	if err != nil {
+		return ""
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return ""
+	}
+	return mediaTypeOf(response.Header.Get("Content-Type"))
+}
+
+// Rejects a URL before its body is downloaded if its type cannot be handled
+func checkWantedURL(context context.Context, fullURL string, result *types.FetchResult) error {
+	skip, headCheck := extensionPolicy(fullURL)
+	if skip {
+		return failResult(result, types.ErrorFiltered, fmt.Errorf("%w: skipped by URL extension", ErrUnwantedContentType))
+	}
+	if !headCheck {
+		return nil
+	}
+	mediaType := headMediaType(context, fullURL)
+	if !isGenericMediaType(mediaType) && handlerFor(mediaType) == nil {
+		result.MediaType = mediaType
+		return failResult(result, types.ErrorFiltered, fmt.Errorf("%w: %s", ErrUnwantedContentType, mediaType))
+	}
+	return nil
+}
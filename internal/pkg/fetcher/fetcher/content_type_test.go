@@ -0,0 +1,154 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"webcrawler/internal/pkg/types"
+)
+
+// Checks which URLs are skipped, fetched directly or checked with HEAD first.
+func TestExtensionPolicy(t *testing.T) {
+	tests := []struct {
+		url       string
+		skip      bool
+		headCheck bool
+	}{
+		{"https://example.com/", false, false},
+		{"https://example.com/about", false, false},
+		{"https://example.com/index.PHP?id=3", false, false},
+		{"https://example.com/report.pdf", false, false},
+		{"https://example.com/feed.xml", false, false},
+		{"https://example.com/photo.JPG", true, false},
+		{"https://example.com/data.json", true, false},
+		{"https://example.com/archive.tar.gz", true, false},
+		{"https://example.com/download.dat", false, true},
+		{"https://example.com/story.12345", false, true},
+	}
+
+	for _, tc := range tests {
+		skip, headCheck := extensionPolicy(tc.url)
+		if skip != tc.skip || headCheck != tc.headCheck {
+			t.Errorf("%s: expected skip=%v headCheck=%v, got skip=%v headCheck=%v", tc.url, tc.skip, tc.headCheck, skip, headCheck)
+		}
+	}
+}
+
+// Checks media type detection from bodies without a useful Content-Type.
+func TestSniffMediaType(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{"<!DOCTYPE html><html><body>Hi</body></html>", "text/html"},
+		{"%PDF-1.4\n%...", "application/pdf"},
+		{`<?xml version="1.0"?><rss version="2.0"></rss>`, "text/xml"},
+		{`<?xml version="1.0"?><html xmlns="http://www.w3.org/1999/xhtml"></html>`, "application/xhtml+xml"},
+		{"Just some text", "text/plain"},
+		{"\x89PNG\r\n\x1a\n\x00\x00", "image/png"},
+	}
+
+	for _, tc := range tests {
+		if got := sniffMediaType([]byte(tc.body)); got != tc.expected {
+			t.Errorf("sniffMediaType(%q) = %q, expected %q", tc.body, got, tc.expected)
+		}
+	}
+}
+
+// Checks that an unwanted Content-Type is rejected without reading the body.
+func TestFetchContentUnwantedType(t *testing.T) {
+	Init()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(strings.Repeat(`{"a":1}`, 1000)))
+	}))
+	defer server.Close()
+
+	_, result, err := fetchContent(context.Background(), server.URL + "/api")
+	if !errors.Is(err, ErrUnwantedContentType) {
+		t.Fatalf("expected ErrUnwantedContentType, got %v", err)
+	}
+	if result.ErrorCategory != types.ErrorFiltered {
+		t.Errorf("expected category %q, got %q", types.ErrorFiltered, result.ErrorCategory)
+	}
+	if result.BytesRead != 0 {
+		t.Errorf("expected body not to be read, got %d bytes", result.BytesRead)
+	}
+}
+
+// Checks that URLs with unwanted extensions are never requested.
+func TestFetchContentSkipsByExtension(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	_, result, err := fetchContent(context.Background(), server.URL + "/image.png")
+	if !errors.Is(err, ErrUnwantedContentType) || result.ErrorCategory != types.ErrorFiltered {
+		t.Errorf("expected filtered ErrUnwantedContentType, got %v (%q)", err, result.ErrorCategory)
+	}
+	if count := requests.Load(); count != 0 {
+		t.Errorf("expected no requests, got %d", count)
+	}
+}
+
+// Checks that unknown extensions are checked with HEAD before downloading.
+func TestFetchContentHeadCheck(t *testing.T) {
+	var gets atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".zipfile") {
+			w.Header().Set("Content-Type", "application/zip")
+		} else {
+			w.Header().Set("Content-Type", "text/html")
+		}
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+			w.Write([]byte("<html><title>Page</title></html>"))
+		}
+	}))
+	defer server.Close()
+
+	_, result, err := fetchContent(context.Background(), server.URL + "/download.zipfile")
+	if !errors.Is(err, ErrUnwantedContentType) {
+		t.Errorf("expected ErrUnwantedContentType, got %v", err)
+	}
+	if result.MediaType != "application/zip" {
+		t.Errorf("expected media type from HEAD, got %q", result.MediaType)
+	}
+	if count := gets.Load(); count != 0 {
+		t.Errorf("expected no GET after HEAD rejected the type, got %d", count)
+	}
+
+	if _, _, err := fetchContent(context.Background(), server.URL + "/story.12345"); err != nil {
+		t.Errorf("expected HTML behind unknown extension to be fetched, got %v", err)
+	}
+	if count := gets.Load(); count != 1 {
+		t.Errorf("expected one GET, got %d", count)
+	}
+}
+
+// Checks that a generic Content-Type is resolved by sniffing the body.
+func TestFetchSniffsGenericContentType(t *testing.T) {
+	Init()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte(`<!DOCTYPE html><html lang="en"><head><title>Sniffed</title></head><body>Body</body></html>`))
+	}))
+	defer server.Close()
+
+	pageData, result, err := Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch returned unexpected error: %v", err)
+	}
+	if result.MediaType != "text/html" || pageData.MediaType != "text/html" {
+		t.Errorf("expected sniffed text/html, got %q and %q", result.MediaType, pageData.MediaType)
+	}
+	if pageData.Title != "Sniffed" {
+		t.Errorf("expected title %q, got %q", "Sniffed", pageData.Title)
+	}
+}
@@ -0,0 +1,106 @@
+package fetcher
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	acceptEncoding      = "gzip, deflate, br"
+	maxCompressionRatio = 100       // Decoded bytes allowed per byte on the wire
+	ratioCheckThreshold = 64 * 1024 // Small bodies are never treated as bombs
+	readChunkSize       = 32 * 1024
+)
+
+// Counts the bytes read from the connection, before any decoding
+type countingReader struct {
+	reader io.Reader
+	count  int64
+	err    error // Last error from the underlying reader, to tell network and decoding errors apart
+}
+
+func (counter *countingReader) Read(buffer []byte) (int, error) {
+	n, err := counter.reader.Read(buffer)
+	counter.count += int64(n)
+	if err != nil && err != io.EOF {
+		counter.err = err
+	}
+	return n, err
+}
+
+// Wraps the body in decoders for each Content-Encoding, applied in reverse
+// order of how the server encoded it.
+func newDecodingReader(body io.Reader, contentEncoding string) (io.Reader, error) {
+	var codings []string
+	for _, coding := range strings.Split(contentEncoding, ",") {
+		if coding = strings.ToLower(strings.TrimSpace(coding)); coding != "" && coding != "identity" {
+			codings = append(codings, coding)
+		}
+	}
+
+	reader := body
+	for i := len(codings) - 1; i >= 0; i-- {
+		var err error
+		switch codings[i] {
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(reader)
+		case "deflate":
+			reader, err = newDeflateReader(reader)
+		case "br":
+			reader = brotli.NewReader(reader)
+		default:
+			return nil, fmt.Errorf("unsupported content encoding %q", codings[i])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s stream: %v", codings[i], err)
+		}
+	}
+	return reader, nil
+}
+
+// HTTP deflate is meant to be zlib wrapped, but some servers send raw
+// deflate data, so check for a zlib header before choosing a decoder.
+func newDeflateReader(body io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(body)
+	header, err := buffered.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0] & 0x0f == 8 && (uint16(header[0]) << 8 | uint16(header[1])) % 31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// Reads up to limit decoded bytes, reporting whether more were available.
+// Fails with ErrDecompressionBomb once the output grows past
+// maxCompressionRatio times the bytes received on the wire.
+func readDecoded(decoded io.Reader, wire *countingReader, limit int64) ([]byte, bool, error) {
+	var body []byte
+	chunk := make([]byte, readChunkSize)
+	for {
+		n, err := decoded.Read(chunk)
+		body = append(body, chunk[:n]...)
+
+		size := int64(len(body))
+		if size > ratioCheckThreshold && size > maxCompressionRatio * max(wire.count, 1) {
+			return body, false, fmt.Errorf("%w: %d bytes decoded from %d on the wire", ErrDecompressionBomb, size, wire.count)
+		}
+		if size > limit {
+			return body[:limit], true, nil
+		}
+		if err == io.EOF {
+			return body, false, nil
+		}
+		if err != nil {
+			return body, false, err
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"webcrawler/internal/pkg/types"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Compresses data with the named content coding.
+func compress(t *testing.T, coding string, data []byte) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	var writer io.WriteCloser
+	switch coding {
+	case "gzip":
+		writer = gzip.NewWriter(&buffer)
+	case "deflate":
+		writer, _ = zlib.NewWriterLevel(&buffer, zlib.BestCompression)
+	case "raw-deflate":
+		writer, _ = flate.NewWriter(&buffer, flate.BestCompression)
+	case "br":
+		writer = brotli.NewWriter(&buffer)
+	default:
+		t.Fatalf("unknown coding %q", coding)
+	}
+	writer.Write(data)
+	writer.Close()
+	return buffer.Bytes()
+}
+
+// Serves a fixed body with the given Content-Encoding, recording the Accept-Encoding sent.
+func newEncodedServer(body []byte, contentEncoding string, acceptEncoding *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if acceptEncoding != nil {
+			*acceptEncoding = r.Header.Get("Accept-Encoding")
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if contentEncoding != "" {
+			w.Header().Set("Content-Encoding", contentEncoding)
+		}
+		w.Write(body)
+	}))
+}
+
+// Checks that each supported coding is requested, decoded and measured.
+func TestFetchContentDecompresses(t *testing.T) {
+	Init()
+	page := strings.Repeat("<p>Compressible content</p>", 200)
+
+	for _, coding := range []string{"gzip", "deflate", "raw-deflate", "br"} {
+		t.Run(coding, func(t *testing.T) {
+			contentEncoding := coding
+			if coding == "raw-deflate" {
+				contentEncoding = "deflate"
+			}
+			encoded := compress(t, coding, []byte(page))
+
+			var acceptEncoding string
+			server := newEncodedServer(encoded, contentEncoding, &acceptEncoding)
+			defer server.Close()
+
+			content, result, err := fetchContent(context.Background(), server.URL)
+			if err != nil {
+				t.Fatalf("fetchContent returned error: %v", err)
+			}
+			if acceptEncoding != "gzip, deflate, br" {
+				t.Errorf("expected Accept-Encoding %q, got %q", "gzip, deflate, br", acceptEncoding)
+			}
+			if content != page {
+				t.Errorf("decoded content does not match the original page")
+			}
+			if result.WireBytes != int64(len(encoded)) {
+				t.Errorf("expected %d wire bytes, got %d", len(encoded), result.WireBytes)
+			}
+			if result.BytesRead != int64(len(page)) {
+				t.Errorf("expected %d decoded bytes, got %d", len(page), result.BytesRead)
+			}
+			if result.ContentEncoding != contentEncoding {
+				t.Errorf("expected content encoding %q, got %q", contentEncoding, result.ContentEncoding)
+			}
+		})
+	}
+}
+
+// Checks that the size limit applies to decoded bytes, not compressed bytes.
+func TestFetchContentDecodedSizeLimit(t *testing.T) {
+	Init()
+	// About 3 MB of text that compresses far below the ratio limit's trigger
+	var page strings.Builder
+	for i := 0; page.Len() < maxBodySize + 1024 * 1024; i++ {
+		page.WriteString("<p>paragraph number ")
+		page.WriteString(strings.Repeat(string(rune('a' + i % 26)), i % 50))
+		page.WriteString("</p>\n")
+	}
+	encoded := compress(t, "gzip", []byte(page.String()))
+	if len(encoded) >= maxBodySize {
+		t.Fatalf("test body should compress below the limit, got %d bytes", len(encoded))
+	}
+
+	server := newEncodedServer(encoded, "gzip", nil)
+	defer server.Close()
+
+	content, result, err := fetchContent(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("fetchContent returned error: %v", err)
+	}
+	if len(content) != maxBodySize || !result.Truncated {
+		t.Errorf("expected decoded content truncated to %d bytes, got %d (truncated=%v)", maxBodySize, len(content), result.Truncated)
+	}
+	if result.WireBytes >= result.BytesRead {
+		t.Errorf("expected fewer wire bytes than decoded bytes, got %d and %d", result.WireBytes, result.BytesRead)
+	}
+}
+
+// Checks that a decompression bomb is rejected by the ratio limit.
+func TestFetchContentDecompressionBomb(t *testing.T) {
+	Init()
+	bomb := compress(t, "gzip", bytes.Repeat([]byte{0}, 10 * 1024 * 1024))
+	server := newEncodedServer(bomb, "gzip", nil)
+	defer server.Close()
+
+	_, result, err := fetchContent(context.Background(), server.URL)
+	if !errors.Is(err, ErrDecompressionBomb) {
+		t.Fatalf("expected ErrDecompressionBomb, got %v", err)
+	}
+	if result.ErrorCategory != types.ErrorParse {
+		t.Errorf("expected category %q, got %q", types.ErrorParse, result.ErrorCategory)
+	}
+	if result.BytesRead > maxBodySize {
+		t.Errorf("expected decoding to stop early, read %d bytes", result.BytesRead)
+	}
+}
+
+// Checks unsupported and corrupt encodings are reported as parse errors.
+func TestFetchContentBadEncoding(t *testing.T) {
+	Init()
+	tests := []struct {
+		name            string
+		body            []byte
+		contentEncoding string
+	}{
+		{"unsupported", []byte("data"), "compress"},
+		{"corrupt gzip", []byte("definitely not gzip"), "gzip"},
+		{"corrupt brotli", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "br"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newEncodedServer(tc.body, tc.contentEncoding, nil)
+			defer server.Close()
+
+			_, result, err := fetchContent(context.Background(), server.URL)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if result.ErrorCategory != types.ErrorParse {
+				t.Errorf("expected category %q, got %q", types.ErrorParse, result.ErrorCategory)
+			}
+		})
+	}
+}
+
+// Checks that stacked codings are undone in reverse order.
+func TestNewDecodingReaderStacked(t *testing.T) {
+	original := []byte("stacked encodings")
+	encoded := compress(t, "br", compress(t, "gzip", original))
+
+	reader, err := newDecodingReader(bytes.NewReader(encoded), "gzip, br")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, original) {
+		t.Errorf("expected %q, got %q", original, decoded)
+	}
+}
@@ -3,26 +3,18 @@ package fetcher
 import (
 	"bufio"
 	"bytes"
-	"errors"
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
+	"webcrawler/internal/pkg/dates"
+	"webcrawler/internal/pkg/robots"
+	"webcrawler/internal/pkg/structured"
 	"webcrawler/internal/pkg/types"
 	"golang.org/x/net/html"
 )
 
-var (
-	socialDomains = map[string]struct{}{
-		"facebook.com": {},
-		"twitter.com":  {},
-		"x.com":        {},
-		"instagram.com":{},
-		"linkedin.com": {},
-	}
-	filterTerms = [6]string{"xxx", "porn", "sex", "onlyfans", "gore", "hentai"}
-)
-
 // Remains but ensure it uses context with timeout
 func traverseAndExtractPageContent(content, baseURL string) (types.PageData, error) {
 	var pageData types.PageData
@@ -45,10 +37,6 @@ func traverseAndExtractPageContent(content, baseURL string) (types.PageData, err
 
 	pageData.IsSecure = baseParsed.Scheme == "https"
 
-	if !isEnglishContent(doc) {
-		return pageData, errors.New("non-English content")
-	}
-
 	var (
 		textBuf       bytes.Buffer
 		linkBuffer    = make([]string, 0, 50)
@@ -78,23 +66,20 @@ func traverseAndExtractPageContent(content, baseURL string) (types.PageData, err
 	pageData.InternalLinks = linkBuffer
 	pageData.ExternalLinks = externalLinks
 	pageData.SocialLinks = filterSocialLinks(externalLinks)
-
-	return pageData, nil
-}
-
-// Determines if the content is in English based on the HTML lang attribute
-func isEnglishContent(doc *html.Node) bool {
-	htmlNode := findHTMLNode(doc)
-	if htmlNode == nil {
-		return true
-	}
-	for _, attr := range htmlNode.Attr {
-		if strings.EqualFold(attr.Key, "lang") {
-			lang := strings.ToLower(strings.SplitN(attr.Val, "-", 2)[0])
-			return lang == "en"
+	for i, block := range pageData.StructuredData {
+		entities, err := structured.ParseJSONLD(block)
+		if err != nil {
+			pageData.JSONLDErrors = append(pageData.JSONLDErrors, fmt.Sprintf("block %d: %v", i + 1, err))
+			continue
 		}
+		pageData.Entities = append(pageData.Entities, entities...)
 	}
-	return true
+	pageData.Entities = append(pageData.Entities, structured.ExtractHTML(doc, baseParsed)...)
+	structured.Promote(&pageData)
+	extractDates(doc, &pageData, baseURL)
+	extractMainContent(doc, &pageData, baseParsed)
+
+	return pageData, nil
 }
 
 // Finds the root <html> node in the document
@@ -144,21 +129,35 @@ func handleElement(node *html.Node, pageData *types.PageData, base *url.URL,
 		case "html":
 			handleHtmlTag(node, pageData)
 		case "title":
-			if err := handleTitle(node, pageData); err != nil {
-				return err // Propagate error upward
-			}
+			handleTitle(node, pageData)
 		case "meta":
 			parseMetaTags(node, pageData)
+			parseMetaRefresh(node, pageData, base)
+			parseRobotsMeta(node, pageData)
 		case "a":
 			processAnchor(node, pageData, base, internalLinks, externalLinks)
+		case "area":
+			processArea(node, pageData, base, internalLinks, externalLinks)
+		case "iframe":
+			processIframe(node, pageData, base)
 		case "img":
 			parseImage(node, pageData)
+			processSrcset(node, pageData, base)
+			collectImage(node, pageData, base)
+		case "source":
+			processSrcset(node, pageData, base)
+			collectSource(node, pageData, base)
+		case "video", "audio":
+			collectMedia(node, pageData, base)
 		case "h1", "h2", "h3", "h4", "h5", "h6":
 			storeHeading(node, pageData)
 		case "link":
 			parseLink(node, pageData, base)
+			processPagination(node, pageData, base, internalLinks, externalLinks)
+			collectLinkAsset(node, pageData, base)
 		case "script":
 			parseScript(node, pageData)
+			collectScript(node, pageData, base)
 	}
 	return nil
 }
@@ -175,23 +174,16 @@ func handleHtmlTag(node *html.Node, pageData *types.PageData) {
     pageData.Language = "unspecified"
 }
 
-// Handles the <title> tag to extract the page title and check for filtered terms
-func handleTitle(node *html.Node, pageData *types.PageData) error {
+// Handles the <title> tag to extract the page title
+func handleTitle(node *html.Node, pageData *types.PageData) {
     pageData.Title = extractNodeText(node)
-    if pageData.Title == "" {
-        return nil
-    }
-    if err := checkTitleFilter(pageData.Title); err != nil {
-        return fmt.Errorf("title contians filtered terms: %w", err)
-    }
-    return nil
 }
 
 // Parses meta tags for various types of metadata
 func parseMetaTags(node *html.Node, pageData *types.PageData) {
 	var (
 		name, content, charset, property string
-		httpEquiv                        string
+		httpEquiv, itemprop              string
 	)
 
 	for _, attr := range node.Attr {
@@ -206,6 +198,8 @@ func parseMetaTags(node *html.Node, pageData *types.PageData) {
 				property = attr.Val
 			case "http-equiv":
 				httpEquiv = strings.ToLower(attr.Val)
+			case "itemprop":
+				itemprop = attr.Val
 			}
 	}
 
@@ -223,50 +217,93 @@ func parseMetaTags(node *html.Node, pageData *types.PageData) {
 			pageData.OpenGraph[property] = content
 		case name == "description":
 			pageData.MetaDescription = content
+		case strings.EqualFold(name, "rating") && strings.TrimSpace(content) != "":
+			pageData.Ratings = append(pageData.Ratings, strings.TrimSpace(content))
 	}
 
-	parseTimestamps(property, content, pageData)
+	for _, key := range []string{property, name, itemprop} {
+		parseTimestamps(key, content, pageData)
+	}
 }
 
-// Parses timestamps from meta tags
-func parseTimestamps(property, content string, pageData *types.PageData) {
-	if content == "" {
+// Records the target of a <meta http-equiv="refresh"> that leads to another URL
+func parseMetaRefresh(node *html.Node, pageData *types.PageData, base *url.URL) {
+	if !strings.EqualFold(strings.TrimSpace(getAttribute(node, "http-equiv")), "refresh") || pageData.RefreshURL != "" {
+		return
+	}
+	delay, target, ok := parseRefreshContent(getAttribute(node, "content"))
+	if !ok || target == "" {
+		return // A plain reload of the same page
+	}
+	resolved, ok := resolveLink(base, target)
+	if !ok {
 		return
 	}
+	pageData.RefreshURL = resolved.String()
+	pageData.RefreshDelay = delay
+}
 
-	var parsedTime time.Time
-	var err error
-	
-	switch property {
-	case "article:published_time", "datepublished":
-		parsedTime, err = time.Parse(time.RFC3339, content)
-		if err == nil {
-			pageData.DatePublished = parsedTime
+// Applies <meta name="robots"> and <meta name="<product token>"> directives
+func parseRobotsMeta(node *html.Node, pageData *types.PageData) {
+	if robots.AppliesToCrawler(getAttribute(node, "name"), crawlerIdentity.ProductToken) {
+		robots.ApplyDirectives(&pageData.Robots, getAttribute(node, "content"))
+	}
+}
+
+// Splits a refresh value such as "5; url='/next'" into its delay and URL,
+// accepting the variations browsers do
+func parseRefreshContent(content string) (time.Duration, string, bool) {
+	content = strings.TrimSpace(content)
+	end := 0
+	for end < len(content) && (content[end] >= '0' && content[end] <= '9' || content[end] == '.') {
+		end++
+	}
+	seconds, err := strconv.ParseFloat(content[:end], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	delay := time.Duration(seconds * float64(time.Second))
+
+	rest := strings.TrimLeft(content[end:], " \t\n\r")
+	if rest != "" && rest[0] != ';' && rest[0] != ',' {
+		return 0, "", false
+	}
+	rest = strings.TrimSpace(strings.TrimLeft(rest, ";,"))
+	if len(rest) >= 3 && strings.EqualFold(rest[:3], "url") {
+		if afterKey := strings.TrimSpace(rest[3:]); strings.HasPrefix(afterKey, "=") {
+			rest = strings.TrimSpace(afterKey[1:])
 		}
-	case "article:modified_time", "datemodified":
-		parsedTime, err = time.Parse(time.RFC3339, content)
-		if err == nil {
-			pageData.DateModified = parsedTime
+	}
+	if len(rest) >= 2 && (rest[0] == '\'' || rest[0] == '"') {
+		if closing := strings.IndexByte(rest[1:], rest[0]); closing >= 0 {
+			rest = rest[1 : closing + 1]
+		} else {
+			rest = rest[1:]
 		}
 	}
+	return delay, strings.TrimSpace(rest), true
 }
 
-// Processes anchor tags to extract URLs and anchor text
+// Records the published or modified date a meta tag carries, whether the tag
+// names it by property, name or itemprop
+func parseTimestamps(key, content string, pageData *types.PageData) {
+	if kind, candidate, ok := dates.FromMeta(key, content); ok {
+		dates.Offer(pageData, kind, candidate)
+	}
+}
+
+// Processes anchor tags to extract URLs and anchor text, recording each link
+// with its title, rel values, position and the alt text of any image it wraps
 func processAnchor(node *html.Node, pageData *types.PageData, base *url.URL,
 	internalLinks *[]string, externalLinks *[]string) {
 
 	href := getAttribute(node, "href")
-	if href == "" {
-		return
-	}
-
-	parsed, err := url.Parse(href)
-	if err != nil {
+	if href == "" || contactExtractor.AddLink(&pageData.Contacts, href) {
 		return
 	}
 
-	resolved := base.ResolveReference(parsed)
-	if !isValidScheme(resolved) {
+	resolved, ok := resolveLink(base, href)
+	if !ok {
 		return
 	}
 
@@ -275,11 +312,14 @@ func processAnchor(node *html.Node, pageData *types.PageData, base *url.URL,
 		pageData.AnchorTexts = append(pageData.AnchorTexts, anchorText)
 	}
 
-	if resolved.Host == base.Host {
-		*internalLinks = append(*internalLinks, resolved.String())
-	} else {
-		*externalLinks = append(*externalLinks, resolved.String())
-	}
+	recordOutlink(pageData, base, resolved, types.Outlink{
+		Kind:     "a",
+		Text:     normalizeText(anchorText),
+		Title:    normalizeText(getAttribute(node, "title")),
+		Rels:     linkRels(getAttribute(node, "rel")),
+		Position: linkPosition(node),
+		ImageAlt: imageAlt(node),
+	}, internalLinks, externalLinks)
 }
 
 // Verifies that the URL scheme is either HTTP or HTTPS
@@ -287,19 +327,9 @@ func isValidScheme(url *url.URL) bool {
 	return url.Scheme == "http" || url.Scheme == "https"
 }
 
-// Filters out non-social external links
+// Filters out external links that are not on a platform of the social catalogue
 func filterSocialLinks(links []string) []string {
-	social := make([]string, 0, 5)
-	for _, link := range links {
-		parsedURL, err := url.Parse(link)
-		if err != nil {
-			continue
-		}
-		if _, exists := socialDomains[parsedURL.Hostname()]; exists {
-			social = append(social, link)
-		}
-	}
-	return social
+	return contactExtractor.SocialLinks(links)
 }
 
 
@@ -326,21 +356,6 @@ func extractNodeText(node *html.Node) string {
 	return strings.TrimSpace(builder.String())
 }
 
-// Verifies title against blocked terms (hot-path optimized)
-func checkTitleFilter(title string) error {
-	if len(filterTerms) == 0 {
-		return nil
-	}
-
-	lowerTitle := strings.ToLower(title)
-	for _, term := range filterTerms {
-		if strings.Contains(lowerTitle, term) {
-			return fmt.Errorf("title contains filtered term: %q", term)
-		}
-	}
-	return nil
-}
-
 // Extracts alt attributes from img elements
 func parseImage(node *html.Node, pageData *types.PageData) {
 	for _, attr := range node.Attr {
@@ -361,25 +376,46 @@ func storeHeading(node *html.Node, pageData *types.PageData) {
 	pageData.Headings[tag] = append(pageData.Headings[tag], text)
 }
 
-// Handles canonical link discovery
+// Handles canonical link and hreflang alternate discovery
 func parseLink(node *html.Node, pageData *types.PageData, base *url.URL) {
-	var href, rel string
+	var href, rel, hreflang string
 	for _, attr := range node.Attr {
 		switch strings.ToLower(attr.Key) {
 		case "href":
 			href = attr.Val
 		case "rel":
 			rel = strings.ToLower(attr.Val)
+		case "hreflang":
+			hreflang = strings.ToLower(strings.TrimSpace(attr.Val))
 		}
 	}
 
-	if href == "" || !strings.Contains(rel, "canonical") {
+	if href == "" {
 		return
 	}
 
-	if parsed, err := url.Parse(href); err == nil {
-		pageData.CanonicalURL = base.ResolveReference(parsed).String()
+	if strings.Contains(rel, "canonical") {
+		if parsed, err := url.Parse(href); err == nil {
+			pageData.CanonicalURL = base.ResolveReference(parsed).String()
+		}
+		return
+	}
+
+	if hreflang != "" && hasToken(rel, "alternate") {
+		if resolved, ok := resolveLink(base, href); ok {
+			pageData.Alternates = append(pageData.Alternates, types.Alternate{Language: hreflang, URL: resolved.String()})
+		}
+	}
+}
+
+// Reports whether a space separated attribute value such as rel contains a token
+func hasToken(value, token string) bool {
+	for _, field := range strings.Fields(value) {
+		if field == token {
+			return true
+		}
 	}
+	return false
 }
 
 // Extracts JSON-LD content
@@ -49,22 +49,16 @@ func TestTraverseAndExtractPageContent(t *testing.T) {
 			expectedIsSecure: true,
 		},
 		{
-			name: "Non-English content should error",
+			name: "Non-English content is extracted with its declared language",
 			content: `<html lang="es">
 				<head><title>Test Page</title></head>
 				<body><p>Hola</p></body>
 			</html>`,
-			baseURL:     "https://example.com",
-			expectError: true,
-		},
-		{
-			name: "Title contains filtered term",
-			content: `<html lang="en">
-				<head><title>Porn Site</title></head>
-				<body><p>Content</p></body>
-			</html>`,
-			baseURL:     "https://example.com",
-			expectError: true,
+			baseURL:          "https://example.com",
+			expectError:      false,
+			expectedTitle:    "Test Page",
+			expectedLanguage: "es",
+			expectedIsSecure: true,
 		},
 	}
 
@@ -176,7 +170,7 @@ func TestExtractNodeText(t *testing.T) {
 	}
 }
 
-// Ensures that only links with known social domains are returned.
+// Ensures that only links to platforms of the social catalogue are returned.
 func TestFilterSocialLinks(t *testing.T) {
 	links := []string{
 		"https://facebook.com/profile",
@@ -185,6 +179,9 @@ func TestFilterSocialLinks(t *testing.T) {
 		"https://instagram.com/pic",
 		"https://linkedin.com/in/someone",
 		"https://other.com",
+		"https://www.youtube.com/@channel",
+		"https://github.com/someone",
+		"https://fosstodon.org/@someone",
 	}
 	filtered := filterSocialLinks(links)
 	expected := []string{
@@ -192,6 +189,9 @@ func TestFilterSocialLinks(t *testing.T) {
 		"https://twitter.com/user",
 		"https://instagram.com/pic",
 		"https://linkedin.com/in/someone",
+		"https://www.youtube.com/@channel",
+		"https://github.com/someone",
+		"https://fosstodon.org/@someone",
 	}
 
 	if len(filtered) != len(expected) {
@@ -227,18 +227,6 @@ func TestFindHTMLNode(t *testing.T) {
 	}
 }
 
-// Checks that titles containing filtered terms are rejected.
-func TestCheckTitleFilter(t *testing.T) {
-	err := checkTitleFilter("This is a safe title")
-	if err != nil {
-		t.Errorf("unexpected error for safe title: %v", err)
-	}
-	err = checkTitleFilter("This site is XXX rated")
-	if err == nil {
-		t.Error("expected error for filtered title, got nil")
-	}
-}
-
 // Verifies that a heading node’s text is stored properly.
 func TestStoreHeading(t *testing.T) {
 	textNode := &html.Node{
@@ -277,6 +265,140 @@ func TestParseLink(t *testing.T) {
 	}
 }
 
+// Verifies that hreflang alternates are captured and other link types ignored.
+func TestParseLinkAlternates(t *testing.T) {
+	base, _ := url.Parse("https://example.com/page")
+	links := [][]html.Attribute{
+		{{Key: "rel", Val: "alternate"}, {Key: "hreflang", Val: "en-GB"}, {Key: "href", Val: "/uk/page"}},
+		{{Key: "rel", Val: "alternate"}, {Key: "hreflang", Val: "x-default"}, {Key: "href", Val: "https://example.org/page"}},
+		{{Key: "rel", Val: "alternate"}, {Key: "type", Val: "application/rss+xml"}, {Key: "href", Val: "/feed"}},
+		{{Key: "rel", Val: "stylesheet"}, {Key: "hreflang", Val: "fr"}, {Key: "href", Val: "/style.css"}},
+	}
+	var pd types.PageData
+	for _, attrs := range links {
+		parseLink(&html.Node{Type: html.ElementNode, Data: "link", Attr: attrs}, &pd, base)
+	}
+	expected := []types.Alternate{
+		{Language: "en-gb", URL: "https://example.com/uk/page"},
+		{Language: "x-default", URL: "https://example.org/page"},
+	}
+	if len(pd.Alternates) != len(expected) {
+		t.Fatalf("expected %d alternates, got %+v", len(expected), pd.Alternates)
+	}
+	for i := range expected {
+		if pd.Alternates[i] != expected[i] {
+			t.Errorf("alternate %d: expected %+v, got %+v", i, expected[i], pd.Alternates[i])
+		}
+	}
+}
+
+// Checks the forms of refresh value that browsers accept.
+func TestParseRefreshContent(t *testing.T) {
+	tests := []struct {
+		content string
+		delay   time.Duration
+		target  string
+		ok      bool
+	}{
+		{"0; url=https://example.com/next", 0, "https://example.com/next", true},
+		{"5;URL='/next'", 5 * time.Second, "/next", true},
+		{" 0 , url = \"/quoted\" ", 0, "/quoted", true},
+		{"1.5; /bare", 1500 * time.Millisecond, "/bare", true},
+		{"30", 30 * time.Second, "", true},
+		{"url=/next", 0, "", false},
+		{"5 seconds", 0, "", false},
+	}
+	for _, tt := range tests {
+		delay, target, ok := parseRefreshContent(tt.content)
+		if ok != tt.ok || delay != tt.delay || target != tt.target {
+			t.Errorf("parseRefreshContent(%q): expected (%v, %q, %v), got (%v, %q, %v)", tt.content, tt.delay, tt.target, tt.ok, delay, target, ok)
+		}
+	}
+}
+
+// Verifies that a meta refresh target is resolved against the base URL.
+func TestParseMetaRefresh(t *testing.T) {
+	node := &html.Node{
+		Type: html.ElementNode,
+		Data: "meta",
+		Attr: []html.Attribute{
+			{Key: "http-equiv", Val: "Refresh"},
+			{Key: "content", Val: "0; url=../moved"},
+		},
+	}
+	var pd types.PageData
+	base, _ := url.Parse("https://example.com/a/b")
+	parseMetaRefresh(node, &pd, base)
+	if pd.RefreshURL != "https://example.com/moved" || pd.RefreshDelay != 0 {
+		t.Errorf("unexpected refresh %q after %v", pd.RefreshURL, pd.RefreshDelay)
+	}
+}
+
+// Checks that robots meta tags for all crawlers and for this one apply, and others do not.
+func TestParseRobotsMeta(t *testing.T) {
+	previous := crawlerIdentity
+	defer func() { crawlerIdentity = previous }()
+	crawlerIdentity.ProductToken = "testbot"
+
+	var pd types.PageData
+	for _, attrs := range [][]html.Attribute{
+		{{Key: "name", Val: "robots"}, {Key: "content", Val: "noarchive"}},
+		{{Key: "name", Val: "TestBot"}, {Key: "content", Val: "nofollow"}},
+		{{Key: "name", Val: "googlebot"}, {Key: "content", Val: "noindex"}},
+	} {
+		parseRobotsMeta(&html.Node{Type: html.ElementNode, Data: "meta", Attr: attrs}, &pd)
+	}
+	if !pd.Robots.NoArchive || !pd.Robots.NoFollow || pd.Robots.NoIndex {
+		t.Errorf("unexpected directives %+v", pd.Robots)
+	}
+}
+
+// Checks that rel values are recorded per link, marking nofollow, ugc and sponsored links.
+func TestProcessAnchorRels(t *testing.T) {
+	base, _ := url.Parse("https://example.com/")
+	var pd types.PageData
+	var internal, external []string
+	for _, attrs := range [][]html.Attribute{
+		{{Key: "href", Val: "https://ads.example/offer"}, {Key: "rel", Val: "Sponsored noopener NOFOLLOW"}},
+		{{Key: "href", Val: "/comment-link"}, {Key: "rel", Val: "ugc"}},
+		{{Key: "href", Val: "/plain"}, {Key: "rel", Val: "noopener"}},
+	} {
+		processAnchor(&html.Node{Type: html.ElementNode, Data: "a", Attr: attrs}, &pd, base, &internal, &external)
+	}
+	if len(internal) != 2 || len(external) != 1 || len(pd.Outlinks) != 3 {
+		t.Fatalf("expected every link to be kept, got %v, %v and %v", internal, external, pd.Outlinks)
+	}
+	if rels := pd.Outlinks[0].Rels; len(rels) != 3 || rels[0] != "sponsored" || rels[2] != "nofollow" || !pd.Outlinks[0].NoFollow() {
+		t.Errorf("unexpected rels for the sponsored link: %v", rels)
+	}
+	if rels := pd.Outlinks[1].Rels; len(rels) != 1 || rels[0] != "ugc" || !pd.Outlinks[1].NoFollow() {
+		t.Errorf("unexpected rels for the ugc link: %v", rels)
+	}
+	if pd.Outlinks[2].NoFollow() || pd.Outlinks[2].Internal != true || pd.Outlinks[0].Internal {
+		t.Errorf("unexpected outlinks %+v", pd.Outlinks)
+	}
+}
+
+// Checks that mailto: and tel: links become contacts rather than outlinks.
+func TestProcessAnchorContacts(t *testing.T) {
+	base, _ := url.Parse("https://example.com/")
+	var pd types.PageData
+	var internal, external []string
+	for _, href := range []string{"mailto:Sales@Example.com?subject=Hello", "tel:+44 20 7946 0000", "tel:020 7946 0000", "/contact"} {
+		anchor := &html.Node{Type: html.ElementNode, Data: "a", Attr: []html.Attribute{{Key: "href", Val: href}}}
+		processAnchor(anchor, &pd, base, &internal, &external)
+	}
+	if len(pd.Contacts.Emails) != 1 || pd.Contacts.Emails[0] != "sales@example.com" {
+		t.Errorf("unexpected emails %v", pd.Contacts.Emails)
+	}
+	if len(pd.Contacts.Phones) != 1 || pd.Contacts.Phones[0] != "+442079460000" {
+		t.Errorf("expected only the international number without a calling code, got %v", pd.Contacts.Phones)
+	}
+	if len(pd.Outlinks) != 1 || len(internal) != 1 {
+		t.Errorf("expected only the page link as an outlink, got %v", pd.Outlinks)
+	}
+}
+
 // Verifies that JSON-LD script content is captured.
 func TestParseScript(t *testing.T) {
 	node := &html.Node{
@@ -300,6 +422,34 @@ func TestParseScript(t *testing.T) {
 	}
 }
 
+// Verifies that JSON-LD and Microdata become entities and their well-known fields are promoted.
+func TestExtractStructuredData(t *testing.T) {
+	content := `<html><head>
+		<script type="application/ld+json">{"@context": "https://schema.org", "@type": "Article", "datePublished": "2024-03-05", "author": {"@type": "Person", "name": "Jane Doe"}}</script>
+		<script type="application/ld+json">{"@type": "Article",</script>
+	</head><body>
+		<div itemscope itemtype="https://schema.org/Product"><span itemprop="name">Desk lamp</span>
+			<div itemprop="offers" itemscope itemtype="https://schema.org/Offer"><span itemprop="price">19.99</span></div>
+		</div>
+	</body></html>`
+	pd, err := traverseAndExtractPageContent(content, "https://example.com/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pd.StructuredData) != 2 || len(pd.Entities) != 2 {
+		t.Fatalf("expected 2 raw blocks and 2 entities, got %d and %d", len(pd.StructuredData), len(pd.Entities))
+	}
+	if len(pd.JSONLDErrors) != 1 || !strings.HasPrefix(pd.JSONLDErrors[0], "block 2: invalid JSON-LD") {
+		t.Errorf("expected the broken block to be recorded, got %v", pd.JSONLDErrors)
+	}
+	if !pd.DatePublished.Equal(time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)) || pd.Byline != "Jane Doe" {
+		t.Errorf("unexpected date %v or byline %q", pd.DatePublished, pd.Byline)
+	}
+	if pd.Product == nil || pd.Product.Name != "Desk lamp" || pd.Product.Price != "19.99" {
+		t.Errorf("unexpected product %+v", pd.Product)
+	}
+}
+
 // Checks that various meta tags (charset, description, OpenGraph) are processed.
 func TestParseMetaTags(t *testing.T) {
 	nodeCharset := &html.Node{
@@ -0,0 +1,169 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"webcrawler/internal/pkg/policy"
+	"webcrawler/internal/pkg/proxy"
+	"webcrawler/internal/pkg/types"
+)
+
+var (
+	ErrRedirectLoop      = errors.New("redirect loop detected")
+	ErrTooManyRedirects  = errors.New("too many redirects")
+	ErrDecompressionBomb = errors.New("compression ratio limit exceeded")
+	ErrCrossHostRedirect = errors.New("redirect to another host")
+	ErrMetaRefresh       = errors.New("meta refresh")
+	ErrUnwantedLanguage  = errors.New("unwanted language")
+	ErrContentPolicy     = errors.New("rejected by content policy")
+)
+
+// Error returned by Fetch, tagged with the category of failure
+type FetchError struct {
+	Category types.ErrorCategory
+	Err      error
+}
+
+func (fetchError *FetchError) Error() string {
+	return fmt.Sprintf("%s: %v", fetchError.Category, fetchError.Err)
+}
+
+func (fetchError *FetchError) Unwrap() error {
+	return fetchError.Err
+}
+
+// Wraps an error with a category, classifying it if no category is given
+func newFetchError(category types.ErrorCategory, err error) *FetchError {
+	var fetchError *FetchError
+	if errors.As(err, &fetchError) && (category == types.ErrorNone || category == fetchError.Category) {
+		return fetchError
+	}
+	if category == types.ErrorNone {
+		category = classifyError(err)
+	}
+	return &FetchError{Category: category, Err: err}
+}
+
+// Records a failure on the fetch result and returns the matching FetchError
+func failResult(result *types.FetchResult, category types.ErrorCategory, err error) error {
+	fetchError := newFetchError(category, err)
+	result.ErrorCategory = fetchError.Category
+	result.Error = fetchError.Err.Error()
+	return fetchError
+}
+
+// Records that the content policy dropped the page, naming the rule
+func rejectByPolicy(result *types.FetchResult, decision policy.Decision) error {
+	result.PolicyRule = decision.Rule
+	return failResult(result, types.ErrorFiltered, fmt.Errorf("%w: %s", ErrContentPolicy, decision.Rule))
+}
+
+// Maps an error from the HTTP client or rate limiter to a category
+func classifyError(err error) types.ErrorCategory {
+	if err == nil {
+		return types.ErrorNone
+	}
+
+	var fetchError *FetchError
+	if errors.As(err, &fetchError) {
+		return fetchError.Category
+	}
+	if errors.Is(err, ErrRedirectLoop) || errors.Is(err, ErrTooManyRedirects) {
+		return types.ErrorRedirect
+	}
+	if errors.Is(err, ErrDecompressionBomb) {
+		return types.ErrorParse
+	}
+	if proxy.IsProxyError(err) {
+		return types.ErrorProxy
+	}
+
+	// DNS errors can also report as timeouts, so check them first
+	var dnsError *net.DNSError
+	if errors.As(err, &dnsError) {
+		return types.ErrorDNS
+	}
+	if isTimeout(err) {
+		return types.ErrorTimeout
+	}
+	if isTLSError(err) {
+		return types.ErrorTLS
+	}
+
+	// A reset or truncated response means the server dropped an established connection
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		strings.Contains(err.Error(), "connection reset by peer") {
+		return types.ErrorReset
+	}
+
+	var opError *net.OpError
+	if errors.As(err, &opError) || errors.Is(err, syscall.ECONNREFUSED) {
+		return types.ErrorConnect
+	}
+	return types.ErrorUnknown
+}
+
+// Maps a response status code to a category, or ErrorNone if it is a success
+func categoryForStatus(statusCode int) types.ErrorCategory {
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		return types.ErrorNone
+	case statusCode >= 300 && statusCode < 400:
+		return types.ErrorRedirect
+	case statusCode >= 400 && statusCode < 500:
+		return types.ErrorHTTP4xx
+	case statusCode >= 500 && statusCode < 600:
+		return types.ErrorHTTP5xx
+	}
+	return types.ErrorUnknown
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netError net.Error
+	return errors.As(err, &netError) && netError.Timeout()
+}
+
+func isTLSError(err error) bool {
+	var (
+		verificationError *tls.CertificateVerificationError
+		recordError       tls.RecordHeaderError
+		authorityError    x509.UnknownAuthorityError
+		hostnameError     x509.HostnameError
+		invalidError      x509.CertificateInvalidError
+	)
+	if errors.As(err, &verificationError) || errors.As(err, &recordError) ||
+		errors.As(err, &authorityError) || errors.As(err, &hostnameError) ||
+		errors.As(err, &invalidError) {
+		return true
+	}
+	// Handshake alerts are not exported as types
+	return strings.Contains(err.Error(), "tls: ")
+}
+
+// Builds the redirect chain that led to the given response
+func redirectChain(resp *http.Response) []types.Redirect {
+	var chain []types.Redirect
+	for request := resp.Request; request != nil && request.Response != nil; request = request.Response.Request {
+		hop := request.Response
+		redirect := types.Redirect{
+			StatusCode: hop.StatusCode,
+			Location:   hop.Header.Get("Location"),
+		}
+		if hop.Request != nil {
+			redirect.URL = hop.Request.URL.String()
+		}
+		chain = append([]types.Redirect{redirect}, chain...)
+	}
+	return chain
+}
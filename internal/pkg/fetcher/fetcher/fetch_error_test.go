@@ -0,0 +1,93 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"webcrawler/internal/pkg/policy"
+	"webcrawler/internal/pkg/proxy"
+	"webcrawler/internal/pkg/types"
+)
+
+// Checks that errors are mapped to the expected categories.
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected types.ErrorCategory
+	}{
+		{"nil", nil, types.ErrorNone},
+		{"redirect loop", fmt.Errorf("%w: http://a", ErrRedirectLoop), types.ErrorRedirect},
+		{"dns", &net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}, types.ErrorDNS},
+		{"dns timeout", &net.DNSError{Err: "timeout", Name: "slow.example", IsTimeout: true}, types.ErrorDNS},
+		{"deadline", fmt.Errorf("wrapped: %w", context.DeadlineExceeded), types.ErrorTimeout},
+		{"refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, types.ErrorConnect},
+		{"reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, types.ErrorReset},
+		{"unexpected eof", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), types.ErrorReset},
+		{"proxy connect", &net.OpError{Op: "proxyconnect", Net: "tcp", Err: syscall.ECONNREFUSED}, types.ErrorProxy},
+		{"no healthy proxy", fmt.Errorf("get: %w", proxy.ErrNoHealthyProxy), types.ErrorProxy},
+		{"tls", errors.New("remote error: tls: handshake failure"), types.ErrorTLS},
+		{"categorised", newFetchError(types.ErrorFiltered, errors.New("blocked")), types.ErrorFiltered},
+		{"other", errors.New("something else"), types.ErrorUnknown},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := classifyError(tc.err); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// Checks the mapping from status codes to categories.
+func TestCategoryForStatus(t *testing.T) {
+	tests := map[int]types.ErrorCategory{
+		200: types.ErrorNone,
+		204: types.ErrorNone,
+		304: types.ErrorRedirect,
+		404: types.ErrorHTTP4xx,
+		429: types.ErrorHTTP4xx,
+		503: types.ErrorHTTP5xx,
+		999: types.ErrorUnknown,
+	}
+	for status, expected := range tests {
+		if got := categoryForStatus(status); got != expected {
+			t.Errorf("status %d: expected %q, got %q", status, expected, got)
+		}
+	}
+}
+
+// A connection to a closed port should be reported as a connect failure.
+func TestFetchContentConnectError(t *testing.T) {
+	Init()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL := server.URL
+	server.Close()
+
+	_, result, err := fetchContent(context.Background(), closedURL)
+	if err == nil {
+		t.Fatal("expected an error for a closed server, got nil")
+	}
+	if result.ErrorCategory != types.ErrorConnect {
+		t.Errorf("expected category %q, got %q (%s)", types.ErrorConnect, result.ErrorCategory, result.Error)
+	}
+}
+
+// Pages dropped by the content policy should surface as filtered, naming the rule.
+func TestRejectByPolicy(t *testing.T) {
+	var result types.FetchResult
+	err := rejectByPolicy(&result, policy.Decision{Drop: true, Rule: "adult-title"})
+	if classifyError(err) != types.ErrorFiltered || !errors.Is(err, ErrContentPolicy) {
+		t.Errorf("expected a filtered content policy error, got %q (%v)", classifyError(err), err)
+	}
+	if result.ErrorCategory != types.ErrorFiltered || result.PolicyRule != "adult-title" {
+		t.Errorf("unexpected result category %q and rule %q", result.ErrorCategory, result.PolicyRule)
+	}
+}
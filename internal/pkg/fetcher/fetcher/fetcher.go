@@ -5,30 +5,41 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"math/rand"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
-	"unicode/utf8"
+	"webcrawler/internal/pkg/contacts"
+	"webcrawler/internal/pkg/dates"
+	"webcrawler/internal/pkg/identity"
+	"webcrawler/internal/pkg/language"
+	"webcrawler/internal/pkg/policy"
+	"webcrawler/internal/pkg/proxy"
+	"webcrawler/internal/pkg/resolver"
+	"webcrawler/internal/pkg/robots"
 	"webcrawler/internal/pkg/types"
 	"webcrawler/internal/pkg/utils"
 	"golang.org/x/net/html"
 )
 
+// A browser User-Agent and its share of traffic, used by the rotation profile
 type UserAgentData struct {
-	UserAgent string `json:"userAgent"`
+	UserAgent string  `json:"ua"`
+	Weight    float64 `json:"pct"`
 }
 
 const (
-	maxBodySize  = 2 * 1024 * 1024 // 2 MB
+	maxBodySize  = 2 * 1024 * 1024 // 2 MB, after decompression
 	maxParseTime = 5 * time.Second
 	defaultMaxRedirects = 3
+	maxRefreshDelay     = 5 * time.Second // Longer meta refreshes are left for readers to see the page first
+	minLanguageConfidence = 0.5 // Pages are only rejected for their language when it is this certain
 )
 
 var (
@@ -37,32 +48,60 @@ var (
 	allocCancel   context.CancelFunc
 	userAgentData []UserAgentData
 
+	// How requests identify the crawler, loaded by Init
+	crawlerIdentity  = identity.Default()
+	allowedLanguages = language.ParseAllowList("en") // Set from the environment by Init
+	contentPolicy    = policy.DefaultEngine()        // Set from the environment by Init
+	contactExtractor = contacts.DefaultExtractor()   // Set from the environment by Init
+
+	dialer = &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	// Caches DNS answers for every connection, set up by Init
+	dnsResolver *resolver.Resolver
+
+	// Connection settings shared by direct and proxied requests. The
+	// administrator sends every URL for a host to the same fetcher process,
+	// which handles one request at a time, so a couple of idle connections
+	// per host are enough but they need to outlive the host's crawl delay.
+	baseTransport = &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true, // A custom dialer otherwise turns HTTP/2 off
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          200,
+		MaxIdleConnsPerHost:   2,
+		DisableCompression:    true, // Accept-Encoding is negotiated and decoded by fetchContent
+	}
+
+	// Routes requests through the configured proxies, set up by Init
+	proxyPool *proxy.Pool
+
 	// HTTP client with custom settings
 	httpClient = &http.Client{
-		Timeout: 10 * time.Second,
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   5 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			TLSHandshakeTimeout:   5 * time.Second,
-			ResponseHeaderTimeout: 5 * time.Second,
-			IdleConnTimeout:       5 * time.Second,
-			MaxIdleConns:          20,
-			MaxIdleConnsPerHost:   10,
-		},
+		Timeout:   10 * time.Second,
+		Transport: baseTransport,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			// Check for redirect loops
 			newURL := req.URL.String()
 			for _, prevReq := range via {
 				if prevReq.URL.String() == newURL {
-					return fmt.Errorf("redirect loop detected: %s", newURL)
+					return fmt.Errorf("%w: %s", ErrRedirectLoop, newURL)
 				}
 			}
 			
 			// Check redirect limit
 			if len(via) >= defaultMaxRedirects {
-				return fmt.Errorf("reached maximum of %d redirects", defaultMaxRedirects)
+				return fmt.Errorf("%w: reached maximum of %d", ErrTooManyRedirects, defaultMaxRedirects)
+			}
+
+			// Leave redirects to other hosts to the administrator, which
+			// checks them against robots.txt and the domain budgets
+			if !strings.EqualFold(req.URL.Hostname(), via[len(via) - 1].URL.Hostname()) {
+				return http.ErrUseLastResponse
 			}
 			
 			return nil
@@ -72,6 +111,46 @@ var (
 
 // Initialize the fetcher module by loading prerequisites.
 func Init() error {
+	loaded, err := identity.FromEnvironment()
+	if err != nil {
+		return fmt.Errorf("invalid crawler identity: %v", err)
+	}
+	crawlerIdentity = loaded
+	userAgentData = nil
+	allowedLanguages = language.AllowListFromEnvironment()
+	assetTypes, err := AssetTypesFromEnvironment()
+	if err != nil {
+		return err
+	}
+	fetchedAssets = assetTypes
+	engine, err := policy.FromEnvironment()
+	if err != nil {
+		return fmt.Errorf("invalid content policy: %v", err)
+	}
+	contentPolicy = engine
+	extractor, err := contacts.FromEnvironment()
+	if err != nil {
+		return fmt.Errorf("invalid contact extraction settings: %v", err)
+	}
+	contactExtractor = extractor
+
+	if err := loadRenderingOption(); err != nil {
+		return err
+	}
+	if renderingEnabled && findChrome() == "" {
+		log.Printf("Warning: %s is set but no Chrome binary was found, rendering is disabled", EnvRenderJS)
+		renderingEnabled = false
+	}
+	loadResolver()
+	if err := loadProxies(); err != nil {
+		return err
+	}
+
+	// Browser User-Agents are only loaded when rotation is explicitly enabled
+	if crawlerIdentity.Profile != identity.ProfileRotate {
+		return nil
+	}
+
 	_, filename, _, ok := runtime.Caller(0)
 	if !ok {
 		return fmt.Errorf("failed to get this file's path at runtime")
@@ -86,95 +165,314 @@ func Init() error {
 		return fmt.Errorf("failed to read user agents file: %v", err)
 	}
 	defer jsonFile.Close()
-	if err := json.NewDecoder(jsonFile).Decode(&userAgentData); err != nil {
+	var decoded []UserAgentData
+	if err := json.NewDecoder(jsonFile).Decode(&decoded); err != nil {
 		return fmt.Errorf("error decoding user agents JSON: %v", err)
 	}
+	for _, agent := range decoded {
+		if strings.TrimSpace(agent.UserAgent) != "" {
+			userAgentData = append(userAgentData, agent)
+		}
+	}
+	if len(userAgentData) == 0 {
+		return fmt.Errorf("user agent rotation is enabled but %s has no user agents", userAgentsPath)
+	}
+	return nil
+}
+
+// Routes every connection through a caching resolver. Proxies are built on
+// top of the base transport, so this must happen before loadProxies.
+func loadResolver() {
+	if dnsResolver != nil {
+		dnsResolver.Close()
+	}
+	config := resolver.DefaultConfig()
+	config.Dialer = dialer
+	dnsResolver = resolver.NewResolver(config)
+	baseTransport.DialContext = dnsResolver.DialContext
+}
+
+// Caches addresses the administrator has already resolved for a host
+func SeedAddresses(host string, addresses []string, expires time.Time) {
+	if dnsResolver != nil {
+		dnsResolver.Seed(host, addresses, expires)
+	}
+}
+
+// Resolves a host ahead of the request so DNS time is measured on its own.
+// The dial then finds the addresses cached. Proxied hosts are resolved by
+// the proxy and are skipped.
+func resolveHost(context context.Context, host string, result *types.FetchResult) error {
+	if dnsResolver == nil || (proxyPool != nil && !proxyPool.IsDirect(host)) {
+		return nil
+	}
+	start := time.Now()
+	resolved, err := dnsResolver.Resolve(context, host)
+	result.DNSDuration = time.Since(start)
+	result.DNSCached = resolved.Cached
+	return err
+}
+
+// Records whether the connection each request goes out on was reused. After
+// redirects, the result reflects the final request.
+func traceConnection(req *http.Request, result *types.FetchResult) *http.Request {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			result.ConnReused = info.Reused
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// Builds the proxy pool from the environment and routes the HTTP client through it
+func loadProxies() error {
+	config, err := proxy.FromEnvironment()
+	if err != nil {
+		return fmt.Errorf("invalid proxy configuration: %v", err)
+	}
+	pool, err := proxy.NewPool(config, baseTransport)
+	if err != nil {
+		return fmt.Errorf("invalid proxy configuration: %v", err)
+	}
+	if proxyPool != nil {
+		proxyPool.Close()
+	}
+	proxyPool = pool
+	proxyPool.Start()
+	httpClient.Transport = proxyPool
 	return nil
 }
 
 // Fetch orchestrates the fetching process.
-// It tries using the HTTP client and falls back to chromedp if necessary.
-func Fetch(context context.Context, shortUrl string) (types.PageData, error) {
+// It tries using the HTTP client and, when rendering is enabled, falls back
+// to chromedp for HTML pages that need JavaScript to show their content.
+// The returned FetchResult is populated even when an error is returned.
+func Fetch(context context.Context, shortUrl string) (types.PageData, types.FetchResult, error) {
+	result := types.FetchResult{RequestedURL: shortUrl}
 
 	fullURL, err := utils.BuildFullUrl(shortUrl)
 
 	if err != nil {
 		fmt.Printf("Failed to build full URL from short URL %v: %v\n", shortUrl, err)
-		return types.PageData{}, fmt.Errorf("failed to build full URL from short URL %v: %v", shortUrl, err)
+		return types.PageData{}, result, failResult(&result, types.ErrorParse, fmt.Errorf("failed to build full URL from short URL %v: %v", shortUrl, err))
 	}
 
-	// Initialize PageData
+	// Some pages can be turned away by their URL alone, before any request
+	if decision := contentPolicy.Evaluate(policy.Page{URL: fullURL}); decision.Drop {
+		return types.PageData{}, result, rejectByPolicy(&result, decision)
+	}
+
+	// Initialize PageData. Robots.txt and crawl delays have already been
+	// checked by the administrator before the URL was dispatched here.
 	var pageData types.PageData
 	pageData.URL = fullURL
 
-	// Wait for permission from the rate limiter
-	err = waitForPermission(context, fullURL)
-	if err != nil {
-		fmt.Printf("Error in rate limiter for URL %s: %v\n", fullURL, err)
-		if errors.Is(err, ErrCrawlingDisallowed) {
-			log.Printf("Crawling disallowed for URL: %s", fullURL)
-			return types.PageData{}, err
-		}
-		return types.PageData{}, fmt.Errorf("error in rate limiter for URL [%s] Cause: [%v]", fullURL, err)
-	}
-
 	// Attempt to fetch content using HTTP client
 	startTime := time.Now()
-	content, err := fetchContent(context, fullURL)
+	content, result, err := fetchContent(context, fullURL)
+	result.RequestedURL = shortUrl // As the administrator knows it, not as expanded for the request
 	pageData.LoadTime = time.Since(startTime)
+	result.Duration = pageData.LoadTime
 	if err != nil {
-		log.Printf("HTTP fetch failed for URL [%s] Cause: [%v]", fullURL, err)
-		return types.PageData{}, err
+		if !errors.Is(err, ErrCrossHostRedirect) {
+			log.Printf("HTTP fetch failed for URL [%s] Cause: [%v]", fullURL, err)
+		}
+		return types.PageData{}, result, err
 	}
 
-	// Extract data from content
-	pd, err := extractPageData(content, fullURL)
+	// Extract data with the handler for the document's type
+	handler := handlerFor(result.MediaType)
+	if handler == nil {
+		return types.PageData{}, result, failResult(&result, types.ErrorFiltered, fmt.Errorf("%w: %s", ErrUnwantedContentType, result.MediaType))
+	}
+	// Relative links resolve against where the page ended up, not where it was requested
+	pd, err := handler.Extract(content, result.FinalURL)
+	if err == nil {
+		// Re-fetch pages that are empty without JavaScript through the browser
+		if _, isHTML := handler.(htmlHandler); isHTML && renderingEnabled && canRender(result.FinalURL) && needsRendering(content, pd.VisibleText) {
+			if rendered, truncated, renderErr := renderPage(context, result.FinalURL); renderErr != nil {
+				log.Printf("Rendering failed for URL [%s], using static HTML. Cause: [%v]", fullURL, renderErr)
+			} else {
+				pd, err = extractPageData(rendered, result.FinalURL)
+				result.Rendered = true
+				result.Truncated = truncated
+				if truncated {
+					log.Printf("Warning: rendered page %s was truncated to %d bytes", fullURL, maxBodySize)
+				}
+			}
+			pageData.LoadTime = time.Since(startTime)
+			result.Duration = pageData.LoadTime
+		}
+	}
 	if err != nil {
-		return types.PageData{}, errors.New(err.Error())
+		category := classifyError(err)
+		if category == types.ErrorUnknown {
+			category = types.ErrorParse
+		}
+		return types.PageData{}, result, failResult(&result, category, err)
+	}
+
+	// Pages that promptly refresh to another URL are handed back like redirects
+	if pd.RefreshURL != "" && pd.RefreshURL != result.FinalURL && pd.RefreshDelay <= maxRefreshDelay {
+		result.RedirectChain = append(result.RedirectChain, types.Redirect{
+			URL:        result.FinalURL,
+			StatusCode: result.StatusCode,
+			Location:   pd.RefreshURL,
+		})
+		result.RedirectTarget = pd.RefreshURL
+		return types.PageData{}, result, failResult(&result, types.ErrorPendingRedirect, fmt.Errorf("%w: %s", ErrMetaRefresh, pd.RefreshURL))
+	}
+
+	// Settle the page's language from its text and what it declares
+	declared := pd.Language
+	if declared == "unspecified" {
+		declared = ""
+	}
+	signals := language.Signals{
+		Text:            pd.VisibleText,
+		HTMLLang:        declared,
+		ContentLanguage: result.Header.Get("Content-Language"),
+		OGLocale:        pd.OpenGraph["og:locale"],
+	}
+	guess := language.Detect(signals)
+	if guess.Confidence >= minLanguageConfidence && !allowedLanguages.Allows(guess.Language) && !allowedLanguages.AllowsDeclared(signals) {
+		return types.PageData{}, result, failResult(&result, types.ErrorFiltered, fmt.Errorf("%w: %s", ErrUnwantedLanguage, guess.Language))
+	}
+	decision := contentPolicy.Evaluate(policy.Page{
+		URL:         result.FinalURL,
+		Title:       pd.Title,
+		Description: pd.MetaDescription,
+		Text:        pd.VisibleText,
+		Ratings:     pd.Ratings,
+	})
+	if decision.Drop {
+		return types.PageData{}, result, rejectByPolicy(&result, decision)
+	}
+	pd.PolicyTags, pd.PolicyRules, pd.PolicyNoFollow = decision.Tags, decision.Fired, decision.NoFollow
+	contactExtractor.Extract(&pd)
+	pd.DeclaredLang = declared
+	pd.Language, pd.LangConfidence = guess.Language, guess.Confidence
+	if pd.Language == "" {
+		pd.Language = "unspecified"
+	}
+
+	robots.ApplyHeader(&pd.Robots, result.Header.Values("X-Robots-Tag"), crawlerIdentity.ProductToken)
+	if lastModified, ok := dates.Parse(result.Header.Get("Last-Modified")); ok {
+		dates.Offer(&pd, dates.Modified, dates.Candidate{Time: lastModified, Source: dates.SourceHeader, Confidence: lastModifiedConfidence})
+	}
+	pd.URL = result.FinalURL
+	pd.RequestedURL = fullURL
+	pd.RedirectChain = result.RedirectChain
+	pd.LoadTime = pageData.LoadTime
+	pd.MediaType = result.MediaType
+	if result.Charset != "" {
+		pd.Charset = result.Charset // The encoding actually used, not just what the page declared
 	}
-	pd.URL = fullURL
 	pageData = pd
 
-	return pageData, nil
+	return pageData, result, nil
 }
 
 // Fetches the page content using the HTTP client.
-func fetchContent(context context.Context, fullURL string) (string, error) {
+// The FetchResult describes the response even if the content is rejected.
+func fetchContent(context context.Context, fullURL string) (string, types.FetchResult, error) {
+	result := types.FetchResult{RequestedURL: fullURL, FinalURL: fullURL}
+
+	// Avoid requesting documents that no handler would accept
+	if err := checkWantedURL(context, fullURL, &result); err != nil {
+		return "", result, err
+	}
+
 	req, err := http.NewRequestWithContext(context, "GET", fullURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create HTTP request: %v", err)
+		return "", result, failResult(&result, types.ErrorParse, fmt.Errorf("failed to create HTTP request: %v", err))
 	}
-	req.Header.Set("User-Agent", getRandomUserAgent())
+	if err := resolveHost(context, req.URL.Hostname(), &result); err != nil {
+		return "", result, failResult(&result, types.ErrorNone, fmt.Errorf("failed to resolve %s: %w", req.URL.Hostname(), err))
+	}
+	setIdentityHeaders(req)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	req = traceConnection(req, &result)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch URL %s: %v", fullURL, err)
+		return "", result, failResult(&result, types.ErrorNone, fmt.Errorf("failed to fetch URL %s: %w", fullURL, err))
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
+	result.StatusCode = resp.StatusCode
+	result.Protocol = resp.Proto
+	result.Header = resp.Header
+	result.FinalURL = resp.Request.URL.String()
+	result.RedirectChain = redirectChain(resp)
+	result.ContentType = resp.Header.Get("Content-Type")
+
+	// Hand redirects to other hosts back along with the chain so far
+	if location, err := resp.Location(); err == nil && categoryForStatus(resp.StatusCode) == types.ErrorRedirect &&
+		!strings.EqualFold(location.Hostname(), resp.Request.URL.Hostname()) {
+		result.RedirectChain = append(result.RedirectChain, types.Redirect{
+			URL:        result.FinalURL,
+			StatusCode: resp.StatusCode,
+			Location:   resp.Header.Get("Location"),
+		})
+		result.RedirectTarget = location.String()
+		return "", result, failResult(&result, types.ErrorPendingRedirect, fmt.Errorf("%w: %s", ErrCrossHostRedirect, result.RedirectTarget))
 	}
 
-	limitedReader := io.LimitReader(resp.Body, maxBodySize) // Limit body size
+	if category := categoryForStatus(resp.StatusCode); category != types.ErrorNone {
+		return "", result, failResult(&result, category, fmt.Errorf("received response code: %d", resp.StatusCode))
+	}
+
+	// Reject unwanted types before downloading the body
+	result.MediaType = mediaTypeOf(result.ContentType)
+	if !isGenericMediaType(result.MediaType) && handlerFor(result.MediaType) == nil {
+		return "", result, failResult(&result, types.ErrorFiltered, fmt.Errorf("%w: %s", ErrUnwantedContentType, result.MediaType))
+	}
 
-	bodyBytes, err := io.ReadAll(limitedReader)
+	// Decode as the body streams in so the size limit applies to decoded bytes
+	result.ContentEncoding = resp.Header.Get("Content-Encoding")
+	wire := &countingReader{reader: resp.Body}
+	decoded, err := newDecodingReader(wire, result.ContentEncoding)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
+		result.WireBytes = wire.count
+		return "", result, failResult(&result, types.ErrorParse, err)
 	}
 
-	// Check if we hit the limit and log a warning if so
-	if len(bodyBytes) == int(maxBodySize) {
+	bodyBytes, truncated, err := readDecoded(decoded, wire, maxBodySize)
+	result.WireBytes = wire.count
+	result.BytesRead = int64(len(bodyBytes))
+	if err != nil {
+		if wire.err != nil {
+			return "", result, failResult(&result, types.ErrorNone, fmt.Errorf("failed to read response body: %w", wire.err))
+		}
+		return "", result, failResult(&result, types.ErrorParse, fmt.Errorf("failed to decode response body: %w", err))
+	}
+
+	// Log a warning if the body was cut off at the limit
+	if truncated {
+		result.Truncated = true
 		log.Printf("Warning: response for %s was truncated to %d bytes", fullURL, maxBodySize)
 	}
 
-	content := string(bodyBytes)
-	// Validate that the content is valid UTF-8.
-	if !utf8.ValidString(content) {
-		return "", fmt.Errorf("invalid UTF-8 content for URL %s", fullURL)
+	// Servers that send no useful type get their body sniffed instead
+	if isGenericMediaType(result.MediaType) {
+		result.MediaType = sniffMediaType(bodyBytes)
+		if handlerFor(result.MediaType) == nil {
+			return "", result, failResult(&result, types.ErrorFiltered, fmt.Errorf("%w: sniffed %s", ErrUnwantedContentType, result.MediaType))
+		}
 	}
 
-	return content, nil
+	// Binary documents are passed to their handler untouched
+	if !isTextMediaType(result.MediaType) {
+		return string(bodyBytes), result, nil
+	}
+
+	// Transcode to UTF-8 so the parser sees the same text a browser would
+	content, charsetName, charsetSource := decodeBody(bodyBytes, result.ContentType)
+	result.Charset = charsetName
+	result.CharsetSource = charsetSource
+
+	return content, result, nil
 }
 
 // Tries to parse HTML, returns an error if parsing fails or takes too long.
@@ -202,16 +500,54 @@ func extractPageData(content, baseURL string) (types.PageData, error) {
 	return traverseAndExtractPageContent(content, baseURL)
 }
 
-// Gets a random user agent from the user agents list.
+// Sets the User-Agent and From headers according to the crawler identity.
+func setIdentityHeaders(req *http.Request) {
+	if crawlerIdentity.Profile == identity.ProfileRotate {
+		req.Header.Set("User-Agent", getRandomUserAgent())
+	} else {
+		req.Header.Set("User-Agent", crawlerIdentity.UserAgent())
+	}
+	if crawlerIdentity.From != "" {
+		req.Header.Set("From", crawlerIdentity.From)
+	}
+}
+
+// Gets a user agent from the user agents list, weighted by traffic share.
+// Falls back to the bot User-Agent if the list is empty.
 func getRandomUserAgent() string {
 	if len(userAgentData) == 0 {
-		log.Fatalf("No user agents loaded")
+		return crawlerIdentity.UserAgent()
 	}
-	return userAgentData[rand.Intn(len(userAgentData))].UserAgent
+	totalWeight := 0.0
+	for _, agent := range userAgentData {
+		totalWeight += agent.Weight
+	}
+	if totalWeight <= 0 {
+		return userAgentData[rand.Intn(len(userAgentData))].UserAgent
+	}
+	target := rand.Float64() * totalWeight
+	for _, agent := range userAgentData {
+		target -= agent.Weight
+		if target < 0 {
+			return agent.UserAgent
+		}
+	}
+	return userAgentData[len(userAgentData) - 1].UserAgent
 }
 
 // Gracefully closes the browser instance and releases resources.
 func Shutdown() {
+	closeTabs()
+	if proxyPool != nil {
+		proxyPool.Close()
+		proxyPool = nil
+		httpClient.Transport = baseTransport
+	}
+	if dnsResolver != nil {
+		dnsResolver.Close()
+		dnsResolver = nil
+		baseTransport.DialContext = dialer.DialContext
+	}
 	if browserCancel != nil {
 		browserCancel()
 		browserCancel = nil
@@ -3,21 +3,170 @@ package fetcher
 
 import (
 	"context"
+	"errors"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+	"webcrawler/internal/pkg/contacts"
+	"webcrawler/internal/pkg/identity"
+	"webcrawler/internal/pkg/policy"
+	"webcrawler/internal/pkg/types"
 )
 
-// Checks that the fetcher is initialized successfully.
+// Checks that the fetcher initializes with the bot identity and no browser User-Agents.
 func TestInit(t *testing.T) {
-	err := Init()
+	t.Setenv(identity.EnvProfile, string(identity.ProfileBot))
+	if err := Init(); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	if len(userAgentData) != 0 {
+		t.Errorf("expected no browser user agents without the rotate profile, got %d", len(userAgentData))
+	}
+}
+
+// Checks that the rotate profile loads the browser User-Agents from the data file.
+func TestInitRotateProfile(t *testing.T) {
+	t.Setenv(identity.EnvProfile, string(identity.ProfileRotate))
+	defer func() {
+		os.Unsetenv(identity.EnvProfile)
+		Init()
+	}()
+	if err := Init(); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	if len(userAgentData) == 0 {
+		t.Fatal("expected non-empty userAgentData after Init")
+	}
+	for _, agent := range userAgentData {
+		if !strings.HasPrefix(agent.UserAgent, "Mozilla/5.0") {
+			t.Errorf("unexpected user agent loaded: %q", agent.UserAgent)
+		}
+	}
+}
+
+// Checks that an invalid identity is reported by Init.
+func TestInitInvalidIdentity(t *testing.T) {
+	t.Setenv(identity.EnvProductToken, "not a token")
+	defer func() {
+		os.Unsetenv(identity.EnvProductToken)
+		Init()
+	}()
+	if err := Init(); err == nil {
+		t.Error("expected error for invalid product token")
+	}
+}
+
+// Checks that requests carry the bot User-Agent and the configured From header.
+func TestFetchContentIdentityHeaders(t *testing.T) {
+	t.Setenv(identity.EnvContactURL, "https://example.com/bot")
+	t.Setenv(identity.EnvFrom, "crawler@example.com")
+	defer func() {
+		os.Unsetenv(identity.EnvContactURL)
+		os.Unsetenv(identity.EnvFrom)
+		Init()
+	}()
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	var userAgent, from string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		from = r.Header.Get("From")
+	}))
+	defer server.Close()
+
+	if _, _, err := fetchContent(context.Background(), server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "Mozilla/5.0 (compatible; webcrawler/1.0; +https://example.com/bot)"; userAgent != expected {
+		t.Errorf("expected User-Agent %q, got %q", expected, userAgent)
+	}
+	if from != "crawler@example.com" {
+		t.Errorf("expected From %q, got %q", "crawler@example.com", from)
+	}
+}
+
+// Checks that DNS time is recorded and addresses seeded by the administrator are used.
+func TestFetchContentSeededAddresses(t *testing.T) {
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Seeded</body></html>"))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	// The .invalid TLD never resolves, so the fetch only works through the seeded address
+	SeedAddresses("seeded.invalid", []string{"127.0.0.1"}, time.Now().Add(time.Minute))
+	content, result, err := fetchContent(context.Background(), "http://seeded.invalid:" + port + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "Seeded") {
+		t.Errorf("unexpected content %q", content)
+	}
+	if !result.DNSCached || result.DNSDuration <= 0 {
+		t.Errorf("expected a timed cache hit, got cached=%v duration=%v", result.DNSCached, result.DNSDuration)
+	}
+}
+
+// Checks that keep-alive connections are reused and reported as such.
+func TestFetchContentConnectionReuse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Hello</body></html>"))
+	}))
+	defer server.Close()
+
+	_, first, err := fetchContent(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, second, err := fetchContent(context.Background(), server.URL)
 	if err != nil {
-		t.Logf("Init returned error (this may be expected if the file is missing): %v", err)
-	} else if len(userAgentData) == 0 {
-		t.Error("expected non-empty userAgentData after Init")
+		t.Fatal(err)
+	}
+	if first.ConnReused || !second.ConnReused {
+		t.Errorf("expected only the second fetch to reuse the connection, got %v and %v", first.ConnReused, second.ConnReused)
+	}
+	if second.Protocol != "HTTP/1.1" {
+		t.Errorf("expected HTTP/1.1, got %q", second.Protocol)
+	}
+}
+
+// Checks that HTTP/2 is negotiated when the server supports it.
+func TestFetchContentHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Hello</body></html>"))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	// Trust the test certificate with otherwise unchanged settings
+	transport := baseTransport.Clone()
+	transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	defer func(previous http.RoundTripper) { httpClient.Transport = previous }(httpClient.Transport)
+	httpClient.Transport = transport
+
+	_, result, err := fetchContent(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Protocol != "HTTP/2.0" {
+		t.Errorf("expected HTTP/2.0, got %q", result.Protocol)
 	}
 }
 
@@ -33,13 +182,19 @@ func TestFetchContentSuccess(t *testing.T) {
 	defer server.Close()
 
 	ctx := context.Background()
-	content, err := fetchContent(ctx, server.URL)
+	content, result, err := fetchContent(ctx, server.URL)
 	if err != nil {
 		t.Fatalf("fetchContent returned unexpected error: %v", err)
 	}
 	if content != responseBody {
 		t.Errorf("expected %q, got %q", responseBody, content)
 	}
+	if result.StatusCode != http.StatusOK || !result.OK() {
+		t.Errorf("expected successful 200 result, got %+v", result)
+	}
+	if result.BytesRead != int64(len(responseBody)) {
+		t.Errorf("expected %d bytes read, got %d", len(responseBody), result.BytesRead)
+	}
 }
 
 // Fetching with non-200 response.
@@ -51,10 +206,326 @@ func TestFetchContentNon200(t *testing.T) {
 	defer server.Close()
 
 	context := context.Background()
-	_, err := fetchContent(context, server.URL)
+	_, result, err := fetchContent(context, server.URL)
 	if err == nil {
 		t.Fatal("expected an error for non-200 status, got nil")
 	}
+	if result.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, result.StatusCode)
+	}
+	if result.ErrorCategory != types.ErrorHTTP4xx {
+		t.Errorf("expected category %q, got %q", types.ErrorHTTP4xx, result.ErrorCategory)
+	}
+}
+
+// A 503 response should be categorised as a server error.
+func TestFetchContentServerError(t *testing.T) {
+	Init()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, result, err := fetchContent(context.Background(), server.URL)
+	if err == nil {
+		t.Fatal("expected an error for 503 status, got nil")
+	}
+	var fetchError *FetchError
+	if !errors.As(err, &fetchError) || fetchError.Category != types.ErrorHTTP5xx {
+		t.Errorf("expected FetchError with category %q, got %v", types.ErrorHTTP5xx, err)
+	}
+	if result.ErrorCategory != types.ErrorHTTP5xx {
+		t.Errorf("expected category %q, got %q", types.ErrorHTTP5xx, result.ErrorCategory)
+	}
+}
+
+// Redirects should be recorded along with the final URL and content type.
+func TestFetchContentRedirectChain(t *testing.T) {
+	Init()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<html></html>"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, result, err := fetchContent(context.Background(), server.URL + "/old")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.FinalURL != server.URL + "/new" {
+		t.Errorf("expected final URL %q, got %q", server.URL + "/new", result.FinalURL)
+	}
+	if len(result.RedirectChain) != 1 {
+		t.Fatalf("expected 1 redirect, got %d", len(result.RedirectChain))
+	}
+	if hop := result.RedirectChain[0]; hop.StatusCode != http.StatusMovedPermanently || hop.Location != "/new" || hop.URL != server.URL + "/old" {
+		t.Errorf("unexpected redirect hop: %+v", hop)
+	}
+	if result.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("unexpected content type %q", result.ContentType)
+	}
+}
+
+// Redirects to another host should be handed back rather than followed.
+func TestFetchContentCrossHostRedirect(t *testing.T) {
+	Init()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://other.example/new", http.StatusMovedPermanently)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, result, err := fetchContent(context.Background(), server.URL + "/old")
+	if !errors.Is(err, ErrCrossHostRedirect) {
+		t.Fatalf("expected a cross-host redirect error, got %v", err)
+	}
+	if result.ErrorCategory != types.ErrorPendingRedirect || result.RedirectTarget != "http://other.example/new" {
+		t.Errorf("unexpected result: category %q, target %q", result.ErrorCategory, result.RedirectTarget)
+	}
+	if len(result.RedirectChain) != 2 {
+		t.Fatalf("expected 2 redirects, got %+v", result.RedirectChain)
+	}
+	if hop := result.RedirectChain[1]; hop.URL != server.URL + "/moved" || hop.StatusCode != http.StatusMovedPermanently {
+		t.Errorf("unexpected last hop: %+v", hop)
+	}
+}
+
+// A prompt meta refresh should be handed back like a redirect, a slow one kept as a page.
+func TestFetchMetaRefresh(t *testing.T) {
+	Init()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/prompt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Moved</title><meta http-equiv="refresh" content="0; url=/new"></head></html>`))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>News</title><meta http-equiv="refresh" content="300; url=/slow"></head></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, result, err := Fetch(context.Background(), server.URL + "/prompt")
+	if !errors.Is(err, ErrMetaRefresh) || result.ErrorCategory != types.ErrorPendingRedirect {
+		t.Fatalf("expected a meta refresh hand-back, got %v (%q)", err, result.ErrorCategory)
+	}
+	if result.RedirectTarget != server.URL + "/new" || len(result.RedirectChain) != 1 || result.RedirectChain[0].StatusCode != http.StatusOK {
+		t.Errorf("unexpected target %q and chain %+v", result.RedirectTarget, result.RedirectChain)
+	}
+
+	pd, result, err := Fetch(context.Background(), server.URL + "/slow")
+	if err != nil || !result.OK() {
+		t.Fatalf("expected the slowly refreshing page to be kept, got %v", err)
+	}
+	if pd.Title != "News" || pd.RefreshDelay != 300 * time.Second {
+		t.Errorf("unexpected page data: title %q, refresh delay %v", pd.Title, pd.RefreshDelay)
+	}
+}
+
+// X-Robots-Tag directives should be combined with those in the page.
+func TestFetchRobotsHeader(t *testing.T) {
+	Init()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Add("X-Robots-Tag", "noindex")
+		w.Header().Add("X-Robots-Tag", "otherbot: nofollow")
+		_, _ = w.Write([]byte(`<html><head><title>Hidden</title><meta name="robots" content="nosnippet"></head></html>`))
+	}))
+	defer server.Close()
+
+	pd, _, err := Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !pd.Robots.NoIndex || !pd.Robots.NoSnippet || pd.Robots.NoFollow {
+		t.Errorf("unexpected directives %+v", pd.Robots)
+	}
+}
+
+// Pages should be kept or rejected by the language of their text, not just their lang attribute.
+func TestFetchLanguage(t *testing.T) {
+	t.Setenv("WEBCRAWLER_LANGUAGES", "en")
+	Init()
+	pages := map[string]string{
+		"/mistagged": `<html lang="fr"><head><title>News</title></head><body><p>Volunteers spent the whole weekend cleaning the beach, collecting plastic bottles, fishing nets and old tyres that the winter storms had washed ashore.</p></body></html>`,
+		"/untagged":  `<html><head><title>Noticias</title></head><body><p>Los voluntarios pasaron todo el fin de semana limpiando la playa y recogiendo botellas de plástico, redes de pesca y neumáticos viejos que las tormentas del invierno habían arrastrado hasta la orilla.</p></body></html>`,
+		"/short":     `<html><head><title>Hola</title></head><body><p>Hola</p></body></html>`,
+		"/close":     `<html><head><title>Praia</title></head><body><p>Os voluntários passaram todo o fim de semana a limpar a praia e a recolher garrafas de plástico, redes de pesca e pneus velhos que as tempestades do inverno tinham trazido até à costa.</p></body></html>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = io.WriteString(w, pages[r.URL.Path])
+	}))
+	defer server.Close()
+
+	pd, _, err := Fetch(context.Background(), server.URL + "/mistagged")
+	if err != nil {
+		t.Fatalf("expected the mis-tagged English page to be kept, got %v", err)
+	}
+	if pd.Language != "en" || pd.DeclaredLang != "fr" || pd.LangConfidence < minLanguageConfidence {
+		t.Errorf("unexpected language %q (declared %q) with confidence %v", pd.Language, pd.DeclaredLang, pd.LangConfidence)
+	}
+
+	_, result, err := Fetch(context.Background(), server.URL + "/untagged")
+	if !errors.Is(err, ErrUnwantedLanguage) || result.ErrorCategory != types.ErrorFiltered {
+		t.Errorf("expected the untagged Spanish page to be filtered, got %v", err)
+	}
+
+	if _, _, err := Fetch(context.Background(), server.URL + "/short"); err != nil {
+		t.Errorf("expected a page too short to identify to be kept, got %v", err)
+	}
+
+	// Portuguese is close to Spanish but should still be told apart from it
+	if _, _, err := Fetch(context.Background(), server.URL + "/close"); !errors.Is(err, ErrUnwantedLanguage) || !strings.HasSuffix(err.Error(), ": pt") {
+		t.Errorf("expected the Portuguese page to be filtered as Portuguese, got %v", err)
+	}
+}
+
+// Checks that a page declaring an allowed language the identifier has no
+// profile for is kept, though its text reads as a related language.
+func TestFetchLanguageWithoutProfile(t *testing.T) {
+	t.Cleanup(func() { Init() })
+	t.Setenv("WEBCRAWLER_LANGUAGES", "nb")
+	Init()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Language", "nb")
+		_, _ = io.WriteString(w, `<html lang="nb"><head><title>Stranden</title></head><body><p>Frivillige brukte hele helgen på å rydde stranden og samle inn plastflasker, fiskegarn og gamle dekk som vinterstormene hadde skylt i land. Kommunen takker alle som stilte opp og håper at enda flere blir med neste år.</p><p>Biblioteket holder åpent lenger på torsdager slik at studentene kan forberede seg til eksamen. Det er gratis å låne bøker, og man kan også bestille dem på nettet og hente dem senere.</p></body></html>`)
+	}))
+	defer server.Close()
+
+	pd, _, err := Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("expected the Norwegian page to be kept, got %v", err)
+	}
+	if pd.DeclaredLang != "nb" {
+		t.Errorf("expected the declared language to be nb, got %q", pd.DeclaredLang)
+	}
+}
+
+// Pages should be dropped, tagged or left unfollowed by the content policy,
+// which is reloaded when its file changes.
+func TestFetchContentPolicy(t *testing.T) {
+	Init()
+	pages := map[string]string{
+		"/adult":   `<html lang="en"><head><title>Porn Site</title></head><body><p>Content</p></body></html>`,
+		"/cricket": `<html lang="en"><head><title>Sussex cricket</title></head><body><p>Content</p></body></html>`,
+		"/rated":   `<html lang="en"><head><title>Club</title><meta name="rating" content="adult"></head><body><p>Content</p></body></html>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = io.WriteString(w, pages[r.URL.Path])
+	}))
+	defer server.Close()
+
+	_, result, err := Fetch(context.Background(), server.URL + "/adult")
+	if !errors.Is(err, ErrContentPolicy) || result.ErrorCategory != types.ErrorFiltered || result.PolicyRule != "adult-title" {
+		t.Errorf("expected the default policy to drop the page, got %v (rule %q)", err, result.PolicyRule)
+	}
+	if _, result, err := Fetch(context.Background(), server.URL + "/rated"); result.PolicyRule != "adult-rating" {
+		t.Errorf("expected the adult rating to drop the page, got %v (rule %q)", err, result.PolicyRule)
+	}
+	if _, _, err := Fetch(context.Background(), server.URL + "/cricket"); err != nil {
+		t.Errorf("expected words only matched whole, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	rules := `{"rules": [
+		{"name": "sport", "fields": ["title"], "words": ["cricket"], "action": "tag", "tag": "sport"},
+		{"name": "no-links", "fields": ["url"], "pattern": "/cricket$", "action": "nofollow"}
+	]}`
+	if err := os.WriteFile(path, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(policy.EnvPolicy, path)
+	if err := Init(); err != nil {
+		t.Fatalf("unexpected error loading the policy: %v", err)
+	}
+	defer func() {
+		os.Unsetenv(policy.EnvPolicy)
+		Init()
+	}()
+
+	pd, _, err := Fetch(context.Background(), server.URL + "/cricket")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pd.PolicyTags) != 1 || pd.PolicyTags[0] != "sport" || !pd.PolicyNoFollow || len(pd.PolicyRules) != 2 {
+		t.Errorf("unexpected tags %v, rules %v and nofollow %v", pd.PolicyTags, pd.PolicyRules, pd.PolicyNoFollow)
+	}
+	if _, _, err := Fetch(context.Background(), server.URL + "/adult"); err != nil {
+		t.Errorf("expected the file to replace the default rules, got %v", err)
+	}
+}
+
+// Checks that contacts are taken from the text, links and structured data of
+// a fetched page, with national numbers read in the configured country.
+func TestFetchContacts(t *testing.T) {
+	t.Cleanup(func() { Init() }) // Runs after the environment is restored
+	t.Setenv(contacts.EnvCallingCode, "44")
+	if err := Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	page := `<html lang="en"><head><title>Contact us</title>
+		<script type="application/ld+json">{"@context": "https://schema.org", "@type": "Organization", "name": "Acme",
+			"address": {"@type": "PostalAddress", "streetAddress": "1 High Street", "addressLocality": "London", "postalCode": "EC1A 1AA", "addressCountry": "GB"}}</script>
+		</head><body><p>Write to press [at] acme [dot] example or call +1 (415) 555-0100. We are @acme@mastodon.social too.</p>
+		<a href="tel:020 7946 0000">Call us</a>
+		<a href="https://github.com/acme">GitHub</a> <a href="https://github.com/features">Features</a>
+		<a rel="me" href="https://social.example/@acme">Our server</a></body></html>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = io.WriteString(w, page)
+	}))
+	defer server.Close()
+
+	pd, _, err := Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	found := pd.Contacts
+	if len(found.Emails) != 1 || found.Emails[0] != "press@acme.example" {
+		t.Errorf("unexpected emails %v", found.Emails)
+	}
+	if len(found.Phones) != 2 || found.Phones[0] != "+442079460000" || found.Phones[1] != "+14155550100" {
+		t.Errorf("unexpected phones %v", found.Phones)
+	}
+	if len(found.Addresses) != 1 || found.Addresses[0] != "1 High Street, London, EC1A 1AA, GB" {
+		t.Errorf("unexpected addresses %v", found.Addresses)
+	}
+	var handles []string
+	for _, profile := range found.Profiles {
+		handles = append(handles, profile.Platform + ":" + profile.Handle)
+	}
+	if strings.Join(handles, " ") != "github:acme mastodon:acme@social.example mastodon:acme@mastodon.social" {
+		t.Errorf("unexpected profiles %v", handles)
+	}
+}
+
+// The result should name the URL as it was requested, even without a scheme.
+func TestFetchRequestedURL(t *testing.T) {
+	Init()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Without a scheme the URL is fetched over HTTPS, which the test server does not speak
+	shortURL := strings.TrimPrefix(server.URL, "http://") + "/page"
+	_, result, err := Fetch(context.Background(), shortURL)
+	if err == nil {
+		t.Fatal("expected the HTTPS request to fail")
+	}
+	if result.RequestedURL != shortURL || result.FinalURL != "https://" + shortURL {
+		t.Errorf("unexpected requested URL %q and final URL %q", result.RequestedURL, result.FinalURL)
+	}
 }
 
 // The response should be truncated to maxBodySize bytes.
@@ -68,13 +539,16 @@ func TestFetchContentTruncated(t *testing.T) {
 	defer server.Close()
 
 	context := context.Background()
-	content, err := fetchContent(context, server.URL)
+	content, result, err := fetchContent(context, server.URL)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if len(content) != int(maxBodySize) {
 		t.Errorf("expected content length %d, got %d", maxBodySize, len(content))
 	}
+	if !result.Truncated {
+		t.Error("expected result to be marked truncated")
+	}
 }
 
 // Parsing a simple HTML document should succeed.
@@ -129,6 +603,20 @@ func TestGetRandomUserAgent(t *testing.T) {
 			t.Errorf("unexpected user agent returned: %q", ua)
 		}
 	}
+
+	// Weights decide the share, so a zero weight agent is never picked
+	userAgentData = []UserAgentData{{UserAgent: "Agent1", Weight: 1}, {UserAgent: "Agent2", Weight: 0}}
+	for i := 0; i < 10; i++ {
+		if ua := getRandomUserAgent(); ua != "Agent1" {
+			t.Errorf("expected weighted pick of Agent1, got %q", ua)
+		}
+	}
+
+	// An empty list falls back to the bot User-Agent instead of failing
+	userAgentData = nil
+	if ua := getRandomUserAgent(); ua != crawlerIdentity.UserAgent() {
+		t.Errorf("expected bot user agent fallback, got %q", ua)
+	}
 }
 
 // Verifies that Shutdown properly nils the cancel functions.
@@ -164,10 +652,13 @@ func TestFetch(t *testing.T) {
 	defer server.Close()
 
 	ctx := context.Background()
-	pagedata, err := Fetch(ctx, server.URL)
+	pagedata, result, err := Fetch(ctx, server.URL)
 	if err != nil {
 		t.Fatalf("Fetch returned unexpected error: %v", err)
 	}
+	if result.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, result.StatusCode)
+	}
 	if pagedata.Title != "Test Fetch" {
 		t.Errorf("expected title %q, got %q", "Test Fetch", pagedata.Title)
 	}
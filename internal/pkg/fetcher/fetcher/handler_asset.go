@@ -0,0 +1,118 @@
+package fetcher
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/url"
+	"os"
+	"strings"
+	"webcrawler/internal/pkg/types"
+)
+
+// Comma separated asset types, e.g. "image,feed", to fetch. The administrator
+// enqueues the assets of these types found on pages, and the fetcher accepts
+// them instead of skipping them as unwanted content.
+const EnvAssets = "WEBCRAWLER_ASSETS"
+
+// Asset types being fetched, set from the environment by Init
+var fetchedAssets = map[string]struct{}{}
+
+// Extensions of the assets that are otherwise never requested, and their types
+var assetExtensions = map[string]string{
+	".jpg": types.AssetImage, ".jpeg": types.AssetImage, ".png": types.AssetImage, ".gif": types.AssetImage,
+	".webp": types.AssetImage, ".svg": types.AssetImage, ".bmp": types.AssetImage, ".tif": types.AssetImage,
+	".tiff": types.AssetImage, ".avif": types.AssetImage,
+	".ico": types.AssetIcon,
+	".mp3": types.AssetAudio, ".wav": types.AssetAudio, ".ogg": types.AssetAudio, ".flac": types.AssetAudio,
+	".m4a": types.AssetAudio,
+	".mp4": types.AssetVideo, ".m4v": types.AssetVideo, ".mov": types.AssetVideo, ".avi": types.AssetVideo,
+	".mkv": types.AssetVideo, ".webm": types.AssetVideo, ".wmv": types.AssetVideo,
+	".js": types.AssetScript,
+	".css": types.AssetStylesheet,
+}
+
+// Parses a comma separated list of asset types
+func ParseAssetTypes(value string) (map[string]struct{}, error) {
+	assetTypes := make(map[string]struct{})
+	for _, assetType := range strings.Split(value, ",") {
+		assetType = strings.ToLower(strings.TrimSpace(assetType))
+		switch assetType {
+		case "":
+		case types.AssetImage, types.AssetVideo, types.AssetAudio, types.AssetIcon,
+			types.AssetFeed, types.AssetStylesheet, types.AssetScript:
+			assetTypes[assetType] = struct{}{}
+		default:
+			return nil, fmt.Errorf("unknown asset type %q", assetType)
+		}
+	}
+	return assetTypes, nil
+}
+
+// Reads the asset types to fetch from the environment
+func AssetTypesFromEnvironment() (map[string]struct{}, error) {
+	assetTypes, err := ParseAssetTypes(os.Getenv(EnvAssets))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", EnvAssets, err)
+	}
+	return assetTypes, nil
+}
+
+// Reports whether assets of a type are being fetched
+func fetchesAsset(assetType string) bool {
+	_, fetched := fetchedAssets[assetType]
+	return assetType != "" && fetched
+}
+
+// Returns the asset type of a media type no document handler accepts, or an
+// empty string if it is not an asset. Feeds have a document handler of their own.
+func assetTypeOf(mediaType string) string {
+	switch {
+	case mediaType == "image/x-icon" || mediaType == "image/vnd.microsoft.icon":
+		return types.AssetIcon
+	case strings.HasPrefix(mediaType, "image/"):
+		return types.AssetImage
+	case strings.HasPrefix(mediaType, "video/"):
+		return types.AssetVideo
+	case strings.HasPrefix(mediaType, "audio/"):
+		return types.AssetAudio
+	case mediaType == "text/css":
+		return types.AssetStylesheet
+	case strings.HasSuffix(mediaType, "/javascript") || strings.HasSuffix(mediaType, "/x-javascript") ||
+		strings.HasSuffix(mediaType, "/ecmascript"):
+		return types.AssetScript
+	}
+	return ""
+}
+
+// Describes a fetched asset: its type, media type and, for images in the
+// formats the standard library reads, its size. It is not registered, since
+// handlerFor only hands out asset handlers for the types being fetched.
+type assetHandler struct {
+	assetType string
+	mediaType string
+}
+
+func (handler assetHandler) MediaTypes() []string {
+	return []string{handler.mediaType}
+}
+
+func (handler assetHandler) Extract(content, baseURL string) (types.PageData, error) {
+	var pageData types.PageData
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return pageData, fmt.Errorf("invalid base URL: %w", err)
+	}
+	pageData.IsSecure = base.Scheme == "https"
+
+	asset := types.Asset{URL: baseURL, Type: handler.assetType, MediaType: handler.mediaType}
+	if handler.assetType == types.AssetImage || handler.assetType == types.AssetIcon {
+		if config, _, err := image.DecodeConfig(strings.NewReader(content)); err == nil {
+			asset.Width, asset.Height = config.Width, config.Height
+		}
+	}
+	pageData.Assets = []types.Asset{asset}
+	return pageData, nil
+}
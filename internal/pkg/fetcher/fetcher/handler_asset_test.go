@@ -0,0 +1,65 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"webcrawler/internal/pkg/types"
+)
+
+// Checks that asset types are parsed and unknown ones rejected.
+func TestParseAssetTypes(t *testing.T) {
+	assetTypes, err := ParseAssetTypes(" Image, feed,,script ")
+	if err != nil || len(assetTypes) != 3 {
+		t.Fatalf("unexpected asset types %v (%v)", assetTypes, err)
+	}
+	if _, found := assetTypes[types.AssetImage]; !found {
+		t.Errorf("expected image to be selected, got %v", assetTypes)
+	}
+	if _, err := ParseAssetTypes("image,fonts"); err == nil {
+		t.Error("expected an unknown asset type to be rejected")
+	}
+}
+
+// Checks that images are skipped unless selected, and described with their size when fetched.
+func TestFetchAsset(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 12, 7))); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(encoded.Bytes())
+	}))
+	defer server.Close()
+
+	Init()
+	if _, result, err := Fetch(context.Background(), server.URL + "/logo.png"); !errors.Is(err, ErrUnwantedContentType) || result.ErrorCategory != types.ErrorFiltered {
+		t.Errorf("expected the image to be skipped, got %v", err)
+	}
+
+	t.Setenv(EnvAssets, "image")
+	if err := Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		os.Unsetenv(EnvAssets)
+		Init()
+	}()
+	for _, path := range []string{"/logo.png", "/logo"} {
+		pd, _, err := Fetch(context.Background(), server.URL + path)
+		if err != nil {
+			t.Fatalf("unexpected error fetching %s: %v", path, err)
+		}
+		if len(pd.Assets) != 1 || pd.Assets[0].Type != types.AssetImage || pd.Assets[0].Width != 12 || pd.Assets[0].Height != 7 ||
+			pd.Assets[0].MediaType != "image/png" || pd.MediaType != "image/png" {
+			t.Errorf("unexpected description of %s: %+v", path, pd.Assets)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"webcrawler/internal/pkg/types"
+)
+
+const maxPDFStreamSize = 8 * 1024 * 1024 // Decompressed size limit for a single content stream
+
+var (
+	pdfStreamStart = regexp.MustCompile(`stream\r?\n`)
+	pdfInfoString  = regexp.MustCompile(`/(Title|Lang|URI)\s*(\(|<[0-9A-Fa-f])`)
+)
+
+// Extracts the text of PDF documents. This is a best-effort reader for the
+// common case of Flate compressed content streams with simple fonts, not a
+// full PDF implementation.
+type pdfHandler struct{}
+
+func (pdfHandler) MediaTypes() []string {
+	return []string{"application/pdf"}
+}
+
+func (pdfHandler) Extract(content, baseURL string) (types.PageData, error) {
+	var pageData types.PageData
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return pageData, err
+	}
+	pageData.IsSecure = base.Scheme == "https"
+
+	data := []byte(content)
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return pageData, errors.New("missing PDF header")
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return pageData, errors.New("encrypted PDF")
+	}
+
+	// Title, language and link annotations are plain strings in the object dictionaries
+	for _, match := range pdfInfoString.FindAllSubmatchIndex(data, -1) {
+		value, _ := readPDFString(data[match[4]:])
+		switch string(data[match[2]:match[3]]) {
+		case "Title":
+			if pageData.Title == "" {
+				pageData.Title = strings.TrimSpace(value)
+			}
+		case "Lang":
+			pageData.Language = strings.TrimSpace(value)
+		case "URI":
+			addLink(&pageData, base, value)
+		}
+	}
+
+	var text strings.Builder
+	for _, stream := range pdfStreams(data) {
+		text.WriteString(pdfContentText(stream))
+		text.WriteByte('\n')
+	}
+	pageData.VisibleText = normalizeText(text.String())
+	pageData.SocialLinks = filterSocialLinks(pageData.ExternalLinks)
+	return pageData, nil
+}
+
+// Returns the decoded content of every stream that may hold page text.
+// Images, fonts and streams with unsupported filters are skipped.
+func pdfStreams(data []byte) [][]byte {
+	var streams [][]byte
+	for _, match := range pdfStreamStart.FindAllIndex(data, -1) {
+		end := bytes.Index(data[match[1]:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		raw := data[match[1]:match[1] + end]
+
+		// The stream dictionary is between the preceding "obj" and "stream"
+		dictStart := bytes.LastIndex(data[:match[0]], []byte("obj"))
+		if dictStart < 0 {
+			continue
+		}
+		dictionary := data[dictStart:match[0]]
+		if bytes.Contains(dictionary, []byte("/Image")) || bytes.Contains(dictionary, []byte("/FontFile")) ||
+			bytes.Contains(dictionary, []byte("/Length1")) || bytes.Contains(dictionary, []byte("/ObjStm")) ||
+			bytes.Contains(dictionary, []byte("/XRef")) {
+			continue
+		}
+
+		switch {
+		case bytes.Contains(dictionary, []byte("/FlateDecode")):
+			reader, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			decoded, _ := io.ReadAll(io.LimitReader(reader, maxPDFStreamSize))
+			if len(decoded) > 0 {
+				streams = append(streams, decoded)
+			}
+		case !bytes.Contains(dictionary, []byte("/Filter")):
+			streams = append(streams, raw)
+		}
+	}
+	return streams
+}
+
+// Collects the strings shown by text operators (Tj, TJ, ' and ") between BT and ET
+func pdfContentText(stream []byte) string {
+	var (
+		builder  strings.Builder
+		operands []string // Strings seen since the last operator
+		inText   bool
+	)
+	for position := 0; position < len(stream); {
+		char := stream[position]
+		switch {
+		case char == '%':
+			// Comment to end of line
+			for position < len(stream) && stream[position] != '\n' && stream[position] != '\r' {
+				position++
+			}
+		case char == '(' || (char == '<' && position + 1 < len(stream) && stream[position + 1] != '<'):
+			value, length := readPDFString(stream[position:])
+			operands = append(operands, value)
+			position += length
+		case char == '[' || char == ']':
+			position++
+		case isPDFDelimiter(char) || isPDFSpace(char):
+			position++
+		default:
+			start := position
+			for position < len(stream) && !isPDFDelimiter(stream[position]) && !isPDFSpace(stream[position]) {
+				position++
+			}
+			word := string(stream[start:position])
+			switch word {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				builder.WriteByte('\n')
+			case "Tj", "TJ", "'", "\"":
+				if inText {
+					if word != "Tj" && word != "TJ" {
+						builder.WriteByte('\n')
+					}
+					builder.WriteString(strings.Join(operands, ""))
+				}
+			case "T*", "Td", "TD", "Tm":
+				if inText {
+					builder.WriteByte(' ')
+				}
+			default:
+				// A large negative kerning adjustment inside TJ is a word gap
+				if strings.HasPrefix(word, "-") && len(word) > 3 && len(operands) > 0 {
+					operands[len(operands) - 1] += " "
+				}
+				continue
+			}
+			operands = operands[:0]
+		}
+	}
+	return builder.String()
+}
+
+// Reads a literal (...) or hex <...> string, returning its text and the
+// number of bytes consumed. UTF-16 strings are recognised by their BOM,
+// anything else is treated as Latin-1.
+func readPDFString(data []byte) (string, int) {
+	var raw []byte
+	consumed := len(data)
+	if len(data) > 0 && data[0] == '<' {
+		end := bytes.IndexByte(data, '>')
+		if end < 0 {
+			return "", len(data)
+		}
+		consumed = end + 1
+		raw = decodePDFHex(data[1:end])
+	} else {
+		depth := 0
+	literal:
+		for position := 0; position < len(data); position++ {
+			char := data[position]
+			switch {
+			case char == '\\' && position + 1 < len(data):
+				position++
+				escaped, skip := decodePDFEscape(data[position:])
+				raw = append(raw, escaped...)
+				position += skip
+			case char == '(':
+				if depth > 0 {
+					raw = append(raw, char)
+				}
+				depth++
+			case char == ')':
+				depth--
+				if depth == 0 {
+					consumed = position + 1
+					break literal
+				}
+				raw = append(raw, char)
+			default:
+				raw = append(raw, char)
+			}
+		}
+	}
+
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw) / 2)
+		for i := 2; i + 1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i]) << 8 | uint16(raw[i + 1]))
+		}
+		return string(utf16.Decode(units)), consumed
+	}
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes), consumed
+}
+
+// Decodes the escape after a backslash in a literal string, returning the
+// bytes it stands for and how many extra bytes it used
+func decodePDFEscape(data []byte) ([]byte, int) {
+	switch data[0] {
+	case 'n':
+		return []byte{'\n'}, 0
+	case 'r':
+		return []byte{'\r'}, 0
+	case 't':
+		return []byte{'\t'}, 0
+	case 'b', 'f':
+		return nil, 0
+	case '\r', '\n':
+		// Line continuation
+		if data[0] == '\r' && len(data) > 1 && data[1] == '\n' {
+			return nil, 1
+		}
+		return nil, 0
+	}
+	if data[0] >= '0' && data[0] <= '7' {
+		value, length := 0, 0
+		for length < 3 && length < len(data) && data[length] >= '0' && data[length] <= '7' {
+			value = value * 8 + int(data[length] - '0')
+			length++
+		}
+		return []byte{byte(value)}, length - 1
+	}
+	return []byte{data[0]}, 0
+}
+
+// Decodes the digits of a hex string, ignoring whitespace
+func decodePDFHex(digits []byte) []byte {
+	var (
+		decoded []byte
+		high    = -1
+	)
+	for _, char := range digits {
+		var value int
+		switch {
+		case char >= '0' && char <= '9':
+			value = int(char - '0')
+		case char >= 'a' && char <= 'f':
+			value = int(char - 'a') + 10
+		case char >= 'A' && char <= 'F':
+			value = int(char - 'A') + 10
+		default:
+			continue
+		}
+		if high < 0 {
+			high = value
+		} else {
+			decoded = append(decoded, byte(high << 4 | value))
+			high = -1
+		}
+	}
+	if high >= 0 {
+		decoded = append(decoded, byte(high << 4))
+	}
+	return decoded
+}
+
+func isPDFSpace(char byte) bool {
+	return char == ' ' || char == '\n' || char == '\r' || char == '\t' || char == '\f' || char == 0
+}
+
+func isPDFDelimiter(char byte) bool {
+	return strings.IndexByte("()<>[]{}/%", char) >= 0
+}
@@ -0,0 +1,122 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// Builds a minimal PDF with one Flate compressed page, an info dictionary
+// and a link annotation.
+func buildTestPDF(title, contentStream string) []byte {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	writer.Write([]byte(contentStream))
+	writer.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj << /Type /Catalog /Pages 2 0 R /Lang (en-GB) >> endobj\n")
+	pdf.WriteString("2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n")
+	pdf.WriteString("3 0 obj << /Type /Page /Parent 2 0 R /Contents 4 0 R /Annots [5 0 R] >> endobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj << /Length %d /Filter /FlateDecode >> stream\n", compressed.Len())
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream endobj\n")
+	pdf.WriteString("5 0 obj << /Type /Annot /Subtype /Link /A << /S /URI /URI (https://other.org/paper) >> >> endobj\n")
+	fmt.Fprintf(&pdf, "6 0 obj << /Title %s >> endobj\n", title)
+	pdf.WriteString("trailer << /Root 1 0 R /Info 6 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+// Checks text, title, language and links are extracted from a PDF.
+func TestPDFHandler(t *testing.T) {
+	content := "BT /F1 12 Tf 72 720 Td (Quarterly \\(Q3\\) report) Tj T* [(Reven) 20 (ue) -300 (grew)] TJ ET\n" +
+		"BT (Second block) Tj ET"
+	pdf := buildTestPDF("(Annual Report)", content)
+
+	pageData, err := pdfHandler{}.Extract(string(pdf), "https://example.com/report.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pageData.Title != "Annual Report" {
+		t.Errorf("expected title %q, got %q", "Annual Report", pageData.Title)
+	}
+	if pageData.Language != "en-GB" {
+		t.Errorf("expected language en-GB, got %q", pageData.Language)
+	}
+	if expected := "Quarterly (Q3) report Revenue grew Second block"; pageData.VisibleText != expected {
+		t.Errorf("expected text %q, got %q", expected, pageData.VisibleText)
+	}
+	if !reflect.DeepEqual(pageData.ExternalLinks, []string{"https://other.org/paper"}) {
+		t.Errorf("unexpected links %v", pageData.ExternalLinks)
+	}
+}
+
+// Checks UTF-16 hex titles and that encrypted or invalid files are rejected.
+func TestPDFHandlerEdgeCases(t *testing.T) {
+	pdf := buildTestPDF("<FEFF00430061006600E9>", "BT (x) Tj ET")
+	pageData, err := pdfHandler{}.Extract(string(pdf), "https://example.com/a.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pageData.Title != "Café" {
+		t.Errorf("expected UTF-16 title %q, got %q", "Café", pageData.Title)
+	}
+
+	encrypted := bytes.Replace(pdf, []byte("/Info 6 0 R"), []byte("/Info 6 0 R /Encrypt 7 0 R"), 1)
+	if _, err := (pdfHandler{}).Extract(string(encrypted), "https://example.com/a.pdf"); err == nil {
+		t.Error("expected error for encrypted PDF")
+	}
+	if _, err := (pdfHandler{}).Extract("<html></html>", "https://example.com/a.pdf"); err == nil {
+		t.Error("expected error for missing PDF header")
+	}
+}
+
+// Checks literal string escapes.
+func TestReadPDFString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		consumed int
+	}{
+		{`(plain) Tj`, "plain", 7},
+		{`(a (nested) b)`, "a (nested) b", 14},
+		{`(tab\there\051)`, "tab\there)", 15},
+		{`<48656C6C6F>`, "Hello", 12},
+		{`<4869 7>`, "Hip", 8},
+	}
+
+	for _, tc := range tests {
+		value, consumed := readPDFString([]byte(tc.input))
+		if value != tc.expected || consumed != tc.consumed {
+			t.Errorf("readPDFString(%q) = %q, %d; expected %q, %d", tc.input, value, consumed, tc.expected, tc.consumed)
+		}
+	}
+}
+
+// Checks that a PDF response is passed to the handler without transcoding.
+func TestFetchPDF(t *testing.T) {
+	Init()
+	pdf := buildTestPDF("(Served Report)", "BT (Body text) Tj ET")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(pdf)
+	}))
+	defer server.Close()
+
+	pageData, result, err := Fetch(context.Background(), server.URL + "/report.pdf")
+	if err != nil {
+		t.Fatalf("Fetch returned unexpected error: %v", err)
+	}
+	if pageData.Title != "Served Report" || pageData.VisibleText != "Body text" {
+		t.Errorf("unexpected PDF data: %q / %q", pageData.Title, pageData.VisibleText)
+	}
+	if result.Charset != "" || pageData.MediaType != "application/pdf" {
+		t.Errorf("expected binary PDF without charset, got %q (%q)", result.Charset, pageData.MediaType)
+	}
+}
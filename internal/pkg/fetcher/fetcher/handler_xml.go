@@ -0,0 +1,178 @@
+package fetcher
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"webcrawler/internal/pkg/dates"
+	"webcrawler/internal/pkg/types"
+
+	"golang.org/x/net/html"
+)
+
+// How far a feed's own dates are trusted
+const feedDateConfidence = 0.9
+
+// Handles RSS, Atom, sitemaps and other XML documents
+type xmlHandler struct{}
+
+func (xmlHandler) MediaTypes() []string {
+	return []string{
+		"application/xml",
+		"text/xml",
+		"application/rss+xml",
+		"application/atom+xml",
+		"application/rdf+xml",
+	}
+}
+
+// Streams through the document collecting the feed or sitemap fields.
+// Unknown XML vocabularies still yield their text and any xml:lang.
+func (xmlHandler) Extract(content, baseURL string) (types.PageData, error) {
+	var pageData types.PageData
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return pageData, fmt.Errorf("invalid base URL: %w", err)
+	}
+	pageData.IsSecure = base.Scheme == "https"
+
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = false
+	// The content has already been transcoded, whatever the declaration says
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var (
+		path    []string // Local names of the open elements
+		text    strings.Builder
+		textBuf strings.Builder
+		root    string
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if root == "" {
+				return pageData, fmt.Errorf("failed to parse XML: %w", err)
+			}
+			break // Keep what was read before the error
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(element.Name.Local)
+			if root == "" {
+				root = name
+				for _, attr := range element.Attr {
+					if attr.Name.Local == "lang" {
+						pageData.Language = attr.Value
+					}
+				}
+			}
+			path = append(path, name)
+			textBuf.Reset()
+			if name == "link" {
+				// Atom links carry the URL in href
+				if href := xmlAttribute(element, "href"); href != "" {
+					if rel := xmlAttribute(element, "rel"); rel == "" || rel == "alternate" {
+						addLink(&pageData, base, href)
+					}
+				}
+			}
+		case xml.CharData:
+			textBuf.Write(element)
+		case xml.EndElement:
+			if len(path) == 0 {
+				continue
+			}
+			value := strings.TrimSpace(textBuf.String())
+			textBuf.Reset()
+			applyXMLField(&pageData, base, path, value)
+			if value != "" {
+				text.WriteString(stripMarkup(value))
+				text.WriteByte('\n')
+			}
+			path = path[:len(path) - 1]
+		}
+	}
+
+	if root == "" {
+		return pageData, errors.New("no XML root element")
+	}
+	pageData.VisibleText = normalizeText(text.String())
+	pageData.SocialLinks = filterSocialLinks(pageData.ExternalLinks)
+	return pageData, nil
+}
+
+// Stores the text of a closed element if it is a field we know about.
+// Path holds the local names from the root down to the closed element.
+func applyXMLField(pageData *types.PageData, base *url.URL, path []string, value string) {
+	if value == "" {
+		return
+	}
+	name := path[len(path) - 1]
+	parent := ""
+	if len(path) > 1 {
+		parent = path[len(path) - 2]
+	}
+	root := path[0]
+
+	// Feed level fields sit under <channel> in RSS and directly under <feed> in Atom
+	feedLevel := parent == "channel" || (root == "feed" && len(path) == 2)
+	switch {
+	case name == "title" && feedLevel && pageData.Title == "":
+		pageData.Title = value
+	case (name == "description" || name == "subtitle") && feedLevel && pageData.MetaDescription == "":
+		pageData.MetaDescription = stripMarkup(value)
+	case name == "language" && feedLevel && pageData.Language == "":
+		pageData.Language = value
+	case (name == "lastbuilddate" || name == "updated") && feedLevel:
+		if date, ok := dates.Parse(value); ok {
+			dates.Offer(pageData, dates.Modified, dates.Candidate{Time: date, Source: dates.SourceFeed, Confidence: feedDateConfidence})
+		}
+	case name == "pubdate" && feedLevel:
+		if date, ok := dates.Parse(value); ok {
+			dates.Offer(pageData, dates.Published, dates.Candidate{Time: date, Source: dates.SourceFeed, Confidence: feedDateConfidence})
+		}
+	case name == "link" && (parent == "item" || parent == "channel"):
+		// RSS links are element text rather than attributes
+		addLink(pageData, base, value)
+	case name == "loc" && (parent == "url" || parent == "sitemap"):
+		addLink(pageData, base, value)
+	}
+}
+
+// Returns the value of an attribute on an XML element, ignoring its namespace
+func xmlAttribute(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if strings.EqualFold(attr.Name.Local, name) {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// Removes HTML tags from feed text, which often embeds escaped markup
+func stripMarkup(value string) string {
+	if !strings.Contains(value, "<") {
+		return value
+	}
+	var builder strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(value))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return strings.TrimSpace(builder.String())
+		case html.TextToken:
+			builder.Write(tokenizer.Text())
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			builder.WriteByte(' ')
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package fetcher
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+	"webcrawler/internal/pkg/types"
+)
+
+const maxTextTitleLength = 200
+
+// Turns a fetched document into PageData. Text documents are given to
+// Extract already transcoded to UTF-8, binary documents as raw bytes.
+type DocumentHandler interface {
+	MediaTypes() []string
+	Extract(content, baseURL string) (types.PageData, error)
+}
+
+var (
+	handlersMutex    sync.RWMutex
+	documentHandlers = make(map[string]DocumentHandler)
+)
+
+func init() {
+	RegisterHandler(htmlHandler{})
+	RegisterHandler(textHandler{})
+	RegisterHandler(xmlHandler{})
+	RegisterHandler(pdfHandler{})
+}
+
+// Registers a handler for each of its media types, replacing any existing one
+func RegisterHandler(handler DocumentHandler) {
+	handlersMutex.Lock()
+	defer handlersMutex.Unlock()
+	for _, mediaType := range handler.MediaTypes() {
+		documentHandlers[strings.ToLower(mediaType)] = handler
+	}
+}
+
+// Returns the handler for a media type, or nil if the type is unwanted.
+// Assets of the types being fetched get an asset handler.
+func handlerFor(mediaType string) DocumentHandler {
+	handlersMutex.RLock()
+	handler := documentHandlers[mediaType]
+	handlersMutex.RUnlock()
+	if handler == nil {
+		if assetType := assetTypeOf(mediaType); fetchesAsset(assetType) {
+			return assetHandler{assetType: assetType, mediaType: mediaType}
+		}
+	}
+	return handler
+}
+
+// Handles HTML pages with the full extractor
+type htmlHandler struct{}
+
+func (htmlHandler) MediaTypes() []string {
+	return []string{"text/html", "application/xhtml+xml"}
+}
+
+func (htmlHandler) Extract(content, baseURL string) (types.PageData, error) {
+	return extractPageData(content, baseURL)
+}
+
+// Handles plain text documents, taking the first line as the title
+type textHandler struct{}
+
+func (textHandler) MediaTypes() []string {
+	return []string{"text/plain"}
+}
+
+func (textHandler) Extract(content, baseURL string) (types.PageData, error) {
+	var pageData types.PageData
+	if parsed, err := url.Parse(baseURL); err == nil {
+		pageData.IsSecure = parsed.Scheme == "https"
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			pageData.Title = truncateRunes(line, maxTextTitleLength)
+			break
+		}
+	}
+	pageData.VisibleText = normalizeText(content)
+	return pageData, nil
+}
+
+// Shortens text to at most limit runes
+func truncateRunes(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit])
+}
+
+// Resolves a link against the base URL. Reports false for unparseable links
+// and schemes other than HTTP(S).
+func resolveLink(base *url.URL, href string) (*url.URL, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return nil, false
+	}
+	resolved := base.ResolveReference(parsed)
+	if !isValidScheme(resolved) {
+		return nil, false
+	}
+	return resolved, true
+}
+
+// Adds a link to the internal or external links depending on its host
+func addLink(pageData *types.PageData, base *url.URL, href string) {
+	resolved, ok := resolveLink(base, href)
+	if !ok {
+		return
+	}
+	if resolved.Host == base.Host {
+		pageData.InternalLinks = append(pageData.InternalLinks, resolved.String())
+	} else {
+		pageData.ExternalLinks = append(pageData.ExternalLinks, resolved.String())
+	}
+}
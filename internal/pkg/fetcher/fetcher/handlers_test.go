@@ -0,0 +1,179 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+	"webcrawler/internal/pkg/types"
+)
+
+// Handler used to check that custom handlers can be registered.
+type jsonTestHandler struct{}
+
+func (jsonTestHandler) MediaTypes() []string {
+	return []string{"application/json"}
+}
+
+func (jsonTestHandler) Extract(content, baseURL string) (types.PageData, error) {
+	return types.PageData{Title: "json", VisibleText: content}, nil
+}
+
+// Checks the first line of a text document becomes its title.
+func TestTextHandler(t *testing.T) {
+	pageData, err := textHandler{}.Extract("\n\n  Release Notes  \nVersion 2\n\nFixed   bugs.\n", "https://example.com/notes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pageData.Title != "Release Notes" {
+		t.Errorf("expected title %q, got %q", "Release Notes", pageData.Title)
+	}
+	if pageData.VisibleText != "Release Notes Version 2 Fixed bugs." {
+		t.Errorf("unexpected visible text %q", pageData.VisibleText)
+	}
+	if !pageData.IsSecure {
+		t.Error("expected https document to be secure")
+	}
+}
+
+// Checks channel fields and item links are read from an RSS feed.
+func TestXMLHandlerRSS(t *testing.T) {
+	feed := `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Example News</title>
+    <link>https://example.com/</link>
+    <atom:link href="https://example.com/feed.xml" rel="self"/>
+    <description><![CDATA[<p>Latest <b>stories</b></p>]]></description>
+    <language>en-gb</language>
+    <lastBuildDate>Mon, 02 Jan 2006 15:04:05 +0000</lastBuildDate>
+    <item>
+      <title>First story</title>
+      <link>/stories/1</link>
+    </item>
+    <item>
+      <title>Elsewhere</title>
+      <link>https://other.org/post</link>
+    </item>
+  </channel>
+</rss>`
+	pageData, err := xmlHandler{}.Extract(feed, "https://example.com/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pageData.Title != "Example News" {
+		t.Errorf("expected channel title, got %q", pageData.Title)
+	}
+	if pageData.MetaDescription != "Latest  stories" {
+		t.Errorf("expected description without markup, got %q", pageData.MetaDescription)
+	}
+	if pageData.Language != "en-gb" {
+		t.Errorf("expected language en-gb, got %q", pageData.Language)
+	}
+	if !pageData.DateModified.Equal(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("unexpected DateModified %v", pageData.DateModified)
+	}
+	expectedInternal := []string{"https://example.com/", "https://example.com/stories/1"}
+	if !reflect.DeepEqual(pageData.InternalLinks, expectedInternal) {
+		t.Errorf("expected internal links %v, got %v", expectedInternal, pageData.InternalLinks)
+	}
+	if !reflect.DeepEqual(pageData.ExternalLinks, []string{"https://other.org/post"}) {
+		t.Errorf("unexpected external links %v", pageData.ExternalLinks)
+	}
+}
+
+// Checks Atom feeds use href links and skip rel="self".
+func TestXMLHandlerAtom(t *testing.T) {
+	feed := `<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="fr">
+  <title>Journal</title>
+  <subtitle>Notes</subtitle>
+  <updated>2024-03-01T10:00:00Z</updated>
+  <link href="https://example.com/atom" rel="self"/>
+  <entry><title>Entry</title><link href="https://example.com/entry/1"/></entry>
+</feed>`
+	pageData, err := xmlHandler{}.Extract(feed, "https://example.com/atom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pageData.Title != "Journal" || pageData.MetaDescription != "Notes" || pageData.Language != "fr" {
+		t.Errorf("unexpected feed fields: %q %q %q", pageData.Title, pageData.MetaDescription, pageData.Language)
+	}
+	if !reflect.DeepEqual(pageData.InternalLinks, []string{"https://example.com/entry/1"}) {
+		t.Errorf("unexpected links %v", pageData.InternalLinks)
+	}
+	if pageData.DateModified.IsZero() {
+		t.Error("expected DateModified from <updated>")
+	}
+}
+
+// Checks sitemap locations become links.
+func TestXMLHandlerSitemap(t *testing.T) {
+	sitemap := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/a</loc></url>
+  <url><loc>https://example.com/b</loc></url>
+</urlset>`
+	pageData, err := xmlHandler{}.Extract(sitemap, "https://example.com/sitemap.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"https://example.com/a", "https://example.com/b"}
+	if !reflect.DeepEqual(pageData.InternalLinks, expected) {
+		t.Errorf("expected %v, got %v", expected, pageData.InternalLinks)
+	}
+}
+
+// Checks that an XML document which cannot be parsed at all is an error.
+func TestXMLHandlerInvalid(t *testing.T) {
+	if _, err := (xmlHandler{}).Extract("", "https://example.com/empty.xml"); err == nil {
+		t.Error("expected error for empty document")
+	}
+}
+
+// Checks that Fetch dispatches to registered handlers by Content-Type.
+func TestFetchDispatchesByContentType(t *testing.T) {
+	Init()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/notes":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte("Plain title\nbody"))
+		case "/feed":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte(`<rss><channel><title>Feed title</title></channel></rss>`))
+		case "/api":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer server.Close()
+
+	for path, expected := range map[string]string{"/notes": "Plain title", "/feed": "Feed title"} {
+		pageData, _, err := Fetch(context.Background(), server.URL + path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", path, err)
+		}
+		if pageData.Title != expected {
+			t.Errorf("%s: expected title %q, got %q", path, expected, pageData.Title)
+		}
+	}
+
+	// JSON is unwanted until a handler is registered for it
+	if _, result, _ := Fetch(context.Background(), server.URL + "/api"); result.ErrorCategory != types.ErrorFiltered {
+		t.Errorf("expected JSON to be filtered, got %q", result.ErrorCategory)
+	}
+	RegisterHandler(jsonTestHandler{})
+	defer func() {
+		handlersMutex.Lock()
+		delete(documentHandlers, "application/json")
+		handlersMutex.Unlock()
+	}()
+	pageData, _, err := Fetch(context.Background(), server.URL + "/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pageData.Title != "json" || pageData.MediaType != "application/json" {
+		t.Errorf("expected custom handler output, got %q (%q)", pageData.Title, pageData.MediaType)
+	}
+}
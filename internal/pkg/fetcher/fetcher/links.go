@@ -0,0 +1,203 @@
+package fetcher
+
+import (
+	"net/url"
+	"strings"
+	"webcrawler/internal/pkg/types"
+	"golang.org/x/net/html"
+)
+
+// Where a link sits, named after the nearest of these elements around it
+var linkPositions = map[string]string{
+	"head":    "head",
+	"nav":     "nav",
+	"header":  "header",
+	"main":    "main",
+	"article": "main",
+	"aside":   "aside",
+	"footer":  "footer",
+}
+
+// Landmark roles that place a link the same way as the matching elements
+var landmarkRoles = map[string]string{
+	"navigation":    "nav",
+	"banner":        "header",
+	"main":          "main",
+	"complementary": "aside",
+	"contentinfo":   "footer",
+}
+
+// Records a link as an outlink, and with the internal or external links when
+// it leads to a page to crawl
+func recordOutlink(pageData *types.PageData, base, resolved *url.URL, outlink types.Outlink,
+	internalLinks *[]string, externalLinks *[]string) {
+
+	outlink.URL = resolved.String()
+	outlink.Internal = resolved.Host == base.Host
+	pageData.Outlinks = append(pageData.Outlinks, outlink)
+	if internalLinks == nil {
+		return
+	}
+	if outlink.Internal {
+		*internalLinks = append(*internalLinks, outlink.URL)
+	} else {
+		*externalLinks = append(*externalLinks, outlink.URL)
+	}
+}
+
+// Processes <area> elements of image maps, whose alt text stands in for anchor text
+func processArea(node *html.Node, pageData *types.PageData, base *url.URL,
+	internalLinks *[]string, externalLinks *[]string) {
+
+	href := getAttribute(node, "href")
+	if href == "" {
+		return
+	}
+	resolved, ok := resolveLink(base, href)
+	if !ok {
+		return
+	}
+	recordOutlink(pageData, base, resolved, types.Outlink{
+		Kind:     "area",
+		Text:     normalizeText(getAttribute(node, "alt")),
+		Title:    normalizeText(getAttribute(node, "title")),
+		Rels:     linkRels(getAttribute(node, "rel")),
+		Position: linkPosition(node),
+	}, internalLinks, externalLinks)
+}
+
+// Records <iframe> elements. Their documents are mostly embedded players,
+// widgets and adverts rather than pages, so they are not crawled.
+func processIframe(node *html.Node, pageData *types.PageData, base *url.URL) {
+	src := getAttribute(node, "src")
+	if src == "" {
+		return
+	}
+	resolved, ok := resolveLink(base, src)
+	if !ok {
+		return
+	}
+	recordOutlink(pageData, base, resolved, types.Outlink{
+		Kind:     "iframe",
+		Title:    normalizeText(getAttribute(node, "title")),
+		Position: linkPosition(node),
+	}, nil, nil)
+}
+
+// Processes <link rel="next"> and <link rel="prev">, which lead through paginated series
+func processPagination(node *html.Node, pageData *types.PageData, base *url.URL,
+	internalLinks *[]string, externalLinks *[]string) {
+
+	rels := linkRels(getAttribute(node, "rel"))
+	paginated := false
+	for _, rel := range rels {
+		paginated = paginated || rel == "next" || rel == "prev" || rel == "previous"
+	}
+	href := getAttribute(node, "href")
+	if !paginated || href == "" {
+		return
+	}
+	resolved, ok := resolveLink(base, href)
+	if !ok {
+		return
+	}
+	recordOutlink(pageData, base, resolved, types.Outlink{
+		Kind:     "link",
+		Title:    normalizeText(getAttribute(node, "title")),
+		Rels:     rels,
+		Position: linkPosition(node),
+	}, internalLinks, externalLinks)
+}
+
+// Records the image candidates of a srcset attribute on <img> or <source>.
+// They belong to the link graph but are not pages, so they are not crawled.
+func processSrcset(node *html.Node, pageData *types.PageData, base *url.URL) {
+	srcset := getAttribute(node, "srcset")
+	if srcset == "" {
+		return
+	}
+	position := linkPosition(node)
+	alt := normalizeText(getAttribute(node, "alt"))
+	for _, candidate := range srcsetURLs(srcset) {
+		if resolved, ok := resolveLink(base, candidate); ok {
+			recordOutlink(pageData, base, resolved, types.Outlink{Kind: "srcset", Position: position, ImageAlt: alt}, nil, nil)
+		}
+	}
+}
+
+// Splits a srcset attribute into its candidate URLs. A URL runs to the next
+// whitespace, and its descriptors to the next comma outside parentheses.
+func srcsetURLs(srcset string) []string {
+	var urls []string
+	rest := srcset
+	for {
+		rest = strings.TrimLeft(rest, " \t\n\r\f,")
+		if rest == "" {
+			return urls
+		}
+		end := strings.IndexAny(rest, " \t\n\r\f")
+		if end < 0 {
+			end = len(rest)
+		}
+		candidate := rest[:end]
+		rest = rest[end:]
+		if trimmed := strings.TrimRight(candidate, ","); trimmed != candidate {
+			urls = append(urls, trimmed) // Trailing commas end a candidate without descriptors
+			continue
+		}
+		urls = append(urls, candidate)
+
+		depth := 0
+		next := len(rest)
+		for i := 0; i < len(rest) && next == len(rest); i++ {
+			switch rest[i] {
+			case '(':
+				depth++
+			case ')':
+				if depth > 0 {
+					depth--
+				}
+			case ',':
+				if depth == 0 {
+					next = i
+				}
+			}
+		}
+		rest = rest[next:]
+	}
+}
+
+// Names the part of the page a link sits in: the nearest sectioning element
+// or landmark role around it, or the body
+func linkPosition(node *html.Node) string {
+	for ancestor := node.Parent; ancestor != nil; ancestor = ancestor.Parent {
+		if ancestor.Type != html.ElementNode {
+			continue
+		}
+		for _, role := range strings.Fields(strings.ToLower(getAttribute(ancestor, "role"))) {
+			if position, landmark := landmarkRoles[role]; landmark {
+				return position
+			}
+		}
+		if position, sectioning := linkPositions[ancestor.Data]; sectioning {
+			return position
+		}
+	}
+	return "body"
+}
+
+// Returns the alt text of the first image inside an anchor that has one
+func imageAlt(node *html.Node) string {
+	image := findElementFunc(node, func(element *html.Node) bool {
+		return element.Data == "img" && strings.TrimSpace(getAttribute(element, "alt")) != ""
+	})
+	if image == nil {
+		return ""
+	}
+	return normalizeText(getAttribute(image, "alt"))
+}
+
+// Splits a rel attribute into its lower cased values
+func linkRels(rel string) []string {
+	return strings.Fields(strings.ToLower(rel))
+}
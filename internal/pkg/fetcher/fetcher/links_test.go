@@ -0,0 +1,95 @@
+package fetcher
+
+import (
+	"testing"
+	"webcrawler/internal/pkg/types"
+)
+
+// Checks that links are recorded with their text, title, rels, position and image alt text.
+func TestExtractOutlinks(t *testing.T) {
+	content := `<html><head>
+		<link rel="next" href="/page/3"><link rel="prev" href="/page/1"><link rel="stylesheet" href="/site.css">
+	</head><body>
+		<nav><a href="/about" title=" About   us ">About</a></nav>
+		<div role="contentinfo"><a href="https://social.example/@site" rel="me nofollow">Follow</a></div>
+		<article>
+			<p>Read <a href="/story">the
+				full story</a>.</p>
+			<a href="/gallery"><img src="/thumb.jpg" alt="Harbour at dusk"></a>
+			<img src="/small.jpg" srcset="/large.jpg 2x, /huge.jpg 3x" alt="Boats">
+			<map name="m"><area href="/north" alt="North pier" shape="rect" coords="0,0,10,10"></map>
+			<iframe src="https://video.example/embed/1" title="Clip"></iframe>
+		</article>
+		<a href="javascript:void(0)">Nothing</a>
+	</body></html>`
+	pd, err := traverseAndExtractPageContent(content, "https://example.com/page/2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	byURL := make(map[string]types.Outlink)
+	for _, outlink := range pd.Outlinks {
+		byURL[outlink.URL] = outlink
+	}
+	if len(pd.Outlinks) != 10 {
+		t.Errorf("expected 10 outlinks, got %d: %+v", len(pd.Outlinks), pd.Outlinks)
+	}
+	expected := map[string]types.Outlink{
+		"https://example.com/page/3":    {Kind: "link", Position: "head", Internal: true},
+		"https://example.com/about":     {Kind: "a", Text: "About", Title: "About us", Position: "nav", Internal: true},
+		"https://social.example/@site":  {Kind: "a", Text: "Follow", Position: "footer"},
+		"https://example.com/story":     {Kind: "a", Text: "the full story", Position: "main", Internal: true},
+		"https://example.com/gallery":   {Kind: "a", Position: "main", ImageAlt: "Harbour at dusk", Internal: true},
+		"https://example.com/huge.jpg":  {Kind: "srcset", Position: "main", ImageAlt: "Boats", Internal: true},
+		"https://example.com/north":     {Kind: "area", Text: "North pier", Position: "main", Internal: true},
+		"https://video.example/embed/1": {Kind: "iframe", Title: "Clip", Position: "main"},
+	}
+	for link, want := range expected {
+		got, found := byURL[link]
+		if !found {
+			t.Errorf("missing outlink %s", link)
+			continue
+		}
+		if got.Kind != want.Kind || got.Text != want.Text || got.Title != want.Title || got.Position != want.Position ||
+			got.ImageAlt != want.ImageAlt || got.Internal != want.Internal {
+			t.Errorf("unexpected outlink for %s: %+v", link, got)
+		}
+	}
+	if rels := byURL["https://social.example/@site"].Rels; len(rels) != 2 || rels[1] != "nofollow" {
+		t.Errorf("unexpected rels %v", rels)
+	}
+	if _, found := byURL["https://example.com/site.css"]; found {
+		t.Error("expected stylesheets not to be outlinks")
+	}
+
+	// Pages are crawled, images from srcset and embedded frames are not
+	crawled := make(map[string]bool)
+	for _, link := range append(pd.InternalLinks, pd.ExternalLinks...) {
+		crawled[link] = true
+	}
+	for _, link := range []string{"https://example.com/page/1", "https://example.com/north"} {
+		if !crawled[link] {
+			t.Errorf("expected %s to be crawled", link)
+		}
+	}
+	if crawled["https://example.com/large.jpg"] {
+		t.Error("expected srcset images not to be crawled")
+	}
+	if crawled["https://video.example/embed/1"] {
+		t.Error("expected iframes not to be crawled")
+	}
+}
+
+// Checks that srcset candidates are split on commas outside their URLs and descriptors.
+func TestSrcsetURLs(t *testing.T) {
+	urls := srcsetURLs(" a.jpg 1x,b.jpg  2x ,c, d.jpg, e.jpg 100w (ignored, text), f.jpg ")
+	expected := []string{"a.jpg", "b.jpg", "c", "d.jpg", "e.jpg", "f.jpg"}
+	if len(urls) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, urls)
+	}
+	for i := range expected {
+		if urls[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, urls)
+		}
+	}
+}
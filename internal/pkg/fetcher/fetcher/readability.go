@@ -0,0 +1,473 @@
+package fetcher
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"webcrawler/internal/pkg/types"
+	"golang.org/x/net/html"
+)
+
+// Main content extraction in the spirit of Mozilla's Readability. Blocks of
+// text are scored by their length and commas, the scores are handed up to
+// their containers, and the container with the best score after discounting
+// its links is taken as the article along with any siblings that score well.
+
+const (
+	minParagraphLength = 25  // Shorter blocks are not scored
+	maxBylineLength    = 100 // Longer "author" elements are biographies, not bylines
+	minLeadImageSize   = 100 // Pixels, for images that declare their size
+	maxLinkDensity     = 0.5 // Containers inside the article with more link text are dropped
+)
+
+var (
+	unlikelyCandidate = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|consent|cookie|disqus|footer|gdpr|header|legends|menu|modal|nav|newsletter|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|widget|advert`)
+	maybeCandidate    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveWeight    = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeWeight    = regexp.MustCompile(`(?i)hidden|banner|combx|comment|com-|contact|foot|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+	bylineCandidate   = regexp.MustCompile(`(?i)byline|author|dateline|writtenby|p-author`)
+	unlikelyImage     = regexp.MustCompile(`(?i)logo|icon|avatar|sprite|pixel|spacer|badge|emoji`)
+)
+
+// Elements that never hold the main content
+var boilerplateTags = map[string]struct{}{
+	"script": {}, "style": {}, "noscript": {}, "template": {}, "nav": {}, "aside": {}, "footer": {},
+	"form": {}, "button": {}, "select": {}, "textarea": {}, "input": {}, "iframe": {}, "svg": {},
+}
+
+// Elements that start a new block of text
+var blockTags = map[string]struct{}{
+	"address": {}, "article": {}, "blockquote": {}, "dd": {}, "div": {}, "dl": {}, "dt": {},
+	"figcaption": {}, "figure": {}, "h1": {}, "h2": {}, "h3": {}, "h4": {}, "h5": {}, "h6": {},
+	"header": {}, "hr": {}, "li": {}, "main": {}, "ol": {}, "p": {}, "pre": {}, "section": {},
+	"table": {}, "td": {}, "th": {}, "tr": {}, "ul": {}, "br": {},
+}
+
+// Fills in the main text, byline and lead image of a parsed HTML document.
+// Metadata the page declares is preferred over what is found in its body,
+// and the authors of its structured data over its meta tags.
+func extractMainContent(doc *html.Node, pageData *types.PageData, base *url.URL) {
+	body := findElement(doc, "body")
+	if body == nil {
+		return
+	}
+
+	bylineNode, byline := findByline(body)
+	if metaByline := metaContent(doc, "author", "byl"); metaByline != "" {
+		byline = metaByline
+	}
+	if len(pageData.Authors) > 0 {
+		byline = strings.Join(pageData.Authors, ", ")
+	}
+	pageData.Byline = byline
+
+	content := topCandidates(body)
+	var builder strings.Builder
+	for _, node := range content {
+		writeContentText(node, bylineNode, &builder)
+	}
+	pageData.MainText = strings.Join(strings.Fields(builder.String()), " ")
+
+	pageData.LeadImage = declaredImage(doc, pageData, base)
+	if pageData.LeadImage == "" {
+		for _, node := range content {
+			if image := firstContentImage(node, base); image != "" {
+				pageData.LeadImage = image
+				break
+			}
+		}
+	}
+}
+
+// Scores the blocks of text under the body and returns the best container
+// followed by its siblings that look like part of the same article, in
+// document order. The body itself is returned when nothing scores.
+func topCandidates(body *html.Node) []*html.Node {
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node // In document order, so ties go to the first
+	addScore := func(node *html.Node, score float64) {
+		if node == nil || node.Type != html.ElementNode {
+			return
+		}
+		if _, seen := scores[node]; !seen {
+			scores[node] = initialScore(node)
+			candidates = append(candidates, node)
+		}
+		scores[node] += score
+	}
+
+	var visit func(node *html.Node)
+	visit = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode || isBoilerplate(child) {
+				continue
+			}
+			if isScorable(child) {
+				text := contentText(child)
+				if length := utf8.RuneCountInString(text); length >= minParagraphLength {
+					score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(length / 100), 3)
+					addScore(child.Parent, score)
+					if child.Parent != nil {
+						addScore(child.Parent.Parent, score / 2)
+					}
+				}
+			}
+			visit(child)
+		}
+	}
+	visit(body)
+
+	var top *html.Node
+	topScore := 0.0
+	for _, candidate := range candidates {
+		scores[candidate] *= 1 - linkDensity(candidate)
+		if top == nil || scores[candidate] > topScore {
+			top, topScore = candidate, scores[candidate]
+		}
+	}
+	if top == nil || top.Parent == nil {
+		return []*html.Node{body}
+	}
+
+	threshold := math.Max(10, topScore * 0.2)
+	topClass := getAttribute(top, "class")
+	var content []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode || isBoilerplate(sibling) {
+			continue
+		}
+		if sibling == top {
+			content = append(content, sibling)
+			continue
+		}
+		score, scored := scores[sibling]
+		if scored && topClass != "" && getAttribute(sibling, "class") == topClass {
+			score += topScore * 0.2
+		}
+		if scored && score >= threshold || sibling.Data == "p" && isStandaloneParagraph(sibling) {
+			content = append(content, sibling)
+		}
+	}
+	return content
+}
+
+// Gives a container a head start by its tag, class and id
+func initialScore(node *html.Node) float64 {
+	score := 0.0
+	switch node.Data {
+	case "article", "main":
+		score = 10
+	case "div":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+	if strings.EqualFold(getAttribute(node, "role"), "main") || strings.EqualFold(getAttribute(node, "itemprop"), "articleBody") {
+		score += 25
+	}
+	for _, value := range []string{getAttribute(node, "class"), getAttribute(node, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeight.MatchString(value) {
+			score -= 25
+		}
+		if positiveWeight.MatchString(value) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// Reports whether an element's own text is scored: paragraphs, and divisions
+// holding text but no other blocks
+func isScorable(node *html.Node) bool {
+	switch node.Data {
+	case "p", "pre", "td", "blockquote":
+		return true
+	case "div", "section", "article":
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if _, block := blockTags[child.Data]; block && child.Type == html.ElementNode {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Reports whether a paragraph beside the article reads like part of it
+func isStandaloneParagraph(node *html.Node) bool {
+	text := contentText(node)
+	length := utf8.RuneCountInString(text)
+	density := linkDensity(node)
+	return length > 80 && density < 0.25 || length > 0 && density == 0 && strings.Contains(text, ". ")
+}
+
+// Reports whether an element is navigation, a banner or similar, or hidden
+func isBoilerplate(node *html.Node) bool {
+	if _, skip := boilerplateTags[node.Data]; skip {
+		return true
+	}
+	if hasAttribute(node, "hidden") || strings.EqualFold(getAttribute(node, "aria-hidden"), "true") {
+		return true
+	}
+	style := strings.ToLower(strings.ReplaceAll(getAttribute(node, "style"), " ", ""))
+	if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+	switch strings.ToLower(getAttribute(node, "role")) {
+	case "navigation", "banner", "complementary", "contentinfo", "dialog", "menu", "menubar":
+		return true
+	}
+	if node.Data == "body" || node.Data == "article" || node.Data == "main" {
+		return false
+	}
+	signature := getAttribute(node, "class") + " " + getAttribute(node, "id")
+	return unlikelyCandidate.MatchString(signature) && !maybeCandidate.MatchString(signature)
+}
+
+// Writes the text of a content node, leaving out boilerplate, the byline and
+// nested containers that are mostly links
+func writeContentText(node, bylineNode *html.Node, builder *strings.Builder) {
+	switch node.Type {
+	case html.TextNode:
+		builder.WriteString(node.Data)
+		return
+	case html.ElementNode:
+		if node == bylineNode || isBoilerplate(node) {
+			return
+		}
+		switch node.Data {
+		case "div", "section", "ul", "ol", "table":
+			if linkDensity(node) > maxLinkDensity {
+				return
+			}
+		}
+	case html.DocumentNode:
+	default:
+		return
+	}
+
+	_, block := blockTags[node.Data]
+	if block {
+		builder.WriteByte('\n')
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		writeContentText(child, bylineNode, builder)
+	}
+	if block {
+		builder.WriteByte('\n')
+	}
+}
+
+// Collects the normalized text of an element without its boilerplate
+func contentText(node *html.Node) string {
+	var builder strings.Builder
+	var visit func(node *html.Node)
+	visit = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			builder.WriteString(node.Data)
+			return
+		}
+		if node.Type == html.ElementNode && isBoilerplate(node) {
+			return
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+		if _, block := blockTags[node.Data]; block {
+			builder.WriteByte(' ')
+		}
+	}
+	visit(node)
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
+// Measures how much of an element's text sits inside links, from 0 to 1
+func linkDensity(node *html.Node) float64 {
+	total := utf8.RuneCountInString(contentText(node))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	var visit func(node *html.Node)
+	visit = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode || isBoilerplate(child) {
+				continue
+			}
+			if child.Data == "a" {
+				linked += utf8.RuneCountInString(contentText(child))
+				continue
+			}
+			visit(child)
+		}
+	}
+	visit(node)
+	return float64(linked) / float64(total)
+}
+
+// Finds the first element marked as the author's name, returning it along
+// with its text minus any leading "By"
+func findByline(body *html.Node) (*html.Node, string) {
+	var found *html.Node
+	var byline string
+	var visit func(node *html.Node)
+	visit = func(node *html.Node) {
+		for child := node.FirstChild; child != nil && found == nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if _, skip := boilerplateTags[child.Data]; skip {
+				continue
+			}
+			if isBylineElement(child) {
+				text := contentText(child)
+				if length := utf8.RuneCountInString(text); length > 0 && length <= maxBylineLength {
+					found, byline = child, trimByPrefix(text)
+					return
+				}
+			}
+			visit(child)
+		}
+	}
+	visit(body)
+	return found, byline
+}
+
+// Reports whether an element names the author by its rel, itemprop, class or id
+func isBylineElement(node *html.Node) bool {
+	if hasToken(getAttribute(node, "rel"), "author") || hasToken(getAttribute(node, "itemprop"), "author") {
+		return true
+	}
+	return bylineCandidate.MatchString(getAttribute(node, "class") + " " + getAttribute(node, "id"))
+}
+
+// Removes a leading "By" from a byline
+func trimByPrefix(byline string) string {
+	if len(byline) > 3 && strings.EqualFold(byline[:3], "by ") {
+		return strings.TrimSpace(byline[3:])
+	}
+	return byline
+}
+
+// Picks the image the page declares for sharing, from Open Graph, Twitter
+// cards or <link rel="image_src">
+func declaredImage(doc *html.Node, pageData *types.PageData, base *url.URL) string {
+	candidates := []string{
+		pageData.OpenGraph["og:image"],
+		pageData.OpenGraph["og:image:url"],
+		pageData.OpenGraph["og:image:secure_url"],
+		metaContent(doc, "twitter:image", "twitter:image:src"),
+	}
+	if link := findElementFunc(doc, func(node *html.Node) bool {
+		return node.Data == "link" && hasToken(getAttribute(node, "rel"), "image_src")
+	}); link != nil {
+		candidates = append(candidates, getAttribute(link, "href"))
+	}
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if resolved, ok := resolveLink(base, candidate); ok {
+			return resolved.String()
+		}
+	}
+	return ""
+}
+
+// Finds the first image in the content that is large enough and not a logo
+// or icon. Lazily loaded images are found by their data-src.
+func firstContentImage(node *html.Node, base *url.URL) string {
+	image := findElementFunc(node, func(node *html.Node) bool {
+		if node.Data != "img" || isBoilerplate(node) {
+			return false
+		}
+		source := imageSource(node)
+		if source == "" || strings.HasPrefix(source, "data:") {
+			return false
+		}
+		if unlikelyImage.MatchString(source + " " + getAttribute(node, "class") + " " + getAttribute(node, "id")) {
+			return false
+		}
+		for _, dimension := range []string{"width", "height"} {
+			if size, err := strconv.Atoi(strings.TrimSuffix(getAttribute(node, dimension), "px")); err == nil && size < minLeadImageSize {
+				return false
+			}
+		}
+		return true
+	})
+	if image == nil {
+		return ""
+	}
+	if resolved, ok := resolveLink(base, imageSource(image)); ok {
+		return resolved.String()
+	}
+	return ""
+}
+
+// Returns where an image is loaded from, preferring lazy loading attributes
+func imageSource(node *html.Node) string {
+	for _, name := range []string{"data-src", "data-original", "src"} {
+		if source := strings.TrimSpace(getAttribute(node, name)); source != "" {
+			return source
+		}
+	}
+	return ""
+}
+
+// Returns the content of the first <meta> with one of the given names
+func metaContent(doc *html.Node, names ...string) string {
+	meta := findElementFunc(doc, func(node *html.Node) bool {
+		if node.Data != "meta" {
+			return false
+		}
+		name := getAttribute(node, "name")
+		for _, candidate := range names {
+			if strings.EqualFold(name, candidate) && strings.TrimSpace(getAttribute(node, "content")) != "" {
+				return true
+			}
+		}
+		return false
+	})
+	if meta == nil {
+		return ""
+	}
+	return strings.TrimSpace(getAttribute(meta, "content"))
+}
+
+// Finds the first element with the given tag
+func findElement(node *html.Node, tag string) *html.Node {
+	return findElementFunc(node, func(node *html.Node) bool { return node.Data == tag })
+}
+
+// Finds the first element, in document order, that matches
+func findElementFunc(node *html.Node, matches func(*html.Node) bool) *html.Node {
+	if node.Type == html.ElementNode && matches(node) {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElementFunc(child, matches); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Reports whether an element carries an attribute, whatever its value
+func hasAttribute(node *html.Node, name string) bool {
+	for _, attr := range node.Attr {
+		if strings.EqualFold(attr.Key, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package fetcher
+
+import (
+	"strings"
+	"testing"
+)
+
+const articlePage = `<html><head><title>Bridge opens</title></head><body>
+<div class="cookie-banner">We use cookies to improve your experience. Accept all cookies?</div>
+<nav><a href="/">Home</a> <a href="/news">News</a> <a href="/sport">Sport</a></nav>
+<div id="page">
+	<div class="article-body">
+		<h1>New bridge opens to traffic</h1>
+		<p class="byline">By Jane Doe</p>
+		<img src="/images/logo.png" width="40" height="40">
+		<img src="/images/bridge.jpg" width="800" height="450">
+		<p>The city council said on Tuesday that the new bridge would open to traffic next month, after more than two years of work.</p>
+		<p>Engineers had to deal with floods, a shortage of steel, and several changes to the design, which pushed the cost up by a third.</p>
+		<ul class="related-links"><li><a href="/a">Council budget</a></li><li><a href="/b">Road works</a></li></ul>
+	</div>
+	<div class="sidebar"><p>Most read: ten things to do this weekend, and more.</p></div>
+</div>
+<footer>Copyright 2026 The Daily News. All rights reserved, everywhere.</footer>
+</body></html>`
+
+// Checks that the article text, byline and lead image are found and the page's chrome left out.
+func TestExtractMainContent(t *testing.T) {
+	pageData, err := traverseAndExtractPageContent(articlePage, "https://news.example.com/bridge")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{"New bridge opens to traffic", "open to traffic next month", "shortage of steel"} {
+		if !strings.Contains(pageData.MainText, expected) {
+			t.Errorf("expected main text to contain %q, got %q", expected, pageData.MainText)
+		}
+	}
+	for _, unexpected := range []string{"cookies", "Sport", "Most read", "Copyright", "Council budget", "Jane Doe"} {
+		if strings.Contains(pageData.MainText, unexpected) {
+			t.Errorf("expected main text to leave out %q, got %q", unexpected, pageData.MainText)
+		}
+	}
+	if !strings.Contains(pageData.VisibleText, "Copyright") {
+		t.Error("expected the full text to keep the footer")
+	}
+	if pageData.Byline != "Jane Doe" {
+		t.Errorf("expected byline %q, got %q", "Jane Doe", pageData.Byline)
+	}
+	if pageData.LeadImage != "https://news.example.com/images/bridge.jpg" {
+		t.Errorf("expected the bridge photo as lead image, got %q", pageData.LeadImage)
+	}
+}
+
+// Verifies that a declared author and sharing image win over what the body suggests.
+func TestExtractMainContentMetadata(t *testing.T) {
+	content := strings.Replace(articlePage, "<title>Bridge opens</title>",
+		`<title>Bridge opens</title><meta name="author" content="John Smith"><meta property="og:image" content="/images/share.jpg">`, 1)
+	pageData, err := traverseAndExtractPageContent(content, "https://news.example.com/bridge")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pageData.Byline != "John Smith" {
+		t.Errorf("expected byline %q, got %q", "John Smith", pageData.Byline)
+	}
+	if pageData.LeadImage != "https://news.example.com/images/share.jpg" {
+		t.Errorf("expected the Open Graph image as lead image, got %q", pageData.LeadImage)
+	}
+}
+
+// Ensures a page without paragraphs still gets its text, minus navigation.
+func TestTopCandidatesFallback(t *testing.T) {
+	pageData, err := traverseAndExtractPageContent(`<html><body><nav><a href="/">Home</a></nav><span>Short note</span></body></html>`, "https://example.com/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pageData.MainText != "Short note" {
+		t.Errorf("expected main text %q, got %q", "Short note", pageData.MainText)
+	}
+}
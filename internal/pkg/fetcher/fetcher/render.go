@@ -0,0 +1,291 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Set to true to re-fetch JavaScript dependent pages through headless Chrome
+const EnvRenderJS = "WEBCRAWLER_RENDER_JS"
+
+const (
+	maxRenderTabs       = 2 // Tabs per fetcher process
+	renderTimeout       = 15 * time.Second
+	renderSettleTime    = 500 * time.Millisecond // Time for scripts to fill the page after load
+	minStaticTextLength = 500                    // Pages with this much text are never rendered
+)
+
+var ErrRenderingUnavailable = errors.New("headless Chrome is not available")
+
+var (
+	renderingEnabled bool
+
+	// Chrome binaries looked for on the PATH, in order
+	chromeExecutables = []string{
+		"headless-shell", "headless_shell", "chromium", "chromium-browser",
+		"google-chrome", "google-chrome-stable", "chrome",
+	}
+
+	// Subresources that never affect the rendered text
+	blockedResourcePatterns = []string{
+		"*.png", "*.jpg", "*.jpeg", "*.gif", "*.webp", "*.svg", "*.ico", "*.avif",
+		"*.woff", "*.woff2", "*.ttf", "*.otf", "*.eot",
+		"*.mp4", "*.webm", "*.mp3", "*.ogg", "*.wav",
+		"*.css",
+	}
+
+	// Markers of client-side rendered apps whose HTML is an empty shell
+	spaRootMarkers = []string{
+		`id="root"`, `id="app"`, `id="__next"`, `id="__nuxt"`, `id="svelte"`,
+		"ng-app", "ng-version", "data-reactroot", "<app-root",
+	}
+	noscriptWarnings = []string{
+		"enable javascript", "javascript is disabled", "javascript is required",
+		"requires javascript", "turn on javascript", "javascript enabled",
+	}
+
+	browserMutex   sync.Mutex
+	browserContext context.Context
+	tabPool        chan *renderTab
+	openTabs       int
+)
+
+// A browser tab that is reused between renders
+type renderTab struct {
+	context context.Context
+	cancel  context.CancelFunc
+}
+
+// Reads the rendering option from the environment
+func loadRenderingOption() error {
+	value, ok := os.LookupEnv(EnvRenderJS)
+	if !ok || value == "" {
+		renderingEnabled = false
+		return nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s value %q: %v", EnvRenderJS, value, err)
+	}
+	renderingEnabled = enabled
+	return nil
+}
+
+// Returns the path of a Chrome binary, or an empty string if none is installed
+func findChrome() string {
+	for _, name := range chromeExecutables {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Reports whether an HTML page probably needs JavaScript to show its content:
+// it has no visible text, or little text alongside an SPA root element or a
+// <noscript> warning.
+func needsRendering(content string, visibleText string) bool {
+	if strings.TrimSpace(visibleText) == "" {
+		return true
+	}
+	if len(visibleText) >= minStaticTextLength {
+		return false
+	}
+
+	lower := strings.ReplaceAll(strings.ToLower(content), "'", `"`)
+	for _, marker := range spaRootMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	if strings.Contains(lower, "<noscript") {
+		for _, warning := range noscriptWarnings {
+			if strings.Contains(lower, warning) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Reports whether a page may be rendered. The browser connects directly, so
+// pages that are fetched through a proxy are never rendered.
+func canRender(pageURL string) bool {
+	if proxyPool == nil {
+		return true
+	}
+	parsed, err := url.Parse(pageURL)
+	return err == nil && proxyPool.IsDirect(parsed.Hostname())
+}
+
+// Starts the shared browser if it is not already running. Must be called
+// with browserMutex held.
+func startBrowser() error {
+	if browserContext != nil {
+		return nil
+	}
+	chromePath := findChrome()
+	if chromePath == "" {
+		return ErrRenderingUnavailable
+	}
+
+	options := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.ExecPath(chromePath),
+		chromedp.Flag("blink-settings", "imagesEnabled=false"),
+		chromedp.UserAgent(crawlerIdentity.UserAgent()),
+	)
+	allocatorContext, cancelAllocator := chromedp.NewExecAllocator(context.Background(), options...)
+	newBrowserContext, cancelBrowser := chromedp.NewContext(allocatorContext)
+
+	// Running an empty task list launches the browser
+	if err := chromedp.Run(newBrowserContext); err != nil {
+		cancelBrowser()
+		cancelAllocator()
+		return fmt.Errorf("failed to start headless Chrome: %w", err)
+	}
+
+	browserContext = newBrowserContext
+	browserCancel = cancelBrowser
+	allocCancel = cancelAllocator
+	tabPool = make(chan *renderTab, maxRenderTabs)
+	openTabs = 0
+	return nil
+}
+
+// Takes a tab from the pool, opening a new one if fewer than maxRenderTabs
+// are open, or waits for one to be released.
+func acquireTab(context context.Context) (*renderTab, error) {
+	browserMutex.Lock()
+	if err := startBrowser(); err != nil {
+		browserMutex.Unlock()
+		return nil, err
+	}
+	pool := tabPool
+	select {
+	case tab := <-pool:
+		browserMutex.Unlock()
+		return tab, nil
+	default:
+	}
+	if openTabs < maxRenderTabs {
+		openTabs++
+		parent := browserContext
+		browserMutex.Unlock()
+		return openTab(parent)
+	}
+	browserMutex.Unlock()
+
+	select {
+	case tab := <-pool:
+		return tab, nil
+	case <-context.Done():
+		return nil, context.Err()
+	}
+}
+
+// Opens and configures a new tab
+func openTab(parent context.Context) (*renderTab, error) {
+	tabContext, cancel := chromedp.NewContext(parent)
+	headers := network.Headers{}
+	if crawlerIdentity.From != "" {
+		headers["From"] = crawlerIdentity.From
+	}
+	err := chromedp.Run(tabContext,
+		network.Enable(),
+		network.SetBlockedURLS(blockedResourcePatterns),
+		network.SetExtraHTTPHeaders(headers),
+		emulation.SetUserAgentOverride(crawlerIdentity.UserAgent()),
+	)
+	if err != nil {
+		cancel()
+		browserMutex.Lock()
+		openTabs--
+		browserMutex.Unlock()
+		return nil, fmt.Errorf("failed to open browser tab: %w", err)
+	}
+	return &renderTab{context: tabContext, cancel: cancel}, nil
+}
+
+// Returns a tab to the pool, or closes it if it may be in a bad state
+func releaseTab(tab *renderTab, healthy bool) {
+	browserMutex.Lock()
+	defer browserMutex.Unlock()
+	if healthy && tabPool != nil {
+		select {
+		case tabPool <- tab:
+			return
+		default:
+		}
+	}
+	tab.cancel()
+	if openTabs > 0 {
+		openTabs--
+	}
+}
+
+// Loads a page in headless Chrome and returns the HTML of the rendered DOM,
+// reporting whether it was cut off at maxBodySize
+func renderPage(parent context.Context, pageURL string) (string, bool, error) {
+	tab, err := acquireTab(parent)
+	if err != nil {
+		return "", false, err
+	}
+
+	renderContext, cancel := context.WithTimeout(tab.context, renderTimeout)
+	defer cancel()
+	// Give up as soon as the caller does
+	stop := context.AfterFunc(parent, cancel)
+	defer stop()
+
+	var rendered string
+	err = chromedp.Run(renderContext,
+		chromedp.Navigate(pageURL),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		chromedp.Sleep(renderSettleTime),
+		chromedp.OuterHTML("html", &rendered, chromedp.ByQuery),
+	)
+	releaseTab(tab, err == nil)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to render %s: %w", pageURL, err)
+	}
+	rendered, truncated := truncateText(rendered, maxBodySize)
+	return rendered, truncated, nil
+}
+
+// Cuts text to at most limit bytes without splitting a UTF-8 sequence,
+// reporting whether anything was cut
+func truncateText(text string, limit int) (string, bool) {
+	if len(text) <= limit {
+		return text, false
+	}
+	end := limit
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return text[:end], true
+}
+
+// Closes pooled tabs. The browser itself is closed by Shutdown.
+func closeTabs() {
+	browserMutex.Lock()
+	defer browserMutex.Unlock()
+	for tabPool != nil && len(tabPool) > 0 {
+		(<-tabPool).cancel()
+	}
+	tabPool = nil
+	openTabs = 0
+	browserContext = nil
+}
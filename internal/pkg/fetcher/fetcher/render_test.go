@@ -0,0 +1,174 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+	"webcrawler/internal/pkg/proxy"
+)
+
+// Page whose text only exists once its script has run.
+const scriptedPage = `<!DOCTYPE html><html lang="en"><head><title>App</title></head>
+<body><div id="root"></div>
+<noscript>You need to enable JavaScript to run this app.</noscript>
+<script>document.getElementById("root").innerHTML = "<p>Rendered by script</p>";</script>
+</body></html>`
+
+// Skips the test when no Chrome binary is installed.
+func requireChrome(t *testing.T) {
+	t.Helper()
+	if findChrome() == "" {
+		t.Skip("no Chrome binary found, skipping rendering test")
+	}
+}
+
+// Checks which pages are detected as needing JavaScript.
+func TestNeedsRendering(t *testing.T) {
+	longText := strings.Repeat("word ", 200)
+	tests := []struct {
+		name     string
+		content  string
+		text     string
+		expected bool
+	}{
+		{"empty body", "<html><body></body></html>", "", true},
+		{"spa root", `<div id='root'></div>`, "Loading", true},
+		{"next.js", `<div id="__next"></div>`, "Loading", true},
+		{"angular", `<app-root></app-root>`, "Loading", true},
+		{"noscript warning", `<noscript>Please enable JavaScript</noscript>`, "Welcome", true},
+		{"javascript mentioned outside noscript", `<p>Enable JavaScript for the best experience</p>`, "Welcome", false},
+		{"static page", `<p>Hello</p>`, "Hello", false},
+		{"plenty of text despite marker", `<div id="root"><p>` + longText + `</p></div>`, longText, false},
+	}
+
+	for _, tc := range tests {
+		if got := needsRendering(tc.content, tc.text); got != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+// Checks the rendering option is read from the environment.
+func TestLoadRenderingOption(t *testing.T) {
+	defer func() { renderingEnabled = false }()
+
+	t.Setenv(EnvRenderJS, "true")
+	if err := loadRenderingOption(); err != nil || !renderingEnabled {
+		t.Errorf("expected rendering enabled, got %v (%v)", renderingEnabled, err)
+	}
+	t.Setenv(EnvRenderJS, "")
+	if err := loadRenderingOption(); err != nil || renderingEnabled {
+		t.Errorf("expected rendering disabled, got %v (%v)", renderingEnabled, err)
+	}
+	t.Setenv(EnvRenderJS, "sometimes")
+	if err := loadRenderingOption(); err == nil {
+		t.Error("expected error for invalid value")
+	}
+}
+
+// Checks that only pages fetched without a proxy are rendered, since the
+// browser does not go through the proxy pool.
+func TestCanRender(t *testing.T) {
+	t.Setenv(proxy.EnvProxies, "http://127.0.0.1:3128")
+	t.Setenv(proxy.EnvProxyRules, "example.com=direct")
+	if err := loadProxies(); err != nil {
+		t.Fatal(err)
+	}
+	defer Shutdown()
+
+	if !canRender("https://www.example.com/app") {
+		t.Error("expected a directly routed page to be renderable")
+	}
+	if canRender("https://example.org/app") {
+		t.Error("expected a proxied page not to be rendered")
+	}
+}
+
+// Checks that rendering is reported unavailable without Chrome.
+func TestRenderPageWithoutChrome(t *testing.T) {
+	if findChrome() != "" {
+		t.Skip("Chrome is installed")
+	}
+	if _, _, err := renderPage(context.Background(), "http://127.0.0.1/"); err != ErrRenderingUnavailable {
+		t.Errorf("expected ErrRenderingUnavailable, got %v", err)
+	}
+}
+
+// Checks that rendered pages are cut at the size limit without splitting a character.
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		text      string
+		limit     int
+		expected  string
+		truncated bool
+	}{
+		{"short", 10, "short", false},
+		{"exactly", 7, "exactly", false},
+		{"abcdef", 3, "abc", true},
+		{"ab€cd", 3, "ab", true}, // € takes three bytes
+		{"ab€cd", 4, "ab", true},
+		{"ab€cd", 5, "ab€", true},
+	}
+	for _, tt := range tests {
+		text, truncated := truncateText(tt.text, tt.limit)
+		if text != tt.expected || truncated != tt.truncated {
+			t.Errorf("truncateText(%q, %d): expected %q %v, got %q %v", tt.text, tt.limit, tt.expected, tt.truncated, text, truncated)
+		}
+		if !utf8.ValidString(text) {
+			t.Errorf("truncateText(%q, %d): split a character, got %q", tt.text, tt.limit, text)
+		}
+	}
+}
+
+// Checks that a script-built page is rendered and its text extracted.
+func TestFetchRendersJavaScriptPage(t *testing.T) {
+	requireChrome(t)
+	Init()
+	renderingEnabled = true
+	defer func() {
+		renderingEnabled = false
+		Shutdown()
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(scriptedPage))
+	}))
+	defer server.Close()
+
+	pageData, result, err := Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch returned unexpected error: %v", err)
+	}
+	if !result.Rendered {
+		t.Error("expected page to be rendered")
+	}
+	if !strings.Contains(pageData.VisibleText, "Rendered by script") {
+		t.Errorf("expected rendered text, got %q", pageData.VisibleText)
+	}
+}
+
+// Checks that tabs are reused rather than opened for every render.
+func TestRenderTabPool(t *testing.T) {
+	requireChrome(t)
+	defer Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(scriptedPage))
+	}))
+	defer server.Close()
+
+	for i := 0; i < maxRenderTabs + 2; i++ {
+		if _, _, err := renderPage(context.Background(), server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	browserMutex.Lock()
+	defer browserMutex.Unlock()
+	if openTabs != 1 {
+		t.Errorf("expected sequential renders to share one tab, got %d open", openTabs)
+	}
+}
@@ -0,0 +1,77 @@
+package fetcher
+
+import (
+	"strings"
+	"webcrawler/internal/pkg/dates"
+	"webcrawler/internal/pkg/types"
+	"golang.org/x/net/html"
+)
+
+const (
+	markedTimeConfidence   = 0.75 // A <time> whose class or itemprop says which date it is
+	pubdateConfidence      = 0.8  // A <time pubdate>
+	firstTimeConfidence    = 0.5  // The first unmarked <time>, usually the article's
+	lastModifiedConfidence = 0.3  // Servers often send the time of the request for generated pages
+)
+
+// Offers the dates of <time> elements and of the page's URL as candidates for
+// its published and modified dates. Times in navigation, sidebars and footers
+// are left out.
+func extractDates(doc *html.Node, pageData *types.PageData, pageURL string) {
+	firstSeen := false
+	var visit func(node *html.Node)
+	visit = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode || isBoilerplate(child) {
+				continue
+			}
+			if child.Data != "time" {
+				visit(child)
+				continue
+			}
+
+			value := getAttribute(child, "datetime")
+			if value == "" {
+				value = extractNodeText(child)
+			}
+			parsed, ok := dates.Parse(value)
+			if !ok {
+				continue
+			}
+			kind, confidence := timeKind(child)
+			if kind == dates.Unknown {
+				if firstSeen {
+					continue
+				}
+				firstSeen = true
+				kind, confidence = dates.Published, firstTimeConfidence
+			}
+			dates.Offer(pageData, kind, dates.Candidate{Time: parsed, Source: dates.SourceTime, Confidence: confidence})
+		}
+	}
+	visit(doc)
+
+	if candidate, ok := dates.FromURL(pageURL); ok {
+		dates.Offer(pageData, dates.Published, candidate)
+	}
+}
+
+// Tells which date a <time> holds from its pubdate attribute or the itemprop
+// and class of it and its parent
+func timeKind(node *html.Node) (dates.Kind, float64) {
+	if hasAttribute(node, "pubdate") {
+		return dates.Published, pubdateConfidence
+	}
+	marker := getAttribute(node, "itemprop") + " " + getAttribute(node, "class")
+	if node.Parent != nil {
+		marker += " " + getAttribute(node.Parent, "class")
+	}
+	marker = strings.ToLower(marker)
+	switch {
+	case strings.Contains(marker, "modified") || strings.Contains(marker, "updated"):
+		return dates.Modified, markedTimeConfidence
+	case strings.Contains(marker, "published") || strings.Contains(marker, "pubdate") || strings.Contains(marker, "posted"):
+		return dates.Published, markedTimeConfidence
+	}
+	return dates.Unknown, 0
+}
@@ -0,0 +1,71 @@
+package fetcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"webcrawler/internal/pkg/dates"
+)
+
+// Checks that <time> elements, meta itemprops and the URL each contribute the dates they are trusted for.
+func TestExtractDates(t *testing.T) {
+	content := `<html><head><meta itemprop="dateModified" content="2024-03-07T08:00:00Z"></head><body>
+		<nav><time datetime="2026-01-01">Today</time></nav>
+		<article>
+			<p>Posted <time datetime="2024-03-05T10:00:00+01:00">5 March</time></p>
+			<p class="updated">Updated <time>March 6, 2024</time></p>
+		</article>
+	</body></html>`
+	pd, err := traverseAndExtractPageContent(content, "https://news.example.com/2024/03/04/bridge")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC); !pd.DatePublished.Equal(expected) || pd.PublishedFrom.Source != dates.SourceURL {
+		t.Errorf("expected the URL's date to beat the first unmarked <time>, got %v from %+v", pd.DatePublished, pd.PublishedFrom)
+	}
+	if expected := time.Date(2024, time.March, 7, 8, 0, 0, 0, time.UTC); !pd.DateModified.Equal(expected) || pd.ModifiedFrom.Source != dates.SourceMeta {
+		t.Errorf("expected the itemprop meta to beat the updated <time>, got %v from %+v", pd.DateModified, pd.ModifiedFrom)
+	}
+
+	pd, err = traverseAndExtractPageContent(content, "https://news.example.com/bridge")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := time.Date(2024, time.March, 5, 9, 0, 0, 0, time.UTC); !pd.DatePublished.Equal(expected) || pd.PublishedFrom.Source != dates.SourceTime {
+		t.Errorf("expected the article's <time> rather than the navigation's, got %v from %+v", pd.DatePublished, pd.PublishedFrom)
+	}
+}
+
+// Verifies that Last-Modified is used only when the page gives no better modified date.
+func TestFetchLastModified(t *testing.T) {
+	Init()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Last-Modified", "Wed, 06 Mar 2024 12:00:00 GMT")
+		if r.URL.Path == "/dated" {
+			_, _ = io.WriteString(w, `<html><head><meta property="article:modified_time" content="2024-03-07T08:00:00Z"></head><body>Bridge</body></html>`)
+			return
+		}
+		_, _ = io.WriteString(w, `<html><body>Bridge</body></html>`)
+	}))
+	defer server.Close()
+
+	pd, _, err := Fetch(context.Background(), server.URL + "/plain")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := time.Date(2024, time.March, 6, 12, 0, 0, 0, time.UTC); !pd.DateModified.Equal(expected) || pd.ModifiedFrom.Source != dates.SourceHeader {
+		t.Errorf("expected the header's date, got %v from %+v", pd.DateModified, pd.ModifiedFrom)
+	}
+
+	pd, _, err = Fetch(context.Background(), server.URL + "/dated")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pd.ModifiedFrom.Source != dates.SourceMeta {
+		t.Errorf("expected the page's own date to win, got %v from %+v", pd.DateModified, pd.ModifiedFrom)
+	}
+}
@@ -5,12 +5,16 @@ import (
     "encoding/gob"
     "context"
     "fmt"
+    "hash/fnv"
     "log"
     "math/rand"
     "os/exec"
+    "strings"
     "sync"
+    "sync/atomic"
     "time"
     "webcrawler/internal/pkg/types"
+    "webcrawler/internal/pkg/utils"
 )
 
 // Represents one child process
@@ -23,27 +27,36 @@ type Worker struct {
     mutex      	sync.Mutex
 }
 
-// Manages a pool of worker processes
+// Manages a pool of worker processes. URLs for the same host go to the same
+// worker so that its keep-alive and HTTP/2 connections to the host are reused.
 type WorkerPool struct {
     size       		int
-    workerChannel   chan *Worker // holds idle workers
+    idleWorkers     map[int]*Worker // idle workers by id
+    releasedChannel chan struct{}   // closed and replaced whenever a worker becomes idle
     workers    		[]*Worker
     workerMutex   	sync.Mutex
     shutdownChannel chan struct{}
     waitGroup       sync.WaitGroup
+    affinityHits    atomic.Int64 // requests served by the host's own worker
+    affinityMisses  atomic.Int64 // requests handed to another worker after waiting
 }
 
+// How long a request waits for its host's worker before taking any idle one
+var affinityWait = 2 * time.Second
+
 // WorkerRequest / WorkerResponse mirror the IPC protocol
 type WorkerRequest struct {
-    RequestID string
-    URL       string
+    RequestID       string
+    URL             string
+    Addresses       []string  // Already resolved addresses of the URL's host, if any
+    AddressesExpire time.Time // When the addresses stop being valid
 }
 
 type WorkerResponse struct {
-    RequestID  string
-    PageData   types.PageData
-    FetchError string
-    FetchTime  time.Duration
+    RequestID   string
+    PageData    types.PageData
+    FetchResult types.FetchResult
+    FetchTime   time.Duration
 }
 
 const FETCHER_MAIN_PATH = "internal/pkg/fetcher/cmd/app/fetcher_main.go"
@@ -52,7 +65,8 @@ const FETCHER_MAIN_PATH = "internal/pkg/fetcher/cmd/app/fetcher_main.go"
 func NewWorkerPool(size int) (*WorkerPool, error) {
     workerPool := &WorkerPool{
         size:       	 size,
-        workerChannel:   make(chan *Worker, size),
+        idleWorkers:     make(map[int]*Worker, size),
+        releasedChannel: make(chan struct{}),
         shutdownChannel: make(chan struct{}),
     }
     for i := 0; i < size; i++ {
@@ -61,13 +75,18 @@ func NewWorkerPool(size int) (*WorkerPool, error) {
             return nil, fmt.Errorf("failed to start worker %d: %v", i, err)
         }
         workerPool.workers = append(workerPool.workers, worker)
-        workerPool.workerChannel <- worker
+        workerPool.idleWorkers[worker.id] = worker
     }
     return workerPool, nil
 }
 
 // Sends a URL to an idle worker, waits up to the context deadline
 func (workerPool *WorkerPool) FetchURL(context context.Context, url string) (WorkerResponse, error) {
+    return workerPool.FetchURLWithAddresses(context, url, nil, time.Time{})
+}
+
+// Like FetchURL, also passing the host's addresses so the worker can skip the DNS lookup
+func (workerPool *WorkerPool) FetchURLWithAddresses(context context.Context, url string, addresses []string, expires time.Time) (WorkerResponse, error) {
     response := WorkerResponse{}
 
     // Don’t accept new requests if shutting down
@@ -77,12 +96,10 @@ func (workerPool *WorkerPool) FetchURL(context context.Context, url string) (Wor
         default:
     }
 
-    // Grab a worker
-    var worker *Worker
-    select {
-    case worker = <-workerPool.workerChannel:
-    case <-context.Done():
-        return response, fmt.Errorf("no worker available before timeout: %w", context.Err())
+    // Grab the host's worker, or any worker if it stays busy
+    worker, err := workerPool.acquireWorker(context, workerPool.workerFor(url))
+    if err != nil {
+        return response, err
     }
 
     // Add to wait group so we can wait if needed on shutdown
@@ -91,10 +108,12 @@ func (workerPool *WorkerPool) FetchURL(context context.Context, url string) (Wor
 
     // Send request
     request := WorkerRequest{
-        RequestID: fmt.Sprintf("req-%d", rand.Int63()),
-        URL:       url,
+        RequestID:       fmt.Sprintf("req-%d", rand.Int63()),
+        URL:             url,
+        Addresses:       addresses,
+        AddressesExpire: expires,
     }
-    response, err := sendRequest(context, worker, request)
+    response, err = sendRequest(context, worker, request)
     if err != nil {
         // kill this worker and try to spawn a new one
         log.Printf("Killing worker %d due to error: %v", worker.id, err)
@@ -104,15 +123,25 @@ func (workerPool *WorkerPool) FetchURL(context context.Context, url string) (Wor
             workerPool.replaceWorker(worker, newWorker)
         } else {
             log.Printf("[WorkerPool] failed to respawn worker %d: %v", worker.id, spawnErr)
+            workerPool.removeWorker(worker)
         }
         return response, err
     }
 
     // success => put worker back
-    workerPool.workerChannel <- worker
+    workerPool.releaseWorker(worker)
     return response, nil
 }
 
+// Reports how often requests were served by their host's own worker
+func (workerPool *WorkerPool) AffinityRate() float64 {
+    hits, misses := workerPool.affinityHits.Load(), workerPool.affinityMisses.Load()
+    if hits + misses == 0 {
+        return 0
+    }
+    return float64(hits) / float64(hits + misses)
+}
+
 // Kills all child processes and waits for in-flight requests to end.
 func (workerPool *WorkerPool) Shutdown() {
     close(workerPool.shutdownChannel)
@@ -126,15 +155,74 @@ func (workerPool *WorkerPool) Shutdown() {
     // Wait for any in-flight requests to finish
     workerPool.waitGroup.Wait()
 
-    // Close the worker channel
-    close(workerPool.workerChannel)
-
     // Clear the worker list
+    workerPool.workerMutex.Lock()
     workerPool.workers = []*Worker{}
+    workerPool.idleWorkers = make(map[int]*Worker)
+    workerPool.workerMutex.Unlock()
 }
 
 // ### WORKER MANAGEMENT INTERNALS ###
 
+// Returns the id of the worker responsible for a URL's host
+func (workerPool *WorkerPool) workerFor(url string) int {
+    host, err := utils.GetHostnameFromURL(url)
+    if err != nil {
+        host = url
+    }
+    hash := fnv.New32a()
+    hash.Write([]byte(strings.ToLower(host)))
+    return int(hash.Sum32() % uint32(workerPool.size))
+}
+
+// Takes the preferred worker once it is idle. If it is still busy after
+// affinityWait, or is gone because it could not be respawned, any idle
+// worker is taken instead.
+func (workerPool *WorkerPool) acquireWorker(context context.Context, preferred int) (*Worker, error) {
+    timer := time.NewTimer(affinityWait)
+    defer timer.Stop()
+    anyWorker := false
+
+    for {
+        workerPool.workerMutex.Lock()
+        if worker, idle := workerPool.idleWorkers[preferred]; idle {
+            delete(workerPool.idleWorkers, preferred)
+            workerPool.workerMutex.Unlock()
+            workerPool.affinityHits.Add(1)
+            return worker, nil
+        }
+        if anyWorker || !workerPool.isLive(preferred) {
+            for id, worker := range workerPool.idleWorkers {
+                delete(workerPool.idleWorkers, id)
+                workerPool.workerMutex.Unlock()
+                workerPool.affinityMisses.Add(1)
+                return worker, nil
+            }
+        }
+        released := workerPool.releasedChannel
+        workerPool.workerMutex.Unlock()
+
+        select {
+        case <-released:
+        case <-timer.C:
+            anyWorker = true
+        case <-workerPool.shutdownChannel:
+            return nil, fmt.Errorf("worker pool is shutting down")
+        case <-context.Done():
+            return nil, fmt.Errorf("no worker available before timeout: %w", context.Err())
+        }
+    }
+}
+
+// Marks a worker idle and wakes any requests waiting for one
+func (workerPool *WorkerPool) releaseWorker(worker *Worker) {
+    workerPool.workerMutex.Lock()
+    defer workerPool.workerMutex.Unlock()
+    workerPool.idleWorkers[worker.id] = worker
+    close(workerPool.releasedChannel)
+    workerPool.releasedChannel = make(chan struct{})
+}
+
 // Starts a new worker process
 func startWorker(id int) (*Worker, error) {
     cmd := exec.Command("go", "run", FETCHER_MAIN_PATH)
@@ -204,6 +292,32 @@ func sendRequest(context context.Context, worker *Worker, request WorkerRequest)
     }
 }
 
+// Reports whether a worker with the id is running, idle or busy. Must be
+// called with the mutex held.
+func (workerPool *WorkerPool) isLive(id int) bool {
+    for _, worker := range workerPool.workers {
+        if worker.id == id {
+            return true
+        }
+    }
+    return false
+}
+
+// Drops a worker that could not be respawned, waking the requests waiting for
+// it so they take another worker at once
+func (workerPool *WorkerPool) removeWorker(oldWorker *Worker) {
+    workerPool.workerMutex.Lock()
+    defer workerPool.workerMutex.Unlock()
+    for i, worker := range workerPool.workers {
+        if worker == oldWorker {
+            workerPool.workers = append(workerPool.workers[:i], workerPool.workers[i + 1:]...)
+            close(workerPool.releasedChannel)
+            workerPool.releasedChannel = make(chan struct{})
+            return
+        }
+    }
+}
+
 // Replaces an old worker with a new one
 func (workerPool *WorkerPool) replaceWorker(oldWorker, newWorker *Worker) {
     workerPool.workerMutex.Lock()
@@ -211,8 +325,10 @@ func (workerPool *WorkerPool) replaceWorker(oldWorker, newWorker *Worker) {
     for i, worker := range workerPool.workers {
         if worker == oldWorker {
             workerPool.workers[i] = newWorker
-            // put new worker in channel
-            workerPool.workerChannel <- newWorker
+            // mark the new worker idle
+            workerPool.idleWorkers[newWorker.id] = newWorker
+            close(workerPool.releasedChannel)
+            workerPool.releasedChannel = make(chan struct{})
             return
         }
     }
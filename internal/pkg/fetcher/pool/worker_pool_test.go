@@ -2,6 +2,7 @@ package worker_pool
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -69,3 +70,99 @@ func TestWorkerPool_FetchURL_Timeout(t *testing.T) {
 	 	t.Errorf("Expected deadline exceeded error, got: %v", fetchErr)
 	}
 }
+
+// Creates a pool of placeholder workers without starting any processes.
+func newIdlePool(size int) *WorkerPool {
+	workerPool := &WorkerPool{
+		size:            size,
+		idleWorkers:     make(map[int]*Worker),
+		releasedChannel: make(chan struct{}),
+		shutdownChannel: make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		worker := &Worker{id: i}
+		workerPool.workers = append(workerPool.workers, worker)
+		workerPool.idleWorkers[i] = worker
+	}
+	return workerPool
+}
+
+// Checks that URLs for the same host are assigned to the same worker.
+func TestWorkerPool_WorkerFor(t *testing.T) {
+	workerPool := newIdlePool(10)
+	first := workerPool.workerFor("https://example.com/a")
+	for _, url := range []string{"example.com/b", "http://EXAMPLE.com/c?d=e", "https://example.com"} {
+		if got := workerPool.workerFor(url); got != first {
+			t.Errorf("Expected %s to go to worker %d, got %d", url, first, got)
+		}
+	}
+
+	seen := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		seen[workerPool.workerFor(fmt.Sprintf("https://host%d.example/", i))] = true
+	}
+	if len(seen) < 5 {
+		t.Errorf("Expected hosts to spread across workers, only %d used", len(seen))
+	}
+}
+
+// Checks that a request waits for its host's worker, then falls back to any idle worker.
+func TestWorkerPool_AcquireWorkerAffinity(t *testing.T) {
+	defer func(previous time.Duration) { affinityWait = previous }(affinityWait)
+	affinityWait = 50 * time.Millisecond
+	workerPool := newIdlePool(2)
+
+	preferred, err := workerPool.acquireWorker(context.Background(), 0)
+	if err != nil || preferred.id != 0 {
+		t.Fatalf("Expected worker 0, got %v, %v", preferred, err)
+	}
+
+	// Worker 0 comes back before the wait runs out, so it is used again
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		workerPool.releaseWorker(preferred)
+	}()
+	again, err := workerPool.acquireWorker(context.Background(), 0)
+	if err != nil || again.id != 0 {
+		t.Fatalf("Expected to wait for worker 0, got %v, %v", again, err)
+	}
+
+	// Worker 0 stays busy, so worker 1 is taken once the wait runs out
+	start := time.Now()
+	other, err := workerPool.acquireWorker(context.Background(), 0)
+	if err != nil || other.id != 1 {
+		t.Fatalf("Expected to fall back to worker 1, got %v, %v", other, err)
+	}
+	if time.Since(start) < affinityWait {
+		t.Error("Expected to wait for the preferred worker before falling back")
+	}
+	if rate := workerPool.AffinityRate(); rate < 0.66 || rate > 0.67 {
+		t.Errorf("Expected an affinity rate of 2/3, got %v", rate)
+	}
+
+	// Nothing is idle, so the caller's deadline ends the wait
+	ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Millisecond)
+	defer cancel()
+	if _, err := workerPool.acquireWorker(ctx, 1); err == nil {
+		t.Error("Expected an error with no idle workers")
+	}
+}
+
+// Checks that a request whose host's worker could not be respawned takes another worker without waiting.
+func TestWorkerPool_AcquireWorkerRemoved(t *testing.T) {
+	workerPool := newIdlePool(2)
+	dead, err := workerPool.acquireWorker(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workerPool.removeWorker(dead)
+
+	start := time.Now()
+	other, err := workerPool.acquireWorker(context.Background(), 0)
+	if err != nil || other.id != 1 {
+		t.Fatalf("Expected worker 1, got %v, %v", other, err)
+	}
+	if elapsed := time.Since(start); elapsed >= affinityWait {
+		t.Errorf("Expected no wait for a removed worker, took %v", elapsed)
+	}
+}
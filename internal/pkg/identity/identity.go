@@ -0,0 +1,124 @@
+package identity
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Selects which User-Agent header is sent with page requests
+type Profile string
+
+const (
+	ProfileBot    Profile = "bot"    // Always send the bot User-Agent
+	ProfileRotate Profile = "rotate" // Opt-in: rotate through browser User-Agents from a list
+)
+
+// Environment variables read by FromEnvironment. The administrator exports
+// them so every fetcher process presents the same identity.
+const (
+	EnvProductToken = "WEBCRAWLER_BOT_TOKEN"
+	EnvVersion      = "WEBCRAWLER_BOT_VERSION"
+	EnvContactURL   = "WEBCRAWLER_CONTACT_URL"
+	EnvFrom         = "WEBCRAWLER_FROM"
+	EnvProfile      = "WEBCRAWLER_UA_PROFILE"
+)
+
+const (
+	DefaultProductToken = "webcrawler"
+	DefaultVersion      = "1.0"
+	DefaultContactURL   = "https://github.com/daniel-maxwell/WebCrawler"
+)
+
+// RFC 9309 limits product tokens to letters, underscores and hyphens
+var productTokenPattern = regexp.MustCompile(`^[a-zA-Z_-]+$`)
+
+// How the crawler identifies itself to the sites it visits
+type Identity struct {
+	ProductToken string  // Bot name, also matched against robots.txt User-agent lines
+	Version      string  // Version appended to the product token
+	ContactURL   string  // Page describing the crawler and how to reach its operators
+	From         string  // Optional operator email sent in the From header
+	Profile      Profile // Which User-Agent is sent with page requests
+}
+
+// Returns the identity used when nothing is configured
+func Default() Identity {
+	return Identity{
+		ProductToken: DefaultProductToken,
+		Version:      DefaultVersion,
+		ContactURL:   DefaultContactURL,
+		Profile:      ProfileBot,
+	}
+}
+
+// Builds the identity from the defaults overridden by any environment variables that are set
+func FromEnvironment() (Identity, error) {
+	identity := Default()
+	if value, ok := os.LookupEnv(EnvProductToken); ok {
+		identity.ProductToken = strings.TrimSpace(value)
+	}
+	if value, ok := os.LookupEnv(EnvVersion); ok {
+		identity.Version = strings.TrimSpace(value)
+	}
+	if value, ok := os.LookupEnv(EnvContactURL); ok {
+		identity.ContactURL = strings.TrimSpace(value)
+	}
+	if value, ok := os.LookupEnv(EnvFrom); ok {
+		identity.From = strings.TrimSpace(value)
+	}
+	if value, ok := os.LookupEnv(EnvProfile); ok {
+		identity.Profile = Profile(strings.ToLower(strings.TrimSpace(value)))
+	}
+	if err := identity.Validate(); err != nil {
+		return Identity{}, err
+	}
+	return identity, nil
+}
+
+// Checks that the identity can be sent as-is
+func (identity Identity) Validate() error {
+	if !productTokenPattern.MatchString(identity.ProductToken) {
+		return fmt.Errorf("invalid product token %q: only letters, '_' and '-' are allowed", identity.ProductToken)
+	}
+	if strings.ContainsAny(identity.Version, " ;()") {
+		return fmt.Errorf("invalid version %q", identity.Version)
+	}
+	contactURL, err := url.Parse(identity.ContactURL)
+	if err != nil || (contactURL.Scheme != "http" && contactURL.Scheme != "https") || contactURL.Host == "" {
+		return fmt.Errorf("invalid contact URL %q: an absolute http(s) URL is required", identity.ContactURL)
+	}
+	if identity.From != "" {
+		address, err := mail.ParseAddress(identity.From)
+		if err != nil || address.Name != "" {
+			return fmt.Errorf("invalid From address %q: a bare email address is required", identity.From)
+		}
+	}
+	switch identity.Profile {
+	case ProfileBot, ProfileRotate:
+	default:
+		return fmt.Errorf("unknown user agent profile %q", identity.Profile)
+	}
+	return nil
+}
+
+// Sets the environment variables so that child processes inherit this identity
+func (identity Identity) Export() {
+	os.Setenv(EnvProductToken, identity.ProductToken)
+	os.Setenv(EnvVersion, identity.Version)
+	os.Setenv(EnvContactURL, identity.ContactURL)
+	os.Setenv(EnvFrom, identity.From)
+	os.Setenv(EnvProfile, string(identity.Profile))
+}
+
+// Returns the bot User-Agent, e.g. "Mozilla/5.0 (compatible; webcrawler/1.0; +https://example.com/bot)"
+func (identity Identity) UserAgent() string {
+	product := identity.ProductToken
+	if identity.Version != "" {
+		product += "/" + identity.Version
+	}
+	return fmt.Sprintf("Mozilla/5.0 (compatible; %s; +%s)", product, identity.ContactURL)
+}
@@ -0,0 +1,102 @@
+package identity
+
+import (
+	"os"
+	"testing"
+)
+
+// TestUserAgent tests the bot User-Agent includes the token, version and contact URL.
+func TestUserAgent(t *testing.T) {
+	identity := Default()
+	identity.ContactURL = "https://example.com/bot"
+	expected := "Mozilla/5.0 (compatible; webcrawler/1.0; +https://example.com/bot)"
+	if got := identity.UserAgent(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+// TestFromEnvironmentDefaults tests that nothing configured gives the bot profile.
+func TestFromEnvironmentDefaults(t *testing.T) {
+	for _, name := range []string{EnvProductToken, EnvVersion, EnvContactURL, EnvFrom, EnvProfile} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	identity, err := FromEnvironment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity != Default() {
+		t.Errorf("Expected default identity, got %+v", identity)
+	}
+	if identity.Profile != ProfileBot {
+		t.Errorf("Expected bot profile by default, got %q", identity.Profile)
+	}
+}
+
+// TestFromEnvironmentOverrides tests that environment variables override the defaults.
+func TestFromEnvironmentOverrides(t *testing.T) {
+	t.Setenv(EnvProductToken, "ExampleBot")
+	t.Setenv(EnvVersion, "2.3")
+	t.Setenv(EnvContactURL, "https://example.com/crawler")
+	t.Setenv(EnvFrom, "crawler@example.com")
+	t.Setenv(EnvProfile, "Rotate")
+
+	identity, err := FromEnvironment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Identity{
+		ProductToken: "ExampleBot",
+		Version:      "2.3",
+		ContactURL:   "https://example.com/crawler",
+		From:         "crawler@example.com",
+		Profile:      ProfileRotate,
+	}
+	if identity != expected {
+		t.Errorf("Expected %+v, got %+v", expected, identity)
+	}
+}
+
+// TestExportRoundTrip tests that an exported identity is read back unchanged.
+func TestExportRoundTrip(t *testing.T) {
+	for _, name := range []string{EnvProductToken, EnvVersion, EnvContactURL, EnvFrom, EnvProfile} {
+		t.Setenv(name, "")
+	}
+	identity := Identity{ProductToken: "my-bot", Version: "1", ContactURL: "http://example.org/", From: "ops@example.org", Profile: ProfileBot}
+	identity.Export()
+
+	loaded, err := FromEnvironment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != identity {
+		t.Errorf("Expected %+v, got %+v", identity, loaded)
+	}
+}
+
+// TestValidate tests that identities which cannot be sent are rejected.
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(identity *Identity)
+	}{
+		{"token with space", func(identity *Identity) { identity.ProductToken = "web crawler" }},
+		{"token with slash", func(identity *Identity) { identity.ProductToken = "webcrawler/1.0" }},
+		{"empty token", func(identity *Identity) { identity.ProductToken = "" }},
+		{"relative contact URL", func(identity *Identity) { identity.ContactURL = "/about" }},
+		{"missing contact URL", func(identity *Identity) { identity.ContactURL = "" }},
+		{"invalid From", func(identity *Identity) { identity.From = "not an email" }},
+		{"named From", func(identity *Identity) { identity.From = "Ops <ops@example.com>" }},
+		{"unknown profile", func(identity *Identity) { identity.Profile = "stealth" }},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			identity := Default()
+			tc.modify(&identity)
+			if err := identity.Validate(); err == nil {
+				t.Errorf("Expected %+v to be rejected", identity)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package language
+
+import (
+	"os"
+	"strings"
+)
+
+// Comma separated languages whose pages are kept, e.g. "en,fr", or "*" for
+// all. Defaults to English.
+const EnvLanguages = "WEBCRAWLER_LANGUAGES"
+
+// How much each declared language counts against the text's own
+const (
+	htmlLangWeight        = 0.6
+	contentLanguageWeight = 0.4
+	ogLocaleWeight        = 0.3
+	textWeight            = 2.0 // Times the identifier's confidence
+	priorWeight           = 0.5 // Uncertainty left when there is little to go on
+)
+
+// What a page says about its language, and what it contains
+type Signals struct {
+	Text            string // Visible text
+	HTMLLang        string // <html lang>
+	ContentLanguage string // Content-Language response header
+	OGLocale        string // og:locale meta property
+}
+
+// Combines the identified language of the text with the declared ones.
+// Declarations are often copied from templates, so long text outweighs
+// them, but they settle pages with little text.
+func Detect(signals Signals) Guess {
+	scores := make(map[string]float64)
+	total := priorWeight
+
+	if guess := Identify(signals.Text); guess.Language != "" {
+		scores[guess.Language] += textWeight * guess.Confidence
+		total += textWeight * guess.Confidence
+	}
+	for _, declared := range []struct {
+		tag    string
+		weight float64
+	}{
+		{signals.HTMLLang, htmlLangWeight},
+		{firstTag(signals.ContentLanguage), contentLanguageWeight},
+		{signals.OGLocale, ogLocaleWeight},
+	} {
+		if language := Normalize(declared.tag); language != "" {
+			scores[language] += declared.weight
+			total += declared.weight
+		}
+	}
+
+	var best Guess
+	for language, score := range scores {
+		if score > best.Confidence || score == best.Confidence && language < best.Language {
+			best = Guess{Language: language, Confidence: score}
+		}
+	}
+	best.Confidence /= total
+	return best
+}
+
+// Reduces a language tag such as "en-GB" or "pt_BR" to its primary language.
+// Tags that name no particular language give an empty string.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if index := strings.IndexAny(tag, "-_"); index >= 0 {
+		tag = tag[:index]
+	}
+	if len(tag) < 2 || len(tag) > 3 {
+		return ""
+	}
+	for _, r := range tag {
+		if r < 'a' || r > 'z' {
+			return ""
+		}
+	}
+	switch tag {
+	case "und", "mul", "zxx":
+		return ""
+	}
+	return tag
+}
+
+// Content-Language may list several languages, the first being the main one
+func firstTag(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return first
+}
+
+// Languages whose pages are kept. Nil keeps every language.
+type AllowList map[string]struct{}
+
+// Parses a comma separated list of languages, where "*" allows all
+func ParseAllowList(value string) AllowList {
+	list := make(AllowList)
+	for _, tag := range strings.Split(value, ",") {
+		if strings.TrimSpace(tag) == "*" {
+			return nil
+		}
+		if language := Normalize(tag); language != "" {
+			list[language] = struct{}{}
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
+
+// Reads the allow-list from the environment, keeping only English if unset
+func AllowListFromEnvironment() AllowList {
+	value, exists := os.LookupEnv(EnvLanguages)
+	if !exists || strings.TrimSpace(value) == "" {
+		value = "en"
+	}
+	return ParseAllowList(value)
+}
+
+// Reports whether a page declares an allowed language that the identifier
+// cannot recognise. Its text is then taken for a related language, such as
+// Norwegian for Danish, so the declaration is trusted instead.
+func (list AllowList) AllowsDeclared(signals Signals) bool {
+	for _, tag := range []string{signals.HTMLLang, firstTag(signals.ContentLanguage), signals.OGLocale} {
+		if language := Normalize(tag); language != "" && list.Allows(language) && !Identifiable(language) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether pages in a language are kept. Pages whose language is
+// unknown are given the benefit of the doubt.
+func (list AllowList) Allows(language string) bool {
+	if list == nil || language == "" {
+		return true
+	}
+	_, allowed := list[Normalize(language)]
+	return allowed
+}
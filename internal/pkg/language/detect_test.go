@@ -0,0 +1,99 @@
+package language
+
+import "testing"
+
+// Held-out texts: none of them appear in the sample texts of the profiles
+const (
+	englishText    = "Volunteers spent the whole weekend cleaning the beach, collecting plastic bottles, fishing nets and old tyres that the winter storms had washed ashore. The mayor thanked everyone who came and promised more bins along the promenade."
+	portugueseText = "Os voluntários passaram todo o fim de semana a limpar a praia e a recolher garrafas de plástico, redes de pesca e pneus velhos que as tempestades do inverno tinham trazido até à costa."
+	danishText     = "Frivillige brugte hele weekenden på at rydde stranden og samle plastikflasker, fiskenet og gamle dæk op, som vinterens storme havde skyllet i land."
+)
+
+// TestDetect tests how the text and the declared languages are weighed against each other.
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		signals  Signals
+		language string
+	}{
+		{"text alone", Signals{Text: englishText}, "en"},
+		{"mis-tagged page", Signals{Text: englishText, HTMLLang: "fr", OGLocale: "fr_FR"}, "en"},
+		{"Portuguese tagged as Spanish", Signals{Text: portugueseText, HTMLLang: "es"}, "pt"},
+		{"Danish tagged as Swedish", Signals{Text: danishText, HTMLLang: "sv"}, "da"},
+		{"little text", Signals{Text: "Bonjour", HTMLLang: "fr-CA"}, "fr"},
+		{"declarations agree", Signals{HTMLLang: "de", ContentLanguage: "en, de", OGLocale: "de_DE"}, "de"},
+		{"nothing to go on", Signals{Text: "OK", HTMLLang: "und"}, ""},
+	}
+	for _, tt := range tests {
+		guess := Detect(tt.signals)
+		if guess.Language != tt.language {
+			t.Errorf("%s: expected %q, got %+v", tt.name, tt.language, guess)
+		}
+		if guess.Confidence < 0 || guess.Confidence >= 1 {
+			t.Errorf("%s: expected a confidence between 0 and 1, got %v", tt.name, guess.Confidence)
+		}
+	}
+
+	// Texts in a language close to another are told apart with less certainty
+	if close, clear := Identify(portugueseText), Identify(englishText); close.Confidence >= clear.Confidence {
+		t.Errorf("Expected Portuguese, close to Spanish, to be less certain than English, got %+v and %+v", close, clear)
+	}
+
+	agreeing := Detect(Signals{Text: englishText, HTMLLang: "en"})
+	alone := Detect(Signals{Text: englishText})
+	if agreeing.Confidence <= alone.Confidence {
+		t.Errorf("Expected an agreeing declaration to raise confidence, got %v and %v", agreeing.Confidence, alone.Confidence)
+	}
+}
+
+// TestNormalize tests reducing language tags to their primary language.
+func TestNormalize(t *testing.T) {
+	for tag, expected := range map[string]string{
+		"en-GB":     "en",
+		" pt_BR ":   "pt",
+		"FIL":       "fil",
+		"x-default": "",
+		"und":       "",
+		"english":   "",
+		"":          "",
+	} {
+		if got := Normalize(tag); got != expected {
+			t.Errorf("Normalize(%q): expected %q, got %q", tag, expected, got)
+		}
+	}
+}
+
+// TestAllowList tests parsing and applying the allow-list.
+func TestAllowList(t *testing.T) {
+	list := ParseAllowList("en, fr-FR")
+	if !list.Allows("en") || !list.Allows("fr-CA") || list.Allows("de") {
+		t.Errorf("Unexpected allow-list %v", list)
+	}
+	if !list.Allows("") {
+		t.Error("Expected pages of unknown language to be allowed")
+	}
+	if all := ParseAllowList("*"); !all.Allows("de") {
+		t.Error("Expected * to allow every language")
+	}
+
+	// Norwegian has no profile, so a page declaring it is not judged by its text
+	norwegian := ParseAllowList("nb")
+	if !norwegian.AllowsDeclared(Signals{HTMLLang: "nb", ContentLanguage: "nb"}) {
+		t.Error("Expected a declared language without a profile to be allowed")
+	}
+	if norwegian.AllowsDeclared(Signals{HTMLLang: "da"}) || list.AllowsDeclared(Signals{HTMLLang: "en"}) {
+		t.Error("Expected only declared languages without a profile to be allowed")
+	}
+	if list.AllowsDeclared(Signals{HTMLLang: "nb"}) {
+		t.Error("Expected a declared language outside the allow-list not to be allowed")
+	}
+
+	t.Setenv(EnvLanguages, "")
+	if list := AllowListFromEnvironment(); !list.Allows("en") || list.Allows("fr") {
+		t.Errorf("Expected English only by default, got %v", list)
+	}
+	t.Setenv(EnvLanguages, "de,es")
+	if list := AllowListFromEnvironment(); list.Allows("en") || !list.Allows("es") {
+		t.Errorf("Expected the configured languages, got %v", list)
+	}
+}
@@ -0,0 +1,267 @@
+package language
+
+import (
+	"embed"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	profileSize    = 300  // Most frequent trigrams kept per language
+	maxSampleRunes = 4096 // Text beyond this adds little to the guess
+	minLetters     = 20   // Shorter text is not identified
+	fullLetters    = 200  // Text this long gets full weight
+	scriptLetters  = 50   // The same for languages told apart by their script alone
+)
+
+//go:embed profiles/*.txt
+var profileFiles embed.FS
+
+// The trigram ranks of a language, built from its sample text
+type profile struct {
+	language string
+	script   *unicode.RangeTable
+	ranks    map[string]int
+}
+
+var profiles = loadProfiles()
+
+// The language of a piece of text, as an ISO 639-1 code, and how sure the
+// identifier is of it from 0 to 1. An empty language means unknown.
+type Guess struct {
+	Language   string
+	Confidence float64
+}
+
+// Builds a trigram profile from each embedded sample text
+func loadProfiles() []profile {
+	entries, err := profileFiles.ReadDir("profiles")
+	if err != nil {
+		panic(err)
+	}
+	loaded := make([]profile, 0, len(entries))
+	for _, entry := range entries {
+		content, err := profileFiles.ReadFile(path.Join("profiles", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		text := string(content)
+		script, _ := dominantScript(text)
+		loaded = append(loaded, profile{
+			language: strings.TrimSuffix(entry.Name(), ".txt"),
+			script:   script,
+			ranks:    rankTrigrams(text),
+		})
+	}
+	return loaded
+}
+
+// Scripts that identify a language on their own, in the order they are checked
+var scriptLanguages = []struct {
+	script   *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// Reports whether the identifier can recognise a language from its text.
+// Others are only known from what pages declare.
+func Identifiable(language string) bool {
+	switch language {
+	case "ja", "zh":
+		return true
+	}
+	for _, candidate := range scriptLanguages {
+		if candidate.language == language {
+			return true
+		}
+	}
+	for _, candidate := range profiles {
+		if candidate.language == language {
+			return true
+		}
+	}
+	return false
+}
+
+// Identifies the language of a text. Languages with a script of their own
+// are recognised by it; languages written in Latin or Cyrillic are compared
+// with the trigram profiles using the out-of-place distance of Cavnar and
+// Trenkle. The confidence grows with the gap to the runner-up and with the
+// length of the text.
+func Identify(text string) Guess {
+	if runes := []rune(text); len(runes) > maxSampleRunes {
+		text = string(runes[:maxSampleRunes])
+	}
+	script, share := dominantScript(text)
+	letters := countLetters(text)
+	if script == nil || letters < minLetters {
+		return Guess{}
+	}
+
+	scriptFactor := math.Min(1, float64(letters) / scriptLetters)
+	switch script {
+	case unicode.Han, unicode.Hiragana, unicode.Katakana:
+		// Japanese mixes kana into its Chinese characters
+		if countScript(text, unicode.Hiragana) + countScript(text, unicode.Katakana) > letters / 10 {
+			return Guess{Language: "ja", Confidence: share * scriptFactor}
+		}
+		return Guess{Language: "zh", Confidence: share * scriptFactor}
+	}
+	for _, candidate := range scriptLanguages {
+		if script == candidate.script {
+			return Guess{Language: candidate.language, Confidence: share * scriptFactor}
+		}
+	}
+
+	ranks := rankTrigrams(text)
+	best, bestDistance, secondDistance := "", math.MaxFloat64, math.MaxFloat64
+	for _, candidate := range profiles {
+		if candidate.script != script {
+			continue
+		}
+		distance := outOfPlace(ranks, candidate.ranks)
+		if distance < bestDistance {
+			best, bestDistance, secondDistance = candidate.language, distance, bestDistance
+		} else if distance < secondDistance {
+			secondDistance = distance
+		}
+	}
+	if best == "" {
+		return Guess{}
+	}
+	gap := 1.0
+	if secondDistance < math.MaxFloat64 && secondDistance > 0 {
+		gap = math.Min(1, (secondDistance - bestDistance) / secondDistance * 10)
+	}
+	return Guess{Language: best, Confidence: gap * share * math.Min(1, float64(letters) / fullLetters)}
+}
+
+// Ranks the most frequent trigrams of a text, padding each word with spaces
+// so that word starts and ends count
+func rankTrigrams(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i + 3 <= len(runes); i++ {
+			counts[string(runes[i:i + 3])]++
+		}
+	}
+
+	trigrams := make([]string, 0, len(counts))
+	for trigram := range counts {
+		trigrams = append(trigrams, trigram)
+	}
+	sort.Slice(trigrams, func(i, j int) bool {
+		if counts[trigrams[i]] != counts[trigrams[j]] {
+			return counts[trigrams[i]] > counts[trigrams[j]]
+		}
+		return trigrams[i] < trigrams[j]
+	})
+	if len(trigrams) > profileSize {
+		trigrams = trigrams[:profileSize]
+	}
+	ranks := make(map[string]int, len(trigrams))
+	for rank, trigram := range trigrams {
+		ranks[trigram] = rank
+	}
+	return ranks
+}
+
+// Sums how far each of the text's trigrams is from its rank in the profile,
+// charging the maximum for trigrams the profile lacks. The result is
+// averaged so texts of different lengths compare.
+func outOfPlace(text, profile map[string]int) float64 {
+	if len(text) == 0 {
+		return math.MaxFloat64
+	}
+	total := 0
+	for trigram, rank := range text {
+		if profileRank, exists := profile[trigram]; exists {
+			total += abs(rank - profileRank)
+		} else {
+			total += profileSize
+		}
+	}
+	return float64(total) / float64(len(text))
+}
+
+// Finds the script most of the text's letters are written in, and the share
+// of letters written in it
+func dominantScript(text string) (*unicode.RangeTable, float64) {
+	scripts := []*unicode.RangeTable{unicode.Latin, unicode.Cyrillic, unicode.Han, unicode.Hiragana, unicode.Katakana}
+	for _, candidate := range scriptLanguages {
+		scripts = append(scripts, candidate.script)
+	}
+
+	counts := make([]int, len(scripts))
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for i, script := range scripts {
+			if unicode.Is(script, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return nil, 0
+	}
+
+	best := 0
+	for i := range counts {
+		if counts[i] > counts[best] {
+			best = i
+		}
+	}
+	if counts[best] == 0 {
+		return nil, 0
+	}
+
+	// Chinese characters and kana together make up Japanese text
+	share := counts[best]
+	switch scripts[best] {
+	case unicode.Han, unicode.Hiragana, unicode.Katakana:
+		share = counts[2] + counts[3] + counts[4]
+	}
+	return scripts[best], float64(share) / float64(letters)
+}
+
+func countLetters(text string) int {
+	count := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			count++
+		}
+	}
+	return count
+}
+
+func countScript(text string, script *unicode.RangeTable) int {
+	count := 0
+	for _, r := range text {
+		if unicode.Is(script, r) {
+			count++
+		}
+	}
+	return count
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,66 @@
+package language
+
+import "testing"
+
+// TestIdentify tests sentences that are not part of the sample texts.
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		language string
+		text     string
+	}{
+		{"en", "The quick brown fox jumps over the lazy dog while the children play in the garden near the house."},
+		{"fr", "Le chat est assis sur le tapis pendant que les enfants jouent dans le jardin près de la maison."},
+		{"de", "Die Katze sitzt auf der Matte, während die Kinder im Garten neben dem Haus spielen."},
+		{"es", "El gato está sentado en la alfombra mientras los niños juegan en el jardín cerca de la casa."},
+		{"it", "Il gatto è seduto sul tappeto mentre i bambini giocano nel giardino vicino alla casa."},
+		{"pt", "O gato está sentado no tapete enquanto as crianças brincam no jardim perto da casa."},
+		{"nl", "De kat zit op de mat terwijl de kinderen in de tuin bij het huis spelen."},
+		{"sv", "Katten sitter på mattan medan barnen leker i trädgården nära huset."},
+		{"pl", "Kot siedzi na dywanie, podczas gdy dzieci bawią się w ogrodzie obok domu."},
+		{"tr", "Kedi halının üzerinde oturuyor, çocuklar ise evin yanındaki bahçede oynuyor."},
+		{"fi", "Kissa istuu matolla, kun lapset leikkivät puutarhassa talon vieressä."},
+		{"ru", "Кошка сидит на ковре, пока дети играют в саду возле дома."},
+		{"uk", "Кішка сидить на килимку, поки діти граються в саду біля будинку."},
+		{"ja", "猫はマットの上に座っていて、子供たちは家の近くの庭で遊んでいます。"},
+		{"zh", "猫坐在垫子上，孩子们在房子附近的花园里玩耍。这是一个美好的下午。"},
+		{"ko", "고양이가 매트 위에 앉아 있고 아이들은 집 근처 정원에서 놀고 있습니다."},
+	}
+	for _, tt := range tests {
+		guess := Identify(tt.text)
+		if guess.Language != tt.language {
+			t.Errorf("Expected %s, got %+v for %q", tt.language, guess, tt.text)
+		}
+		if guess.Confidence <= 0 || guess.Confidence > 1 {
+			t.Errorf("Expected a confidence between 0 and 1, got %v for %q", guess.Confidence, tt.text)
+		}
+	}
+}
+
+// TestIdentifyConfidence tests that longer text is identified with more confidence and short text not at all.
+func TestIdentifyConfidence(t *testing.T) {
+	short := Identify("Hello Fetch")
+	if short.Language != "" {
+		t.Errorf("Expected short text not to be identified, got %+v", short)
+	}
+
+	sentence := "The library will stay open late on Thursdays so that students can prepare for their exams. "
+	one := Identify(sentence)
+	four := Identify(sentence + sentence + sentence + sentence)
+	if one.Language != "en" || four.Language != "en" || four.Confidence <= one.Confidence {
+		t.Errorf("Expected confidence to grow with length, got %+v then %+v", one, four)
+	}
+}
+
+// TestIdentifiable tests which languages the identifier can recognise.
+func TestIdentifiable(t *testing.T) {
+	for _, language := range []string{"en", "da", "uk", "ko", "ja", "zh"} {
+		if !Identifiable(language) {
+			t.Errorf("Expected %s to be identifiable", language)
+		}
+	}
+	for _, language := range []string{"nb", "ca", "gl", "sk", "hr", ""} {
+		if Identifiable(language) {
+			t.Errorf("Expected %q not to be identifiable", language)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package linkgraph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A link graph read back for ranking. Nodes are numbered from zero, and a
+// node's links hold the numbers of the nodes it links to, sorted and without
+// repeats.
+type Graph struct {
+	Names []string // The URL, or host, of each node
+	Links [][]uint32
+}
+
+// Reads the link graph in a directory. Only the latest record of each page is
+// kept. Reading stops at a record cut short by a crash or naming URLs written
+// after the URLs file was read, as happens while the crawler is running.
+func Load(dir string) (*Graph, error) {
+	urls, err := readURLs(filepath.Join(dir, urlsFile), false)
+	if err != nil {
+		return nil, err
+	}
+	graph := &Graph{Names: urls, Links: make([][]uint32, len(urls))}
+
+	file, err := os.Open(filepath.Join(dir, edgesFile))
+	if os.IsNotExist(err) {
+		return graph, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening link graph edges: %v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return graph, nil // Nothing was recorded yet
+	}
+	if string(header) != string(magic) {
+		return nil, fmt.Errorf("%s is not a link graph edges file", file.Name())
+	}
+	for {
+		source, targets, err := decodeRecord(reader, len(urls))
+		if err != nil {
+			return graph, nil
+		}
+		graph.Links[source] = targets
+	}
+}
+
+// Returns the number of links in the graph
+func (graph *Graph) Edges() int {
+	edges := 0
+	for _, targets := range graph.Links {
+		edges += len(targets)
+	}
+	return edges
+}
+
+// Collapses the graph into a graph of hosts, in which a host links to
+// another when any of its pages links to one of the other's. Links within a
+// host are left out. Hosts are lower cased and lose any leading "www.".
+func (graph *Graph) Hosts() *Graph {
+	hosts := &Graph{}
+	hostIDs := make(map[string]uint32)
+	nodeHosts := make([]uint32, len(graph.Names))
+	for node, name := range graph.Names {
+		host := hostOf(name)
+		id, known := hostIDs[host]
+		if !known {
+			id = uint32(len(hosts.Names))
+			hostIDs[host] = id
+			hosts.Names = append(hosts.Names, host)
+		}
+		nodeHosts[node] = id
+	}
+
+	linked := make([]map[uint32]struct{}, len(hosts.Names))
+	for node, targets := range graph.Links {
+		from := nodeHosts[node]
+		for _, target := range targets {
+			to := nodeHosts[target]
+			if to == from {
+				continue
+			}
+			if linked[from] == nil {
+				linked[from] = make(map[uint32]struct{})
+			}
+			linked[from][to] = struct{}{}
+		}
+	}
+	hosts.Links = make([][]uint32, len(hosts.Names))
+	for from, targets := range linked {
+		for to := range targets {
+			hosts.Links[from] = append(hosts.Links[from], to)
+		}
+		sort.Slice(hosts.Links[from], func(i, j int) bool { return hosts.Links[from][i] < hosts.Links[from][j] })
+	}
+	return hosts
+}
+
+// Returns the host of a URL the way the host graph names it
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
@@ -0,0 +1,18 @@
+package linkgraph
+
+import "testing"
+
+// TestHosts tests that pages are collapsed into hosts without links within a host.
+func TestHosts(t *testing.T) {
+	graph := &Graph{
+		Names: []string{"https://www.a.example/", "https://a.example/about", "https://b.example/", "https://B.example/x"},
+		Links: [][]uint32{{1, 2}, {3}, {3}, {0}},
+	}
+	hosts := graph.Hosts()
+	if len(hosts.Names) != 2 || hosts.Names[0] != "a.example" || hosts.Names[1] != "b.example" {
+		t.Fatalf("Unexpected hosts %v", hosts.Names)
+	}
+	if len(hosts.Links[0]) != 1 || hosts.Links[0][0] != 1 || len(hosts.Links[1]) != 1 || hosts.Links[1][0] != 0 {
+		t.Errorf("Unexpected host links %v", hosts.Links)
+	}
+}
@@ -0,0 +1,64 @@
+package linkgraph
+
+import "math"
+
+// Settings of the PageRank computation
+type Options struct {
+	Damping       float64 // Chance of following a link rather than jumping to a random node
+	MaxIterations int
+	Tolerance     float64 // Stops once the scores move less than this in total
+}
+
+// Returns the usual PageRank settings
+func DefaultOptions() Options {
+	return Options{Damping: 0.85, MaxIterations: 100, Tolerance: 1e-9}
+}
+
+// Computes the PageRank of every node by power iteration. Nodes without
+// links spread their score evenly over the graph. The scores sum to one.
+// Returns the scores and the number of iterations run.
+func Rank(graph *Graph, options Options) ([]float64, int) {
+	nodes := len(graph.Names)
+	if nodes == 0 {
+		return nil, 0
+	}
+	scores := make([]float64, nodes)
+	next := make([]float64, nodes)
+	for node := range scores {
+		scores[node] = 1 / float64(nodes)
+	}
+
+	iterations := 0
+	for iterations < options.MaxIterations {
+		iterations++
+		dangling := 0.0
+		for node, targets := range graph.Links {
+			if len(targets) == 0 {
+				dangling += scores[node]
+			}
+		}
+		base := (1 - options.Damping + options.Damping * dangling) / float64(nodes)
+		for node := range next {
+			next[node] = base
+		}
+		for node, targets := range graph.Links {
+			if len(targets) == 0 {
+				continue
+			}
+			share := options.Damping * scores[node] / float64(len(targets))
+			for _, target := range targets {
+				next[target] += share
+			}
+		}
+
+		change := 0.0
+		for node := range scores {
+			change += math.Abs(next[node] - scores[node])
+		}
+		scores, next = next, scores
+		if change < options.Tolerance {
+			break
+		}
+	}
+	return scores, iterations
+}
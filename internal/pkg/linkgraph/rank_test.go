@@ -0,0 +1,41 @@
+package linkgraph
+
+import (
+	"math"
+	"testing"
+)
+
+// TestRank tests PageRank against a small graph with a dangling node.
+func TestRank(t *testing.T) {
+	// a links to b and c, b to c, c to a, and d links nowhere
+	graph := &Graph{
+		Names: []string{"a", "b", "c", "d"},
+		Links: [][]uint32{{1, 2}, {2}, {0}, nil},
+	}
+	scores, iterations := Rank(graph, DefaultOptions())
+	if iterations >= DefaultOptions().MaxIterations {
+		t.Errorf("Expected the scores to converge, ran %d iterations", iterations)
+	}
+	total := 0.0
+	for _, score := range scores {
+		total += score
+	}
+	if math.Abs(total - 1) > 1e-9 {
+		t.Errorf("Expected the scores to sum to one, got %v", total)
+	}
+	if !(scores[2] > scores[0] && scores[0] > scores[1] && scores[1] > scores[3]) {
+		t.Errorf("Unexpected order of scores %v", scores)
+	}
+	// Nothing links to d, so it only gets the random jumps and its own share
+	expected := (1 - 0.85 + 0.85 * scores[3]) / 4
+	if math.Abs(scores[3] - expected) > 1e-6 {
+		t.Errorf("Expected d to score %v, got %v", expected, scores[3])
+	}
+}
+
+// TestRankEmpty tests that an empty graph has no scores.
+func TestRankEmpty(t *testing.T) {
+	if scores, _ := Rank(&Graph{}, DefaultOptions()); scores != nil {
+		t.Errorf("Expected no scores, got %v", scores)
+	}
+}
@@ -0,0 +1,124 @@
+package linkgraph
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"webcrawler/internal/pkg/utils"
+)
+
+// Writes scores as tab separated lines of score and URL or host, highest
+// first. Scores are scaled so that the average node scores one, which keeps
+// them readable whatever the size of the graph.
+func WriteScores(path string, names []string, scores []float64) error {
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+
+	// Write to a temporary file first so a crawler starting meanwhile, or an
+	// interrupted run, never leaves it reading a partial file
+	tempPath := path + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("error creating scores file: %v", err)
+	}
+	writer := bufio.NewWriter(file)
+	scale := float64(len(names))
+	for _, node := range order {
+		fmt.Fprintf(writer, "%s\t%s\n", strconv.FormatFloat(scores[node] * scale, 'g', 6, 64), names[node])
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("error writing scores file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("error writing scores file: %v", err)
+	}
+	return os.Rename(tempPath, path)
+}
+
+// Reads a scores file written by WriteScores
+func ReadScores(path string) (map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scores := make(map[string]float64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, name, found := strings.Cut(scanner.Text(), "\t")
+		if !found || name == "" {
+			continue
+		}
+		if score, err := strconv.ParseFloat(value, 64); err == nil {
+			scores[name] = score
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading scores file: %v", err)
+	}
+	return scores, nil
+}
+
+// Page and host scores, used to decide which URLs the crawler fetches first
+type Ranks struct {
+	pages map[string]float64
+	hosts map[string]float64
+}
+
+// Loads the page and host scores. A missing file leaves its scores empty, so
+// the crawler can start before any ranking has been run.
+func LoadRanks(pagesPath, hostsPath string) (*Ranks, error) {
+	ranks := &Ranks{}
+	for _, file := range []struct {
+		path   string
+		scores *map[string]float64
+	}{{pagesPath, &ranks.pages}, {hostsPath, &ranks.hosts}} {
+		if file.path == "" {
+			continue
+		}
+		scores, err := ReadScores(file.path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		*file.scores = scores
+	}
+	return ranks, nil
+}
+
+// Returns how urgently a URL should be fetched: its page score if it has
+// been ranked, else the score of its host, else zero. One is average. URLs
+// without a scheme, such as bare domains, are taken to be HTTPS.
+func (ranks *Ranks) Priority(rawURL string) float64 {
+	if ranks == nil {
+		return 0
+	}
+	fullURL, err := utils.BuildFullUrl(rawURL)
+	if err != nil {
+		return 0
+	}
+	if score, ranked := ranks.pages[normalize(fullURL)]; ranked {
+		return score
+	}
+	return ranks.hosts[hostOf(fullURL)]
+}
+
+// Returns how many pages and hosts have scores
+func (ranks *Ranks) Len() (int, int) {
+	if ranks == nil {
+		return 0, 0
+	}
+	return len(ranks.pages), len(ranks.hosts)
+}
@@ -0,0 +1,63 @@
+package linkgraph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestWriteScores tests that scores are written highest first, scaled to an average of one.
+func TestWriteScores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scores.tsv")
+	if err := WriteScores(path, []string{"low", "high"}, []float64{0.25, 0.75}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "1.5\thigh\n0.5\tlow\n" {
+		t.Errorf("Unexpected scores file %q", data)
+	}
+	scores, err := ReadScores(path)
+	if err != nil || scores["high"] != 1.5 || scores["low"] != 0.5 {
+		t.Errorf("Unexpected scores %v (%v)", scores, err)
+	}
+
+	// Rewriting replaces the file whole, leaving no temporary file behind
+	if err := WriteScores(path, []string{"only"}, []float64{1}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "1\tonly\n" {
+		t.Errorf("Unexpected rewritten scores file %q", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected only the scores file, got %d files", len(entries))
+	}
+}
+
+// TestPriority tests that page scores are preferred to host scores.
+func TestPriority(t *testing.T) {
+	dir := t.TempDir()
+	pages, hosts := filepath.Join(dir, "pages.tsv"), filepath.Join(dir, "hosts.tsv")
+	os.WriteFile(pages, []byte(strings.Join([]string{"3\thttps://a.example/top", "0.2\thttps://a.example/"}, "\n")), 0644)
+	os.WriteFile(hosts, []byte("2\ta.example\n"), 0644)
+
+	ranks, err := LoadRanks(pages, hosts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for rawURL, expected := range map[string]float64{
+		"https://a.example/top#more": 3,
+		"https://www.a.example/new":  2,
+		"a.example":                  2,
+		"https://b.example/":         0,
+	} {
+		if priority := ranks.Priority(rawURL); priority != expected {
+			t.Errorf("Expected %s to have priority %v, got %v", rawURL, expected, priority)
+		}
+	}
+
+	ranks, err = LoadRanks(filepath.Join(dir, "missing.tsv"), "")
+	if pageCount, hostCount := ranks.Len(); err != nil || pageCount != 0 || hostCount != 0 {
+		t.Errorf("Expected missing files to give no scores, got %d and %d (%v)", pageCount, hostCount, err)
+	}
+}
@@ -0,0 +1,283 @@
+package linkgraph
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Files of a link graph directory
+const (
+	urlsFile  = "urls.txt"  // One URL per line, its line number being its node ID
+	edgesFile = "edges.bin" // Adjacency records, see Store
+)
+
+// Written at the start of the edges file
+var magic = []byte("LKG1")
+
+// Records the links between crawled pages as they are extracted. Every URL is
+// given a node ID in the order it is first seen and appended to urls.txt.
+// Each crawled page appends one record to edges.bin: its node ID, the number
+// of pages it links to, then their IDs sorted and stored as differences from
+// the previous ID, all as unsigned varints. A page crawled again appends a new
+// record, which replaces the old one when the graph is loaded.
+type Store struct {
+	maxNodes int
+	mutex    sync.Mutex
+	ids      map[string]uint32
+	urls     *os.File
+	edges    *os.File
+}
+
+// Opens the link graph in a directory, creating it if needed. Once maxNodes
+// URLs are known, links to new URLs are no longer recorded; zero means no
+// limit. A record cut short by a crash is discarded.
+func Open(dir string, maxNodes int) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating link graph directory: %v", err)
+	}
+	store := &Store{maxNodes: maxNodes, ids: make(map[string]uint32)}
+
+	urls, err := readURLs(filepath.Join(dir, urlsFile), true)
+	if err != nil {
+		return nil, err
+	}
+	for id, rawURL := range urls {
+		store.ids[rawURL] = uint32(id)
+	}
+	if err := repairEdges(filepath.Join(dir, edgesFile), len(urls)); err != nil {
+		return nil, err
+	}
+
+	if store.urls, err = os.OpenFile(filepath.Join(dir, urlsFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+		return nil, fmt.Errorf("error opening link graph URLs: %v", err)
+	}
+	if store.edges, err = os.OpenFile(filepath.Join(dir, edgesFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+		store.urls.Close()
+		return nil, fmt.Errorf("error opening link graph edges: %v", err)
+	}
+	return store, nil
+}
+
+// Records the pages a page links to, replacing what was recorded for it
+// before. Links to the page itself are left out.
+func (store *Store) AddPage(source string, targets []string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var newURLs strings.Builder
+	sourceID, ok := store.intern(source, &newURLs)
+	if !ok {
+		return nil
+	}
+	seen := make(map[uint32]struct{}, len(targets))
+	ids := make([]uint32, 0, len(targets))
+	for _, target := range targets {
+		id, ok := store.intern(target, &newURLs)
+		if _, duplicate := seen[id]; !ok || duplicate || id == sourceID {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// URLs go out first so a record never names an ID the URLs file lacks
+	if newURLs.Len() > 0 {
+		if _, err := store.urls.WriteString(newURLs.String()); err != nil {
+			return fmt.Errorf("error writing link graph URLs: %v", err)
+		}
+	}
+	if _, err := store.edges.Write(encodeRecord(sourceID, ids)); err != nil {
+		return fmt.Errorf("error writing link graph edges: %v", err)
+	}
+	return nil
+}
+
+// Returns how many URLs the graph knows
+func (store *Store) Nodes() int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return len(store.ids)
+}
+
+// Closes the graph's files
+func (store *Store) Close() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return errors.Join(store.urls.Close(), store.edges.Close())
+}
+
+// Returns the node ID of a URL, giving it one if it is new and the graph has
+// room. New URLs are added to pending for writing out. Must be called with
+// the mutex held.
+func (store *Store) intern(rawURL string, pending *strings.Builder) (uint32, bool) {
+	rawURL = normalize(rawURL)
+	if rawURL == "" {
+		return 0, false
+	}
+	if id, known := store.ids[rawURL]; known {
+		return id, true
+	}
+	if store.maxNodes > 0 && len(store.ids) >= store.maxNodes {
+		return 0, false
+	}
+	id := uint32(len(store.ids))
+	store.ids[rawURL] = id
+	pending.WriteString(rawURL)
+	pending.WriteByte('\n')
+	return id, true
+}
+
+// Encodes a page's record: its ID, the number of links, then the gaps
+// between the sorted target IDs
+func encodeRecord(source uint32, targets []uint32) []byte {
+	record := binary.AppendUvarint(nil, uint64(source))
+	record = binary.AppendUvarint(record, uint64(len(targets)))
+	previous := uint32(0)
+	for _, target := range targets {
+		record = binary.AppendUvarint(record, uint64(target - previous))
+		previous = target
+	}
+	return record
+}
+
+// Reads one record, checking its IDs against the number of known URLs
+func decodeRecord(reader *bufio.Reader, nodes int) (uint32, []uint32, error) {
+	source, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	count, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return 0, nil, unexpected(err)
+	}
+	if source >= uint64(nodes) || count > uint64(nodes) {
+		return 0, nil, fmt.Errorf("invalid link graph record for node %d", source)
+	}
+	targets := make([]uint32, count)
+	previous := uint64(0)
+	for i := range targets {
+		gap, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return 0, nil, unexpected(err)
+		}
+		previous += gap
+		if previous >= uint64(nodes) {
+			return 0, nil, fmt.Errorf("invalid link graph record for node %d", source)
+		}
+		targets[i] = uint32(previous)
+	}
+	return uint32(source), targets, nil
+}
+
+// Reports an end of file inside a record as a truncated record
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Reads the URLs file. When repair is set, a last line without its newline,
+// left by a crash, is cut off the file.
+func readURLs(path string, repair bool) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading link graph URLs: %v", err)
+	}
+	complete := len(data)
+	if complete > 0 && data[complete - 1] != '\n' {
+		complete = strings.LastIndexByte(string(data), '\n') + 1
+		if repair {
+			if err := os.Truncate(path, int64(complete)); err != nil {
+				return nil, fmt.Errorf("error repairing link graph URLs: %v", err)
+			}
+		}
+	}
+	if complete == 0 {
+		return nil, nil
+	}
+	return strings.Split(string(data[:complete - 1]), "\n"), nil
+}
+
+// Checks the edges file, writing its header if it is new and cutting off a
+// record left incomplete by a crash
+func repairEdges(path string, nodes int) error {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening link graph edges: %v", err)
+	}
+	defer file.Close()
+
+	header := make([]byte, len(magic))
+	if n, err := io.ReadFull(file, header); err != nil {
+		if n == 0 || err == io.ErrUnexpectedEOF {
+			// New, or cut short before its header was written
+			if err := file.Truncate(0); err != nil {
+				return fmt.Errorf("error repairing link graph edges: %v", err)
+			}
+			_, err := file.WriteAt(magic, 0)
+			return err
+		}
+		return fmt.Errorf("error reading link graph edges: %v", err)
+	}
+	if string(header) != string(magic) {
+		return fmt.Errorf("%s is not a link graph edges file", path)
+	}
+
+	counter := &countingReader{reader: file, read: int64(len(magic))}
+	records := bufio.NewReader(counter)
+	valid := counter.read
+	for {
+		if _, _, err := decodeRecord(records, nodes); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			// Nothing after a bad record can be read, so it and the rest go
+			if err := file.Truncate(valid); err != nil {
+				return fmt.Errorf("error repairing link graph edges: %v", err)
+			}
+			return nil
+		}
+		valid = counter.read - int64(records.Buffered())
+	}
+}
+
+// Counts the bytes read through it, so record boundaries can be found
+// beneath a buffered reader
+type countingReader struct {
+	reader io.Reader
+	read   int64
+}
+
+func (counter *countingReader) Read(buffer []byte) (int, error) {
+	n, err := counter.reader.Read(buffer)
+	counter.read += int64(n)
+	return n, err
+}
+
+// Puts a URL in the form it is stored in, without a fragment. URLs that
+// would break the line based URLs file are refused.
+func normalize(rawURL string) string {
+	if rawURL == "" || strings.ContainsAny(rawURL, "\r\n") {
+		return ""
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	parsed.Fragment = ""
+	return parsed.String()
+}
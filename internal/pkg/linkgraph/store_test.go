@@ -0,0 +1,108 @@
+package linkgraph
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestStoreRoundTrip tests that pages and their links are read back, the latest record of a page winning.
+func TestStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := store.AddPage("https://a.example/", []string{"https://b.example/x", "https://a.example/#top", "https://b.example/x", "https://c.example/"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store.AddPage("https://b.example/x", []string{"https://a.example/"})
+	store.AddPage("https://b.example/x", []string{"https://c.example/"})
+	if store.Nodes() != 3 {
+		t.Errorf("Expected 3 nodes, got %d", store.Nodes())
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Reopening keeps the IDs already given out
+	store, err = Open(dir, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store.AddPage("https://c.example/", []string{"https://d.example/", "https://a.example/"})
+	store.Close()
+
+	graph, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string][]string{
+		"https://a.example/":  {"https://b.example/x", "https://c.example/"},
+		"https://b.example/x": {"https://c.example/"},
+		"https://c.example/":  {"https://a.example/", "https://d.example/"},
+		"https://d.example/":  nil,
+	}
+	if len(graph.Names) != len(expected) || graph.Edges() != 5 {
+		t.Fatalf("Unexpected graph %v %v", graph.Names, graph.Links)
+	}
+	for node, name := range graph.Names {
+		var links []string
+		for _, target := range graph.Links[node] {
+			links = append(links, graph.Names[target])
+		}
+		if len(links) != len(expected[name]) {
+			t.Errorf("Expected %s to link to %v, got %v", name, expected[name], links)
+			continue
+		}
+		for i := range links {
+			if links[i] != expected[name][i] {
+				t.Errorf("Expected %s to link to %v, got %v", name, expected[name], links)
+			}
+		}
+	}
+}
+
+// TestStoreRepair tests that records and URLs cut short by a crash are discarded on open.
+func TestStoreRepair(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := Open(dir, 0)
+	store.AddPage("https://a.example/", []string{"https://b.example/"})
+	store.Close()
+
+	edges, _ := os.OpenFile(filepath.Join(dir, edgesFile), os.O_APPEND|os.O_WRONLY, 0644)
+	edges.Write(encodeRecord(1, []uint32{0, 300})[:3])
+	edges.Close()
+	urls, _ := os.OpenFile(filepath.Join(dir, urlsFile), os.O_APPEND|os.O_WRONLY, 0644)
+	urls.WriteString("https://half")
+	urls.Close()
+
+	store, err := Open(dir, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store.AddPage("https://b.example/", []string{"https://c.example/"})
+	store.Close()
+
+	graph, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(graph.Names) != 3 || graph.Names[2] != "https://c.example/" || graph.Edges() != 2 {
+		t.Errorf("Expected the broken tail to be dropped, got %v %v", graph.Names, graph.Links)
+	}
+}
+
+// TestStoreMaxNodes tests that links to new URLs are dropped once the graph is full.
+func TestStoreMaxNodes(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := Open(dir, 2)
+	store.AddPage("https://a.example/", []string{"https://b.example/", "https://c.example/"})
+	store.AddPage("https://c.example/", []string{"https://a.example/"})
+	store.Close()
+
+	graph, _ := Load(dir)
+	if len(graph.Names) != 2 || graph.Edges() != 1 {
+		t.Errorf("Unexpected graph %v %v", graph.Names, graph.Links)
+	}
+}
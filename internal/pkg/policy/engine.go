@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Path of the JSON policy file. The built-in policy is used when it is unset.
+const EnvPolicy = "WEBCRAWLER_POLICY"
+
+// How often the policy file is checked for changes
+const reloadInterval = 5 * time.Second
+
+// Evaluates pages against a policy file, reloading it when it changes. A file
+// that no longer parses is reported and the previous policy kept.
+type Engine struct {
+	path     string
+	interval time.Duration
+	mutex    sync.Mutex
+	policy   *Policy
+	modified time.Time // Modification time of the loaded file
+	checked  time.Time
+}
+
+// Creates an engine for the built-in policy
+func DefaultEngine() *Engine {
+	return &Engine{policy: Default()}
+}
+
+// Creates an engine for a policy file, or for the built-in policy if the
+// path is empty
+func NewEngine(path string) (*Engine, error) {
+	if path == "" {
+		return DefaultEngine(), nil
+	}
+	engine := &Engine{path: path, interval: reloadInterval}
+	if err := engine.load(); err != nil {
+		return nil, err
+	}
+	engine.checked = time.Now()
+	return engine, nil
+}
+
+// Creates an engine for the policy file named by the environment
+func FromEnvironment() (*Engine, error) {
+	return NewEngine(os.Getenv(EnvPolicy))
+}
+
+// Evaluates the current policy against a page
+func (engine *Engine) Evaluate(page Page) Decision {
+	return engine.current().Evaluate(page)
+}
+
+// Returns the policy, reloading the file first if it has changed since it
+// was last checked
+func (engine *Engine) current() *Policy {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+	if engine.path == "" || time.Since(engine.checked) < engine.interval {
+		return engine.policy
+	}
+	engine.checked = time.Now()
+
+	info, err := os.Stat(engine.path)
+	if err != nil {
+		log.Printf("Warning: cannot check content policy %s, keeping the loaded one: %v", engine.path, err)
+		return engine.policy
+	}
+	if !info.ModTime().Equal(engine.modified) {
+		engine.modified = info.ModTime() // A broken file is not retried until it changes again
+		if err := engine.load(); err != nil {
+			log.Printf("Warning: keeping the loaded content policy: %v", err)
+		} else {
+			log.Printf("Reloaded content policy from %s", engine.path)
+		}
+	}
+	return engine.policy
+}
+
+// Reads and compiles the policy file
+func (engine *Engine) load() error {
+	info, err := os.Stat(engine.path)
+	if err != nil {
+		return fmt.Errorf("error reading policy file: %v", err)
+	}
+	data, err := os.ReadFile(engine.path)
+	if err != nil {
+		return fmt.Errorf("error reading policy file: %v", err)
+	}
+	policy, err := Parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", engine.path, err)
+	}
+	engine.policy, engine.modified = policy, info.ModTime()
+	return nil
+}
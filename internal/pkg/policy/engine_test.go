@@ -0,0 +1,56 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestEngineReload tests that a changed policy file is picked up and a broken one ignored.
+func TestEngineReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	write := func(content string, modified time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write(`{"rules": [{"name": "first", "fields": ["title"], "words": ["alpha"], "action": "drop"}]}`, start)
+
+	engine, err := NewEngine(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	engine.interval = 0
+	if decision := engine.Evaluate(Page{Title: "alpha"}); decision.Rule != "first" {
+		t.Errorf("Expected the first policy, got %+v", decision)
+	}
+
+	write(`{"rules": [{"name": "second", "fields": ["title"], "words": ["beta"], "action": "drop"}]}`, start.Add(time.Minute))
+	if decision := engine.Evaluate(Page{Title: "beta"}); decision.Rule != "second" {
+		t.Errorf("Expected the reloaded policy, got %+v", decision)
+	}
+
+	write(`{"rules": [`, start.Add(2 * time.Minute))
+	if decision := engine.Evaluate(Page{Title: "beta"}); decision.Rule != "second" {
+		t.Errorf("Expected a broken file to leave the policy as it was, got %+v", decision)
+	}
+}
+
+// TestNewEngineDefault tests that no path gives the built-in policy and a missing file an error.
+func TestNewEngineDefault(t *testing.T) {
+	engine, err := NewEngine("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decision := engine.Evaluate(Page{Title: "porn"}); !decision.Drop {
+		t.Errorf("Expected the built-in policy, got %+v", decision)
+	}
+	if _, err := NewEngine(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing policy file")
+	}
+}
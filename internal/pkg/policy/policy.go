@@ -0,0 +1,282 @@
+package policy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// What a rule does to the pages it matches
+const (
+	ActionDrop     = "drop"     // The page is rejected and not written out
+	ActionTag      = "tag"      // The page is kept and labelled with the rule's tag
+	ActionNoFollow = "nofollow" // The page is kept but its links are not followed
+)
+
+// Parts of a page a rule can look at
+const (
+	FieldURL         = "url"
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldText        = "text"
+	FieldRating      = "rating" // <meta name="rating"> values
+)
+
+// Names given to the domain lists when they reject a page
+const (
+	RuleAllowDomains = "allow_domains"
+	RuleDenyDomains  = "deny_domains"
+)
+
+//go:embed default.json
+var defaultPolicy []byte
+
+// One rule of a policy file. A rule matches a page when the page is on one of
+// its domains, if any are given, and one of its fields contains one of its
+// words or matches its pattern, if either is given. Words match whole words
+// only, and both words and patterns ignore case.
+type Rule struct {
+	Name    string   `json:"name"`
+	Domains []string `json:"domains,omitempty"` // A domain also covers its subdomains
+	Fields  []string `json:"fields,omitempty"`
+	Words   []string `json:"words,omitempty"`
+	Pattern string   `json:"pattern,omitempty"` // A regular expression
+	Action  string   `json:"action"`
+	Tag     string   `json:"tag,omitempty"` // Required for the tag action
+}
+
+// The contents of a policy file
+type File struct {
+	AllowDomains []string `json:"allow_domains,omitempty"` // When given, pages on other domains are dropped
+	DenyDomains  []string `json:"deny_domains,omitempty"`
+	Rules        []Rule   `json:"rules"`
+}
+
+// A rule ready to be matched
+type compiledRule struct {
+	Rule
+	text *regexp.Regexp // Words and pattern combined, nil when the rule has neither
+}
+
+// A content policy, parsed and compiled. It is safe for concurrent use.
+type Policy struct {
+	allow []string
+	deny  []string
+	rules []compiledRule
+}
+
+// The parts of a page a policy is evaluated against. Before a page is
+// fetched only its URL is known, and empty fields match nothing.
+type Page struct {
+	URL         string
+	Title       string
+	Description string
+	Text        string
+	Ratings     []string
+}
+
+// The outcome of evaluating a policy against a page
+type Decision struct {
+	Drop     bool
+	Rule     string   // The rule that dropped the page
+	NoFollow bool
+	Tags     []string
+	Fired    []string // Every rule that matched, in order
+}
+
+// Parses and compiles a policy file
+func Parse(data []byte) (*Policy, error) {
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	return Compile(file)
+}
+
+// Compiles a policy, checking every rule
+func Compile(file File) (*Policy, error) {
+	policy := &Policy{allow: normalizeDomains(file.AllowDomains), deny: normalizeDomains(file.DenyDomains)}
+	names := make(map[string]struct{}, len(file.Rules))
+	for i, rule := range file.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i + 1)
+		}
+		if _, duplicate := names[rule.Name]; duplicate {
+			return nil, fmt.Errorf("rule %q is defined twice", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		policy.rules = append(policy.rules, compiled)
+	}
+	return policy, nil
+}
+
+// Returns the policy built into the crawler, which drops pages with adult
+// words in their title or an adult rating
+func Default() *Policy {
+	policy, err := Parse(defaultPolicy)
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	switch rule.Action {
+	case ActionDrop, ActionNoFollow:
+	case ActionTag:
+		if rule.Tag == "" {
+			return compiledRule{}, fmt.Errorf("the tag action needs a tag")
+		}
+	default:
+		return compiledRule{}, fmt.Errorf("unknown action %q", rule.Action)
+	}
+	rule.Domains = normalizeDomains(rule.Domains)
+
+	var alternatives []string
+	for _, word := range rule.Words {
+		if word = strings.TrimSpace(word); word != "" {
+			alternatives = append(alternatives, `(?:^|[^\p{L}\p{N}_])` + regexp.QuoteMeta(word) + `(?:$|[^\p{L}\p{N}_])`)
+		}
+	}
+	if rule.Pattern != "" {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return compiledRule{}, fmt.Errorf("invalid pattern: %w", err)
+		}
+		alternatives = append(alternatives, "(?:" + rule.Pattern + ")")
+	}
+
+	if len(alternatives) == 0 {
+		if len(rule.Domains) == 0 {
+			return compiledRule{}, fmt.Errorf("the rule matches nothing, give it domains, words or a pattern")
+		}
+		return compiledRule{Rule: rule}, nil
+	}
+	if len(rule.Fields) == 0 {
+		return compiledRule{}, fmt.Errorf("words and patterns need fields to look in")
+	}
+	for _, field := range rule.Fields {
+		switch field {
+		case FieldURL, FieldTitle, FieldDescription, FieldText, FieldRating:
+		default:
+			return compiledRule{}, fmt.Errorf("unknown field %q", field)
+		}
+	}
+	text, err := regexp.Compile("(?i)" + strings.Join(alternatives, "|"))
+	if err != nil {
+		return compiledRule{}, err
+	}
+	return compiledRule{Rule: rule, text: text}, nil
+}
+
+// Evaluates the policy against a page. The domain lists are checked first,
+// then the rules in order; the first rule that drops the page ends the
+// evaluation.
+func (policy *Policy) Evaluate(page Page) Decision {
+	var decision Decision
+	host := hostOf(page.URL)
+	if host != "" && len(policy.allow) > 0 && !onDomains(host, policy.allow) {
+		return Decision{Drop: true, Rule: RuleAllowDomains, Fired: []string{RuleAllowDomains}}
+	}
+	if host != "" && onDomains(host, policy.deny) {
+		return Decision{Drop: true, Rule: RuleDenyDomains, Fired: []string{RuleDenyDomains}}
+	}
+
+	for _, rule := range policy.rules {
+		if !rule.matches(page, host) {
+			continue
+		}
+		decision.Fired = append(decision.Fired, rule.Name)
+		switch rule.Action {
+		case ActionDrop:
+			decision.Drop, decision.Rule = true, rule.Name
+			return decision
+		case ActionTag:
+			decision.Tags = appendUnique(decision.Tags, rule.Tag)
+		case ActionNoFollow:
+			decision.NoFollow = true
+		}
+	}
+	return decision
+}
+
+// Reports whether a rule matches a page
+func (rule compiledRule) matches(page Page, host string) bool {
+	if len(rule.Domains) > 0 && (host == "" || !onDomains(host, rule.Domains)) {
+		return false
+	}
+	if rule.text == nil {
+		return true
+	}
+	for _, field := range rule.Fields {
+		for _, value := range page.values(field) {
+			if value != "" && rule.text.MatchString(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the values of one of a page's fields
+func (page Page) values(field string) []string {
+	switch field {
+	case FieldURL:
+		return []string{page.URL}
+	case FieldTitle:
+		return []string{page.Title}
+	case FieldDescription:
+		return []string{page.Description}
+	case FieldText:
+		return []string{page.Text}
+	case FieldRating:
+		return page.Ratings
+	}
+	return nil
+}
+
+// Reports whether a host is one of the domains or a subdomain of one
+func onDomains(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "." + domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the lower cased host of a URL, without any port or leading "www."
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
+
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "www.")
+		if domain = strings.Trim(domain, "."); domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestDefaultPolicy tests that the built-in rules match whole words only.
+func TestDefaultPolicy(t *testing.T) {
+	policy := Default()
+	for title, dropped := range map[string]bool{
+		"Hot XXX videos":              true,
+		"Sex education in schools":    true,
+		"Cricket results from Sussex": false,
+		"Essex County Council":        false,
+		"Gorey harbour walks":         false,
+	} {
+		decision := policy.Evaluate(Page{URL: "https://example.com/", Title: title})
+		if decision.Drop != dropped {
+			t.Errorf("%q: expected drop %v, got %+v", title, dropped, decision)
+		}
+		if dropped && decision.Rule != "adult-title" {
+			t.Errorf("%q: expected the adult-title rule, got %q", title, decision.Rule)
+		}
+	}
+	if decision := policy.Evaluate(Page{URL: "https://example.com/", Ratings: []string{"RTA-5042-1996-1400-1577-RTA"}}); !decision.Drop || decision.Rule != "adult-rating" {
+		t.Errorf("Expected the RTA label to drop the page, got %+v", decision)
+	}
+}
+
+// TestEvaluate tests domain lists, URL patterns and the tag and nofollow actions.
+func TestEvaluate(t *testing.T) {
+	policy, err := Parse([]byte(`{
+		"deny_domains": ["spam.example"],
+		"rules": [
+			{"name": "calendar", "fields": ["url"], "pattern": "/calendar/\\d{4}", "action": "nofollow"},
+			{"name": "casino", "fields": ["title", "text"], "words": ["online casino"], "action": "tag", "tag": "gambling"},
+			{"name": "forum", "domains": ["forum.example.org"], "action": "tag", "tag": "ugc"},
+			{"name": "spam-text", "fields": ["description"], "pattern": "cheap (pills|watches)", "action": "drop"},
+			{"name": "blocked", "domains": ["example.net"], "action": "drop"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decision := policy.Evaluate(Page{URL: "https://www.spam.example/page"})
+	if !decision.Drop || decision.Rule != RuleDenyDomains {
+		t.Errorf("Expected the deny list to drop the page, got %+v", decision)
+	}
+
+	decision = policy.Evaluate(Page{URL: "https://forum.example.org/calendar/2024", Text: "Best Online Casino bonuses"})
+	if decision.Drop || !decision.NoFollow || !reflect.DeepEqual(decision.Tags, []string{"gambling", "ugc"}) {
+		t.Errorf("Unexpected decision %+v", decision)
+	}
+
+	decision = policy.Evaluate(Page{URL: "https://blog.example.net/", Description: "Buy CHEAP watches"})
+	if !decision.Drop || decision.Rule != "spam-text" || !reflect.DeepEqual(decision.Fired, []string{"spam-text"}) {
+		t.Errorf("Expected the first dropping rule to end the evaluation, got %+v", decision)
+	}
+
+	allowList, err := Parse([]byte(`{"allow_domains": ["example.com"], "rules": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision := allowList.Evaluate(Page{URL: "https://docs.example.com/"}); decision.Drop {
+		t.Errorf("Expected a subdomain of an allowed domain to be kept, got %+v", decision)
+	}
+	if decision := allowList.Evaluate(Page{URL: "https://example.net/"}); !decision.Drop || decision.Rule != RuleAllowDomains {
+		t.Errorf("Expected other domains to be dropped, got %+v", decision)
+	}
+}
+
+// TestParseInvalid tests that broken rules are reported with their name.
+func TestParseInvalid(t *testing.T) {
+	for document, expected := range map[string]string{
+		`{"rules": [{"name": "a", "fields": ["title"], "words": ["x"], "action": "delete"}]}`: "unknown action",
+		`{"rules": [{"name": "a", "fields": ["title"], "words": ["x"], "action": "tag"}]}`:    "needs a tag",
+		`{"rules": [{"name": "a", "fields": ["title"], "pattern": "(", "action": "drop"}]}`:   "invalid pattern",
+		`{"rules": [{"name": "a", "words": ["x"], "action": "drop"}]}`:                        "need fields",
+		`{"rules": [{"name": "a", "fields": ["body"], "words": ["x"], "action": "drop"}]}`:    "unknown field",
+		`{"rules": [{"name": "a", "action": "drop"}]}`:                                        "matches nothing",
+		`{"rules": [{"fields": ["title"], "words": ["x"], "action": "drop"}]}`:                "no name",
+		`{"rules": [`: "invalid policy",
+	} {
+		if _, err := Parse([]byte(document)); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", document, expected, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A page the content policy dropped, and the rule that dropped it
+type Rejection struct {
+	URL        string
+	Rule       string
+	RejectedAt time.Time
+}
+
+// Append-only, tab separated file of rejected pages
+type RejectionLog struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// Creates a rejection log handle. The file is created on first write.
+func NewRejectionLog(path string) *RejectionLog {
+	return &RejectionLog{path: path}
+}
+
+// Appends a rejection to the log
+func (rejections *RejectionLog) Write(rejection Rejection) error {
+	rejections.mutex.Lock()
+	defer rejections.mutex.Unlock()
+
+	file, err := os.OpenFile(rejections.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening rejection log: %v", err)
+	}
+	defer file.Close()
+
+	clean := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+	line := strings.Join([]string{
+		rejection.RejectedAt.UTC().Format(time.RFC3339),
+		clean.Replace(rejection.Rule),
+		clean.Replace(rejection.URL),
+	}, "\t")
+	_, err = file.WriteString(line + "\n")
+	return err
+}
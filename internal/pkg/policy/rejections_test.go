@@ -0,0 +1,30 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRejectionLogWrite tests that each rejection becomes one tab separated line.
+func TestRejectionLogWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rejections.tsv")
+	rejections := NewRejectionLog(path)
+	at := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	if err := rejections.Write(Rejection{URL: "https://example.com/a", Rule: "adult-title", RejectedAt: at}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rejections.Write(Rejection{URL: "https://example.com/b\tc", Rule: "deny_domains", RejectedAt: at}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "2024-03-05T10:00:00Z\tadult-title\thttps://example.com/a\n2024-03-05T10:00:00Z\tdeny_domains\thttps://example.com/b c\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(data))
+	}
+}
@@ -0,0 +1,282 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrNoHealthyProxy   = errors.New("no healthy proxy available")
+	ErrProxyUnavailable = errors.New("proxy is unhealthy")
+)
+
+// An upstream proxy with its own connection pool
+type upstream struct {
+	url       *url.URL
+	transport *http.Transport
+	rotating  bool // Part of the pool rather than only used by a rule
+	mutex     sync.Mutex
+	failures  int
+	healthy   bool
+}
+
+// Routes requests directly or through upstream proxies according to the
+// configured rules. Pool implements http.RoundTripper so it can be used as
+// the Transport of any client.
+type Pool struct {
+	config    Config
+	direct    *http.Transport
+	upstreams map[string]*upstream // Keyed by proxy URL
+	rotation  []*upstream
+	next      atomic.Uint64
+	cancel    context.CancelFunc
+	waitGroup sync.WaitGroup
+}
+
+// Creates a pool whose connections are configured like base
+func NewPool(config Config, base *http.Transport) (*Pool, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	defaults := DefaultConfig()
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = defaults.MaxFailures
+	}
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = defaults.HealthCheckInterval
+	}
+	if config.HealthCheckTimeout <= 0 {
+		config.HealthCheckTimeout = defaults.HealthCheckTimeout
+	}
+
+	pool := &Pool{
+		config:    config,
+		direct:    base.Clone(),
+		upstreams: make(map[string]*upstream),
+	}
+	pool.direct.Proxy = nil
+
+	for _, proxy := range config.Proxies {
+		upstream := pool.addUpstream(proxy)
+		if !upstream.rotating {
+			upstream.rotating = true
+			pool.rotation = append(pool.rotation, upstream)
+		}
+	}
+	for _, rule := range config.Rules {
+		if rule.Target != TargetDirect && rule.Target != TargetPool {
+			pool.addUpstream(rule.Target)
+		}
+	}
+	return pool, nil
+}
+
+// Adds an upstream for a validated proxy URL, reusing an existing one
+func (pool *Pool) addUpstream(raw string) *upstream {
+	if existing, exists := pool.upstreams[raw]; exists {
+		return existing
+	}
+	proxyURL, _ := parseProxyURL(raw)
+	transport := pool.direct.Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	added := &upstream{url: proxyURL, transport: transport, healthy: true}
+	pool.upstreams[raw] = added
+	return added
+}
+
+// Sends the request through the route chosen for its host
+func (pool *Pool) RoundTrip(request *http.Request) (*http.Response, error) {
+	chosen, err := pool.pick(strings.ToLower(request.URL.Hostname()))
+	if err != nil {
+		return nil, err
+	}
+	if chosen == nil {
+		return pool.direct.RoundTrip(request)
+	}
+
+	response, err := chosen.transport.RoundTrip(request)
+	switch {
+	case err == nil:
+		pool.reportSuccess(chosen)
+	case IsProxyError(err):
+		pool.reportFailure(chosen, err)
+	}
+	return response, err
+}
+
+// Reports whether requests to the host are sent without a proxy
+func (pool *Pool) IsDirect(host string) bool {
+	return pool.target(strings.ToLower(host)) == TargetDirect
+}
+
+// Returns how many proxies in the rotation are currently healthy
+func (pool *Pool) Healthy() int {
+	count := 0
+	for _, upstream := range pool.rotation {
+		if upstream.isHealthy() {
+			count++
+		}
+	}
+	return count
+}
+
+// Returns the target of the first rule matching the host
+func (pool *Pool) target(host string) string {
+	for _, rule := range pool.config.Rules {
+		if rule.matches(host) {
+			return rule.Target
+		}
+	}
+	if len(pool.rotation) > 0 {
+		return TargetPool
+	}
+	return TargetDirect
+}
+
+// Chooses the upstream for a host, or nil to connect directly
+func (pool *Pool) pick(host string) (*upstream, error) {
+	switch target := pool.target(host); target {
+	case TargetDirect:
+		return nil, nil
+	case TargetPool:
+		// Round robin over the rotation, skipping unhealthy proxies
+		start := pool.next.Add(1)
+		for i := 0; i < len(pool.rotation); i++ {
+			candidate := pool.rotation[(start + uint64(i)) % uint64(len(pool.rotation))]
+			if candidate.isHealthy() {
+				return candidate, nil
+			}
+		}
+		return nil, ErrNoHealthyProxy
+	default:
+		pinned := pool.upstreams[target]
+		if !pinned.isHealthy() {
+			return nil, fmt.Errorf("%w: %s", ErrProxyUnavailable, pinned.url.Redacted())
+		}
+		return pinned, nil
+	}
+}
+
+func (upstream *upstream) isHealthy() bool {
+	upstream.mutex.Lock()
+	defer upstream.mutex.Unlock()
+	return upstream.healthy
+}
+
+func (pool *Pool) reportSuccess(upstream *upstream) {
+	upstream.mutex.Lock()
+	defer upstream.mutex.Unlock()
+	upstream.failures = 0
+	upstream.healthy = true
+}
+
+// Counts a failure to reach the proxy, taking it out of rotation after MaxFailures in a row
+func (pool *Pool) reportFailure(upstream *upstream, err error) {
+	upstream.mutex.Lock()
+	defer upstream.mutex.Unlock()
+	upstream.failures++
+	if upstream.healthy && upstream.failures >= pool.config.MaxFailures {
+		upstream.healthy = false
+		log.Printf("Proxy %s marked unhealthy after %d failures: %v", upstream.url.Redacted(), upstream.failures, err)
+	}
+}
+
+// Reports whether an error came from the proxy rather than the target, including
+// there being no healthy proxy to use
+func IsProxyError(err error) bool {
+	if errors.Is(err, ErrNoHealthyProxy) || errors.Is(err, ErrProxyUnavailable) {
+		return true
+	}
+	var opError *net.OpError
+	if errors.As(err, &opError) {
+		return opError.Op == "proxyconnect" || strings.HasPrefix(opError.Op, "socks")
+	}
+	return false
+}
+
+// Starts checking unhealthy proxies in the background until Close is called
+func (pool *Pool) Start() {
+	if len(pool.upstreams) == 0 || pool.cancel != nil {
+		return
+	}
+	context, cancel := context.WithCancel(context.Background())
+	pool.cancel = cancel
+	pool.waitGroup.Add(1)
+	go func() {
+		defer pool.waitGroup.Done()
+		ticker := time.NewTicker(pool.config.HealthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-context.Done():
+				return
+			case <-ticker.C:
+				pool.checkUnhealthy(context)
+			}
+		}
+	}()
+}
+
+// Stops health checks and closes idle connections
+func (pool *Pool) Close() {
+	if pool.cancel != nil {
+		pool.cancel()
+		pool.waitGroup.Wait()
+		pool.cancel = nil
+	}
+	pool.direct.CloseIdleConnections()
+	for _, upstream := range pool.upstreams {
+		upstream.transport.CloseIdleConnections()
+	}
+}
+
+// Checks every unhealthy proxy and returns the ones that respond to service
+func (pool *Pool) checkUnhealthy(context context.Context) {
+	for _, upstream := range pool.upstreams {
+		if upstream.isHealthy() {
+			continue
+		}
+		if err := pool.check(context, upstream); err == nil {
+			pool.reportSuccess(upstream)
+			log.Printf("Proxy %s is healthy again", upstream.url.Redacted())
+		}
+	}
+}
+
+// Fetches the health check URL through the proxy, or just dials the proxy if there is none
+func (pool *Pool) check(parent context.Context, upstream *upstream) error {
+	checkContext, cancel := context.WithTimeout(parent, pool.config.HealthCheckTimeout)
+	defer cancel()
+
+	if pool.config.HealthCheckURL == "" {
+		var dialer net.Dialer
+		connection, err := dialer.DialContext(checkContext, "tcp", upstream.url.Host)
+		if err != nil {
+			return err
+		}
+		return connection.Close()
+	}
+
+	request, err := http.NewRequestWithContext(checkContext, http.MethodGet, pool.config.HealthCheckURL, nil)
+	if err != nil {
+		return err
+	}
+	response, err := upstream.transport.RoundTrip(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 500 {
+		return fmt.Errorf("health check returned %d", response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Starts an HTTP proxy that answers every request itself with its name
+func newTestHTTPProxy(t *testing.T, name string) (*httptest.Server, *[]string) {
+	var authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.IsAbs() {
+			http.Error(w, "not a proxy request", http.StatusBadRequest)
+			return
+		}
+		authorizations = append(authorizations, r.Header.Get("Proxy-Authorization"))
+		fmt.Fprintf(w, "%s %s", name, r.URL.Host)
+	}))
+	t.Cleanup(server.Close)
+	return server, &authorizations
+}
+
+// Starts a minimal SOCKS5 proxy requiring the given username and password
+func newTestSOCKS5Proxy(t *testing.T, username, password string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSOCKS5(connection, username, password)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveSOCKS5(connection net.Conn, username, password string) {
+	defer connection.Close()
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(connection, header); err != nil {
+		return
+	}
+	methods := make([]byte, header[1])
+	io.ReadFull(connection, methods)
+	connection.Write([]byte{5, 2}) // Username and password
+
+	version := make([]byte, 2)
+	io.ReadFull(connection, version)
+	user := make([]byte, version[1])
+	io.ReadFull(connection, user)
+	length := make([]byte, 1)
+	io.ReadFull(connection, length)
+	pass := make([]byte, length[0])
+	io.ReadFull(connection, pass)
+	if string(user) != username || string(pass) != password {
+		connection.Write([]byte{1, 1})
+		return
+	}
+	connection.Write([]byte{1, 0})
+
+	request := make([]byte, 4)
+	io.ReadFull(connection, request)
+	var host string
+	switch request[3] {
+	case 1:
+		address := make([]byte, 4)
+		io.ReadFull(connection, address)
+		host = net.IP(address).String()
+	case 3:
+		io.ReadFull(connection, length)
+		name := make([]byte, length[0])
+		io.ReadFull(connection, name)
+		host = string(name)
+	default:
+		return
+	}
+	port := make([]byte, 2)
+	io.ReadFull(connection, port)
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		connection.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	connection.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(target, connection)
+	io.Copy(connection, target)
+}
+
+// Returns the address of a port nothing is listening on
+func closedAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func newTestPool(t *testing.T, config Config) *Pool {
+	pool, err := NewPool(config, http.DefaultTransport.(*http.Transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func get(t *testing.T, pool *Pool, target string) (string, error) {
+	client := &http.Client{Transport: pool, Timeout: 5 * time.Second}
+	response, err := client.Get(target)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	return string(body), err
+}
+
+// TestHTTPProxyWithCredentials tests that requests go through an HTTP proxy with its credentials.
+func TestHTTPProxyWithCredentials(t *testing.T) {
+	server, authorizations := newTestHTTPProxy(t, "first")
+	proxyURL := "http://user:secret@" + server.Listener.Addr().String()
+	pool := newTestPool(t, Config{Proxies: []string{proxyURL}})
+
+	body, err := get(t, pool, "http://example.com/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "first example.com" {
+		t.Errorf("Expected the proxy's response, got %q", body)
+	}
+	if len(*authorizations) != 1 || (*authorizations)[0] != "Basic dXNlcjpzZWNyZXQ=" {
+		t.Errorf("Expected basic proxy credentials, got %v", *authorizations)
+	}
+}
+
+// TestSOCKS5ProxyWithCredentials tests that requests tunnel through an authenticated SOCKS5 proxy.
+func TestSOCKS5ProxyWithCredentials(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "target")
+	}))
+	defer target.Close()
+	address := newTestSOCKS5Proxy(t, "user", "secret")
+
+	pool := newTestPool(t, Config{Proxies: []string{"socks5://user:secret@" + address}})
+	body, err := get(t, pool, target.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "target" {
+		t.Errorf("Expected the target's response, got %q", body)
+	}
+
+	wrongPassword := newTestPool(t, Config{Proxies: []string{"socks5://user:wrong@" + address}})
+	if _, err := get(t, wrongPassword, target.URL); err == nil {
+		t.Error("Expected the wrong password to be refused")
+	}
+}
+
+// TestRouting tests that rules send hosts direct, to a specific proxy or to the pool.
+func TestRouting(t *testing.T) {
+	pooled, _ := newTestHTTPProxy(t, "pooled")
+	pinned, _ := newTestHTTPProxy(t, "pinned")
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "direct")
+	}))
+	defer target.Close()
+
+	pool := newTestPool(t, Config{
+		Proxies: []string{pooled.URL},
+		Rules: []Rule{
+			{Domain: "127.0.0.1", Target: TargetDirect},
+			{Domain: "corp.net", Target: pinned.URL},
+		},
+	})
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{target.URL, "direct"},
+		{"http://intranet.corp.net/", "pinned intranet.corp.net"},
+		{"http://example.com/", "pooled example.com"},
+	}
+	for _, tt := range tests {
+		body, err := get(t, pool, tt.url)
+		if err != nil {
+			t.Fatalf("Fetching %s: %v", tt.url, err)
+		}
+		if body != tt.want {
+			t.Errorf("Fetching %s: expected %q, got %q", tt.url, tt.want, body)
+		}
+	}
+	if !pool.IsDirect("127.0.0.1") || pool.IsDirect("example.com") {
+		t.Error("IsDirect does not follow the rules")
+	}
+}
+
+// TestRotation tests that the pool alternates between its proxies.
+func TestRotation(t *testing.T) {
+	first, _ := newTestHTTPProxy(t, "first")
+	second, _ := newTestHTTPProxy(t, "second")
+	pool := newTestPool(t, Config{Proxies: []string{first.URL, second.URL}})
+
+	seen := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		body, err := get(t, pool, "http://example.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[body]++
+	}
+	if seen["first example.com"] != 2 || seen["second example.com"] != 2 {
+		t.Errorf("Expected requests split evenly, got %v", seen)
+	}
+}
+
+// TestUnhealthyProxy tests that failing proxies leave the rotation and health checks restore them.
+func TestUnhealthyProxy(t *testing.T) {
+	healthy, _ := newTestHTTPProxy(t, "healthy")
+	deadAddress := closedAddress(t)
+	pool := newTestPool(t, Config{
+		Proxies:            []string{"http://" + deadAddress, healthy.URL},
+		MaxFailures:        1,
+		HealthCheckTimeout: time.Second,
+	})
+
+	// One of the first two requests goes to the dead proxy and fails
+	failures := 0
+	for i := 0; i < 2; i++ {
+		if _, err := get(t, pool, "http://example.com/"); err != nil {
+			failures++
+		}
+	}
+	if failures != 1 || pool.Healthy() != 1 {
+		t.Fatalf("Expected one failure and one healthy proxy, got %d failures and %d healthy", failures, pool.Healthy())
+	}
+	for i := 0; i < 3; i++ {
+		if body, err := get(t, pool, "http://example.com/"); err != nil || body != "healthy example.com" {
+			t.Errorf("Expected the healthy proxy to be used, got %q, %v", body, err)
+		}
+	}
+
+	// The proxy comes back once something listens on its address again
+	listener, err := net.Listen("tcp", deadAddress)
+	if err != nil {
+		t.Skipf("Could not reopen %s: %v", deadAddress, err)
+	}
+	defer listener.Close()
+	pool.checkUnhealthy(context.Background())
+	if pool.Healthy() != 2 {
+		t.Errorf("Expected the health check to restore the proxy, %d healthy", pool.Healthy())
+	}
+}
+
+// TestNoHealthyProxy tests that requests fail fast when every proxy is down.
+func TestNoHealthyProxy(t *testing.T) {
+	pinnedAddress := "http://" + closedAddress(t)
+	pool := newTestPool(t, Config{
+		Proxies:     []string{"http://" + closedAddress(t)},
+		Rules:       []Rule{{Domain: "corp.net", Target: pinnedAddress}},
+		MaxFailures: 1,
+	})
+
+	get(t, pool, "http://example.com/")
+	if _, err := get(t, pool, "http://example.com/"); !errors.Is(err, ErrNoHealthyProxy) {
+		t.Errorf("Expected ErrNoHealthyProxy, got %v", err)
+	}
+	get(t, pool, "http://corp.net/")
+	if _, err := get(t, pool, "http://corp.net/"); !errors.Is(err, ErrProxyUnavailable) {
+		t.Errorf("Expected ErrProxyUnavailable, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
-package queue 
+package queue
 
 import (
+    "container/heap"
     "errors"
     "sync"
 )
@@ -8,15 +9,42 @@ import (
 type Queue struct {
     mu       sync.Mutex
     capacity int
-    q        []string
+    q        entries
+    inserted uint64 // Insertions so far, used to keep equal priorities in order
 }
 
-// First in, first out queue 
+// First in, first out queue
 type FifoQueue interface {
     Insert()
     Remove()
 }
 
+// An item with its priority and place in the insertion order
+type entry struct {
+    item     string
+    priority float64
+    sequence uint64
+}
+
+// Heap of entries, highest priority first and oldest first among equals
+type entries []entry
+
+func (e entries) Len() int      { return len(e) }
+func (e entries) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e entries) Less(i, j int) bool {
+    if e[i].priority != e[j].priority {
+        return e[i].priority > e[j].priority
+    }
+    return e[i].sequence < e[j].sequence
+}
+func (e *entries) Push(x any) { *e = append(*e, x.(entry)) }
+func (e *entries) Pop() any {
+    old := *e
+    last := old[len(old) - 1]
+    *e = old[:len(old) - 1]
+    return last
+}
+
 // Creates an empty queue with a specified capacity
 func CreateQueue(capacity int) (*Queue, error) {
     if capacity <= 0 {
@@ -24,29 +52,35 @@ func CreateQueue(capacity int) (*Queue, error) {
     }
     return &Queue{
         capacity: capacity,
-        q:        make([]string, 0, capacity),
+        q:        make(entries, 0, capacity),
     }, nil
 }
 
 // Inserts an item into the queue
 func (q *Queue) Insert(item string) error {
+    return q.InsertWithPriority(item, 0)
+}
+
+// Inserts an item that is removed ahead of every item with a lower priority.
+// Items with the same priority leave in the order they were inserted.
+func (q *Queue) InsertWithPriority(item string, priority float64) error {
     q.mu.Lock()
     defer q.mu.Unlock()
     if len(q.q) < int(q.capacity) {
-        q.q = append(q.q, item)
+        heap.Push(&q.q, entry{item: item, priority: priority, sequence: q.inserted})
+        q.inserted++
         return nil
     }
     return errors.New("queue is full")
 }
 
-// Removes the oldest element from the queue
+// Removes the highest priority element from the queue, the oldest if all
+// were inserted with the same priority
 func (q *Queue) Remove() (string, error) {
     q.mu.Lock()
     defer q.mu.Unlock()
     if len(q.q) > 0 {
-        item := q.q[0]
-        q.q = q.q[1:]
-        return item, nil
+        return heap.Pop(&q.q).(entry).item, nil
     }
     return "", errors.New("queue is empty")
 }
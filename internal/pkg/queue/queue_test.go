@@ -241,3 +241,22 @@ func TestIsEmpty(t *testing.T) {
 		t.Errorf("Expected queue to be empty again")
 	}
 }
+
+// Tests that higher priorities leave first and equal priorities keep their order.
+func TestInsertWithPriority(t *testing.T) {
+	q, err := CreateQueue(5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	q.Insert("a")
+	q.InsertWithPriority("b", 2.5)
+	q.InsertWithPriority("c", 0.5)
+	q.InsertWithPriority("d", 2.5)
+	q.Insert("e")
+	for _, expected := range []string{"b", "d", "c", "a", "e"} {
+		item, err := q.Remove()
+		if err != nil || item != expected {
+			t.Errorf("Expected %q, got %q (%v)", expected, item, err)
+		}
+	}
+}
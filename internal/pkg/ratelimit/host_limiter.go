@@ -0,0 +1,168 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+	"webcrawler/internal/pkg/retry"
+	"webcrawler/internal/pkg/types"
+)
+
+// Tuning for the adaptive per-host limiter
+type Config struct {
+	MinDelay            time.Duration // Default minimum delay between requests to a host
+	MaxDelay            time.Duration // Upper bound the delay can grow to
+	IncreaseFactor      float64       // Multiplicative slowdown on 429/503, timeouts or rising latency
+	DecreaseStep        time.Duration // Additive speed-up after each healthy response
+	LatencyFactor       float64       // Latency above baseline times this counts as rising
+	MaxHosts            int           // Idle hosts are forgotten beyond this many
+	MaxConnectionsPerIP int           // Global ceiling on concurrent requests per IP
+}
+
+// Returns the configuration used by the administrator
+func DefaultConfig() Config {
+	return Config{
+		MinDelay:            1 * time.Second,
+		MaxDelay:            2 * time.Minute,
+		IncreaseFactor:      2,
+		DecreaseStep:        100 * time.Millisecond,
+		LatencyFactor:       3,
+		MaxHosts:            100000,
+		MaxConnectionsPerIP: 4,
+	}
+}
+
+type hostState struct {
+	delay       time.Duration // Current gap between requests
+	floor       time.Duration // Minimum gap for this host, e.g. from Crawl-delay
+	nextAllowed time.Time     // Earliest time the next request may start
+	latency     time.Duration // Moving average of response latency
+	baseline    time.Duration // Slowly adapting reference latency
+}
+
+// Spaces out requests to each host, slowing down when the server signals
+// distress and speeding up again while responses are healthy (AIMD).
+type HostLimiter struct {
+	config Config
+	mutex  sync.Mutex
+	hosts  map[string]*hostState
+}
+
+// Creates a limiter with the given configuration
+func NewHostLimiter(config Config) *HostLimiter {
+	return &HostLimiter{
+		config: config,
+		hosts:  make(map[string]*hostState),
+	}
+}
+
+// Blocks until a request to the host is allowed, or the context is done
+func (limiter *HostLimiter) Wait(context context.Context, host string) error {
+	limiter.mutex.Lock()
+	state := limiter.getState(host)
+	now := time.Now()
+	slot := now
+	if state.nextAllowed.After(now) {
+		slot = state.nextAllowed
+	}
+	state.nextAllowed = slot.Add(state.delay)
+	limiter.mutex.Unlock()
+
+	wait := slot.Sub(now)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-context.Done():
+		return context.Err()
+	}
+}
+
+// Adjusts the host's delay based on the outcome of a fetch
+func (limiter *HostLimiter) Observe(host string, result types.FetchResult) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	state := limiter.getState(host)
+
+	throttled := result.StatusCode == http.StatusTooManyRequests ||
+		result.StatusCode == http.StatusServiceUnavailable ||
+		result.ErrorCategory == types.ErrorTimeout
+	if result.StatusCode == 0 && !throttled {
+		return // No response from the server, e.g. blocked by robots.txt
+	}
+	slow := false
+
+	if result.Duration > 0 && result.ErrorCategory != types.ErrorTimeout {
+		if state.latency == 0 {
+			state.latency, state.baseline = result.Duration, result.Duration
+		} else {
+			state.latency = (state.latency * 4 + result.Duration) / 5
+			slow = float64(state.latency) > float64(state.baseline) * limiter.config.LatencyFactor
+			// Let the baseline follow lasting changes slowly
+			state.baseline += (state.latency - state.baseline) / 50
+		}
+	}
+
+	if throttled || slow {
+		state.delay = time.Duration(float64(state.delay) * limiter.config.IncreaseFactor)
+	} else {
+		state.delay -= limiter.config.DecreaseStep
+	}
+	state.delay = min(max(state.delay, limiter.config.MinDelay, state.floor), limiter.config.MaxDelay)
+
+	retryAfter := retry.ParseRetryAfter(http.Header(result.Header).Get("Retry-After"), time.Now())
+	if retryAfter > 0 {
+		retryAfter = min(retryAfter, limiter.config.MaxDelay)
+		if resume := time.Now().Add(retryAfter); resume.After(state.nextAllowed) {
+			state.nextAllowed = resume
+		}
+	}
+}
+
+// Sets a minimum delay for a host that the limiter will never go below
+func (limiter *HostLimiter) SetFloor(host string, floor time.Duration) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	state := limiter.getState(host)
+	state.floor = min(floor, limiter.config.MaxDelay)
+	state.delay = max(state.delay, state.floor)
+}
+
+// Returns the current delay between requests to a host
+func (limiter *HostLimiter) Delay(host string) time.Duration {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if state, exists := limiter.hosts[host]; exists {
+		return state.delay
+	}
+	return limiter.config.MinDelay
+}
+
+// Gets or creates the state for a host. Must be called with the mutex held.
+func (limiter *HostLimiter) getState(host string) *hostState {
+	state, exists := limiter.hosts[host]
+	if !exists {
+		if limiter.config.MaxHosts > 0 && len(limiter.hosts) >= limiter.config.MaxHosts {
+			limiter.pruneIdle()
+		}
+		state = &hostState{delay: limiter.config.MinDelay}
+		limiter.hosts[host] = state
+	}
+	return state
+}
+
+// Forgets hosts that are back at the default delay and not waiting on anything.
+// Must be called with the mutex held.
+func (limiter *HostLimiter) pruneIdle() {
+	now := time.Now()
+	for host, state := range limiter.hosts {
+		if state.delay <= limiter.config.MinDelay && state.floor == 0 && state.nextAllowed.Before(now) {
+			delete(limiter.hosts, host)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+	"webcrawler/internal/pkg/types"
+)
+
+func testConfig() Config {
+	return Config{
+		MinDelay:       10 * time.Millisecond,
+		MaxDelay:       time.Second,
+		IncreaseFactor: 2,
+		DecreaseStep:   5 * time.Millisecond,
+		LatencyFactor:  3,
+	}
+}
+
+// Consecutive requests to the same host should be spaced by the delay.
+func TestHostLimiterSpacesRequests(t *testing.T) {
+	limiter := NewHostLimiter(Config{MinDelay: 50 * time.Millisecond, MaxDelay: time.Second})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background(), "example.com"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100 * time.Millisecond {
+		t.Errorf("expected at least 100ms for 3 requests, took %v", elapsed)
+	}
+
+	// A different host is not affected
+	start = time.Now()
+	limiter.Wait(context.Background(), "other.com")
+	if elapsed := time.Since(start); elapsed > 20 * time.Millisecond {
+		t.Errorf("expected no wait for a new host, took %v", elapsed)
+	}
+}
+
+// Throttling responses double the delay; healthy ones reduce it additively.
+func TestHostLimiterAIMD(t *testing.T) {
+	limiter := NewHostLimiter(testConfig())
+	host := "example.com"
+
+	limiter.Observe(host, types.FetchResult{StatusCode: http.StatusTooManyRequests, ErrorCategory: types.ErrorHTTP4xx})
+	if delay := limiter.Delay(host); delay != 20 * time.Millisecond {
+		t.Errorf("expected 20ms after 429, got %v", delay)
+	}
+	limiter.Observe(host, types.FetchResult{StatusCode: http.StatusServiceUnavailable, ErrorCategory: types.ErrorHTTP5xx})
+	if delay := limiter.Delay(host); delay != 40 * time.Millisecond {
+		t.Errorf("expected 40ms after 503, got %v", delay)
+	}
+	limiter.Observe(host, types.FetchResult{StatusCode: http.StatusOK})
+	if delay := limiter.Delay(host); delay != 35 * time.Millisecond {
+		t.Errorf("expected 35ms after healthy response, got %v", delay)
+	}
+	for i := 0; i < 20; i++ {
+		limiter.Observe(host, types.FetchResult{StatusCode: http.StatusOK})
+	}
+	if delay := limiter.Delay(host); delay != 10 * time.Millisecond {
+		t.Errorf("expected delay to return to the minimum, got %v", delay)
+	}
+	for i := 0; i < 20; i++ {
+		limiter.Observe(host, types.FetchResult{ErrorCategory: types.ErrorTimeout})
+	}
+	if delay := limiter.Delay(host); delay != time.Second {
+		t.Errorf("expected delay capped at the maximum, got %v", delay)
+	}
+}
+
+// A sharp rise in latency should slow the host down.
+func TestHostLimiterLatency(t *testing.T) {
+	limiter := NewHostLimiter(testConfig())
+	host := "example.com"
+
+	for i := 0; i < 5; i++ {
+		limiter.Observe(host, types.FetchResult{StatusCode: http.StatusOK, Duration: 100 * time.Millisecond})
+	}
+	before := limiter.Delay(host)
+	for i := 0; i < 10; i++ {
+		limiter.Observe(host, types.FetchResult{StatusCode: http.StatusOK, Duration: 2 * time.Second})
+	}
+	if after := limiter.Delay(host); after <= before {
+		t.Errorf("expected delay to grow with latency, before %v after %v", before, after)
+	}
+}
+
+// Retry-After pushes back the next allowed request.
+func TestHostLimiterRetryAfter(t *testing.T) {
+	limiter := NewHostLimiter(testConfig())
+	host := "example.com"
+	limiter.Observe(host, types.FetchResult{
+		StatusCode:    http.StatusServiceUnavailable,
+		ErrorCategory: types.ErrorHTTP5xx,
+		Header:        http.Header{"Retry-After": []string{"1"}},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100 * time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, host); err == nil {
+		t.Error("expected wait to outlast the context because of Retry-After")
+	}
+}
+
+// The floor keeps the delay from dropping below a host's Crawl-delay.
+func TestHostLimiterFloor(t *testing.T) {
+	limiter := NewHostLimiter(testConfig())
+	host := "example.com"
+	limiter.SetFloor(host, 200 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		limiter.Observe(host, types.FetchResult{StatusCode: http.StatusOK})
+	}
+	if delay := limiter.Delay(host); delay != 200 * time.Millisecond {
+		t.Errorf("expected delay held at floor, got %v", delay)
+	}
+
+	// Requests are spaced by the floor
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background(), host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400 * time.Millisecond {
+		t.Errorf("expected 3 requests to take at least 400ms, took %v", elapsed)
+	}
+}
+
+// Idle hosts are dropped once the host limit is reached.
+func TestHostLimiterPrunesIdleHosts(t *testing.T) {
+	config := testConfig()
+	config.MaxHosts = 2
+	limiter := NewHostLimiter(config)
+	limiter.Observe("a.com", types.FetchResult{StatusCode: http.StatusOK})
+	limiter.Observe("b.com", types.FetchResult{StatusCode: http.StatusTooManyRequests})
+	limiter.Observe("c.com", types.FetchResult{StatusCode: http.StatusOK})
+
+	if _, exists := limiter.hosts["a.com"]; exists {
+		t.Error("expected idle host a.com to be pruned")
+	}
+	if _, exists := limiter.hosts["b.com"]; !exists {
+		t.Error("expected throttled host b.com to be kept")
+	}
+}
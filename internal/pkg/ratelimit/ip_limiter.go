@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	ipCacheTTL   = 5 * time.Minute
+	maxCachedIPs = 100000
+)
+
+type cachedIP struct {
+	ip      string
+	expires time.Time
+}
+
+// Caps the number of concurrent requests to any single IP address,
+// no matter how many hostnames point at it.
+type IPLimiter struct {
+	limit      int
+	lookupHost func(context.Context, string) ([]string, error)
+	mutex      sync.Mutex
+	slots      map[string]chan struct{}
+	ips        map[string]cachedIP
+}
+
+// Creates a limiter allowing up to `limit` concurrent requests per IP
+func NewIPLimiter(limit int) *IPLimiter {
+	return &IPLimiter{
+		limit:      max(limit, 1),
+		lookupHost: net.DefaultResolver.LookupHost,
+		slots:      make(map[string]chan struct{}),
+		ips:        make(map[string]cachedIP),
+	}
+}
+
+// Resolves hosts with the given function instead of the system resolver
+func (limiter *IPLimiter) SetLookup(lookupHost func(context.Context, string) ([]string, error)) {
+	limiter.lookupHost = lookupHost
+}
+
+// Blocks until a request slot for the host's IP is free. The returned
+// function must be called to release the slot once the request is done.
+func (limiter *IPLimiter) Acquire(context context.Context, host string) (func(), error) {
+	key := limiter.resolve(context, host)
+
+	limiter.mutex.Lock()
+	slot, exists := limiter.slots[key]
+	if !exists {
+		slot = make(chan struct{}, limiter.limit)
+		limiter.slots[key] = slot
+	}
+	limiter.mutex.Unlock()
+
+	select {
+	case slot <- struct{}{}:
+		return func() { <-slot }, nil
+	case <-context.Done():
+		return nil, context.Err()
+	}
+}
+
+// Resolves a host to the IP used as its slot key. Falls back to the host
+// itself if it cannot be resolved, leaving DNS errors to the fetch.
+func (limiter *IPLimiter) resolve(context context.Context, host string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+
+	limiter.mutex.Lock()
+	cached, exists := limiter.ips[host]
+	limiter.mutex.Unlock()
+	if exists && time.Now().Before(cached.expires) {
+		return cached.ip
+	}
+
+	addresses, err := limiter.lookupHost(context, host)
+	if err != nil || len(addresses) == 0 {
+		return host
+	}
+
+	limiter.mutex.Lock()
+	if len(limiter.ips) >= maxCachedIPs {
+		now := time.Now()
+		for cachedHost, entry := range limiter.ips {
+			if now.After(entry.expires) {
+				delete(limiter.ips, cachedHost)
+			}
+		}
+	}
+	limiter.ips[host] = cachedIP{ip: addresses[0], expires: time.Now().Add(ipCacheTTL)}
+	limiter.mutex.Unlock()
+	return addresses[0]
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Hosts sharing an IP should share its concurrency slots.
+func TestIPLimiterSharedIP(t *testing.T) {
+	limiter := NewIPLimiter(1)
+	limiter.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		return []string{"10.0.0.1"}, nil
+	}
+
+	release, err := limiter.Acquire(context.Background(), "a.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "b.example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected second host on the same IP to block, got %v", err)
+	}
+
+	release()
+	release, err = limiter.Acquire(context.Background(), "b.example.com")
+	if err != nil {
+		t.Fatalf("expected slot after release, got %v", err)
+	}
+	release()
+}
+
+// Different IPs have independent slots, and unresolvable hosts fall back to their name.
+func TestIPLimiterIndependentIPs(t *testing.T) {
+	limiter := NewIPLimiter(1)
+	limiter.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		if host == "broken.invalid" {
+			return nil, errors.New("no such host")
+		}
+		return []string{"10.0.0." + string(host[0])}, nil
+	}
+
+	for _, host := range []string{"1.example.com", "2.example.com", "broken.invalid", "192.0.2.1"} {
+		ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+		if _, err := limiter.Acquire(ctx, host); err != nil {
+			t.Errorf("expected slot for %s, got %v", host, err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,160 @@
+package redirects
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"webcrawler/internal/pkg/types"
+	"webcrawler/internal/pkg/utils"
+)
+
+// Longest chain of remembered redirects followed by Resolve
+const maxResolveHops = 10
+
+// Remembers permanent (301 and 308) redirects so that links to the old URL
+// can be rewritten before they are queued. Redirects are appended to a tab
+// separated file as they are learnt and read back when the store is opened.
+type Store struct {
+	path       string
+	maxEntries int
+	mutex      sync.Mutex
+	targets    map[string]string // Old URL to the URL it permanently redirects to
+}
+
+// Opens the store, loading any redirects saved by earlier runs. An empty
+// path keeps redirects in memory only.
+func NewStore(path string, maxEntries int) (*Store, error) {
+	store := &Store{path: path, maxEntries: maxEntries, targets: make(map[string]string)}
+	if path == "" {
+		return store, nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening redirects file: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		from, to, found := strings.Cut(scanner.Text(), "\t")
+		if found && from != "" && to != "" {
+			store.add(from, to) // Later lines replace earlier ones
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading redirects file: %v", err)
+	}
+	return store, nil
+}
+
+// Records every permanent hop in a redirect chain
+func (store *Store) RememberChain(chain []types.Redirect) error {
+	for _, hop := range chain {
+		if hop.StatusCode != 301 && hop.StatusCode != 308 {
+			continue
+		}
+		from, err := url.Parse(hop.URL)
+		if err != nil {
+			continue
+		}
+		to, err := from.Parse(hop.Location)
+		if err != nil {
+			continue
+		}
+		if err := store.Remember(hop.URL, to.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Records that one URL permanently redirects to another
+func (store *Store) Remember(from, to string) error {
+	from, to = normalize(from), normalize(to)
+	if from == "" || to == "" || from == to {
+		return nil
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.targets[from] == to {
+		return nil
+	}
+	if !store.add(from, to) || store.path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(store.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening redirects file: %v", err)
+	}
+	defer file.Close()
+	_, err = file.WriteString(from + "\t" + to + "\n")
+	return err
+}
+
+// Returns where a URL ends up after following remembered redirects, or the
+// URL itself if none are known. Loops are cut short.
+func (store *Store) Resolve(rawURL string) string {
+	current := normalize(rawURL)
+	if current == "" {
+		return rawURL
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	seen := map[string]bool{current: true}
+	for i := 0; i < maxResolveHops; i++ {
+		next, exists := store.targets[current]
+		if !exists || seen[next] {
+			break
+		}
+		seen[next] = true
+		current = next
+	}
+	if len(seen) == 1 {
+		return rawURL
+	}
+	return current
+}
+
+// Returns how many redirects are remembered
+func (store *Store) Len() int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return len(store.targets)
+}
+
+// Adds a redirect unless the store is full. Must be called with the mutex
+// held, or before the store is shared.
+func (store *Store) add(from, to string) bool {
+	if _, exists := store.targets[from]; !exists && store.maxEntries > 0 && len(store.targets) >= store.maxEntries {
+		return false
+	}
+	store.targets[from] = to
+	return true
+}
+
+// Puts a URL in the form the fetcher requests it in, without a fragment
+func normalize(rawURL string) string {
+	if strings.ContainsAny(rawURL, "\t\n") {
+		return ""
+	}
+	fullURL, err := utils.BuildFullUrl(rawURL)
+	if err != nil {
+		return ""
+	}
+	parsed, err := url.Parse(fullURL)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	parsed.Fragment = ""
+	return parsed.String()
+}
@@ -0,0 +1,97 @@
+package redirects
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"webcrawler/internal/pkg/types"
+)
+
+// TestRememberChain tests that only permanent hops are remembered, with relative Locations resolved.
+func TestRememberChain(t *testing.T) {
+	store, err := NewStore("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := []types.Redirect{
+		{URL: "http://example.com/old", StatusCode: 301, Location: "https://example.com/old"},
+		{URL: "https://example.com/old", StatusCode: 308, Location: "/new"},
+		{URL: "https://example.com/new", StatusCode: 302, Location: "/login"},
+	}
+	if err := store.RememberChain(chain); err != nil {
+		t.Fatal(err)
+	}
+	if store.Len() != 2 {
+		t.Errorf("Expected 2 permanent redirects, got %d", store.Len())
+	}
+	if got := store.Resolve("http://example.com/old"); got != "https://example.com/new" {
+		t.Errorf("Expected the chain to be followed to https://example.com/new, got %q", got)
+	}
+	if got := store.Resolve("https://example.com/new"); got != "https://example.com/new" {
+		t.Errorf("Expected the temporary redirect to be ignored, got %q", got)
+	}
+}
+
+// TestResolve tests short URLs, fragments, unknown URLs and loops.
+func TestResolve(t *testing.T) {
+	store, _ := NewStore("", 0)
+	store.Remember("https://old.example", "https://new.example/")
+	store.Remember("https://a.example/", "https://b.example/")
+	store.Remember("https://b.example/", "https://a.example/")
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"old.example", "https://new.example/"},
+		{"https://old.example#top", "https://new.example/"},
+		{"https://unknown.example/page", "https://unknown.example/page"},
+		{"https://a.example/", "https://b.example/"},
+	}
+	for _, tt := range tests {
+		if got := store.Resolve(tt.url); got != tt.want {
+			t.Errorf("Resolve(%q): expected %q, got %q", tt.url, tt.want, got)
+		}
+	}
+}
+
+// TestStorePersistence tests that redirects are saved as they are learnt and loaded on reopening.
+func TestStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redirects.tsv")
+	store, err := NewStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Remember("https://example.com/a", "https://example.com/b")
+	store.Remember("https://example.com/a", "https://example.com/c")
+	store.Remember("https://example.com/a", "https://example.com/c") // Unchanged, not written again
+
+	content, _ := os.ReadFile(path)
+	if lines := strings.Count(string(content), "\n"); lines != 2 {
+		t.Errorf("Expected 2 lines in the file, got %d: %q", lines, content)
+	}
+
+	reopened, err := NewStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Resolve("https://example.com/a"); got != "https://example.com/c" {
+		t.Errorf("Expected the latest redirect after reopening, got %q", got)
+	}
+}
+
+// TestStoreMaxEntries tests that new redirects are ignored once the store is full.
+func TestStoreMaxEntries(t *testing.T) {
+	store, _ := NewStore("", 1)
+	store.Remember("https://example.com/a", "https://example.com/b")
+	store.Remember("https://example.com/c", "https://example.com/d")
+	store.Remember("https://example.com/a", "https://example.com/e")
+	if store.Len() != 1 {
+		t.Errorf("Expected 1 redirect, got %d", store.Len())
+	}
+	if got := store.Resolve("https://example.com/a"); got != "https://example.com/e" {
+		t.Errorf("Expected existing redirects to still be updated, got %q", got)
+	}
+}
+
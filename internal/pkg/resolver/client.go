@@ -0,0 +1,366 @@
+package resolver
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	resolvConfPath     = "/etc/resolv.conf"
+	hostsPath          = "/etc/hosts"
+	hostsCheckInterval = 5 * time.Second // How often the hosts file is checked for changes
+	maxPacketSize      = 4096
+)
+
+// The name server's answer did not fit in a UDP packet
+var errTruncated = errors.New("DNS response truncated")
+
+// Sends A and AAAA queries straight to the name servers so that the TTLs of
+// the answers are known, which the standard library resolver does not expose.
+// Hosts pinned in the hosts file are answered from it first. Names with fewer
+// dots than resolv.conf's ndots, which the search list applies to, and
+// anything the servers cannot answer, are left to the system resolver.
+type dnsClient struct {
+	servers []string
+	hosts   *hostsFile // Nil to skip the hosts file
+	ndots   int        // At least 1, so single label names such as "localhost" always are
+	timeout time.Duration
+}
+
+// The addresses pinned in a hosts file, reloaded when the file changes
+type hostsFile struct {
+	path    string
+	mutex   sync.Mutex
+	checked time.Time
+	modTime time.Time
+	size    int64
+	table   map[string][]net.IP
+}
+
+// Reads the name servers listed in resolv.conf
+func systemNameServers(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" && net.ParseIP(fields[1]) != nil {
+			servers = append(servers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	return servers
+}
+
+// Reads the ndots option of resolv.conf, 1 if it is not set
+func systemNdots(path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		return 1
+	}
+	defer file.Close()
+
+	ndots := 1
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "options" {
+			continue
+		}
+		for _, option := range fields[1:] {
+			if value, found := strings.CutPrefix(option, "ndots:"); found {
+				if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+					ndots = min(n, 15) // The limit resolv.conf puts on it
+				}
+			}
+		}
+	}
+	return ndots
+}
+
+// Resolves a host, returning its IPv4 addresses before its IPv6 ones and the
+// shortest TTL among the answers
+func (client *dnsClient) lookup(context context.Context, host string) ([]net.IP, time.Duration, error) {
+	if ips := client.hosts.lookup(host); len(ips) > 0 {
+		return ips, 0, nil
+	}
+	if len(client.servers) == 0 || strings.Count(strings.TrimSuffix(host, "."), ".") < max(client.ndots, 1) {
+		return systemLookup(context, host)
+	}
+	name, err := dnsmessage.NewName(host + ".")
+	if err != nil {
+		return nil, 0, &net.DNSError{Err: "invalid host name", Name: host, IsNotFound: true}
+	}
+
+	var lastErr error
+	for _, server := range client.servers {
+		ips, ttl, err := client.query(context, server, name)
+		var dnsError *net.DNSError
+		if err == nil || (errors.As(err, &dnsError) && dnsError.IsNotFound) {
+			return ips, ttl, err
+		}
+		lastErr = err
+		if context.Err() != nil {
+			break
+		}
+	}
+	if errors.Is(lastErr, errTruncated) {
+		return systemLookup(context, host)
+	}
+	return nil, 0, &net.DNSError{Err: lastErr.Error(), Name: host, IsTemporary: true, IsTimeout: context.Err() != nil}
+}
+
+// Asks one server for both address types at once
+func (client *dnsClient) query(context context.Context, server string, name dnsmessage.Name) ([]net.IP, time.Duration, error) {
+	type answer struct {
+		ips []net.IP
+		ttl time.Duration
+		err error
+	}
+	answers := make(chan answer, 2)
+	for _, queryType := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		go func(queryType dnsmessage.Type) {
+			ips, ttl, err := client.exchange(context, server, name, queryType)
+			answers <- answer{ips, ttl, err}
+		}(queryType)
+	}
+	first, second := <-answers, <-answers
+	if len(second.ips) > 0 && (len(first.ips) == 0 || second.ips[0].To4() != nil) {
+		first, second = second, first
+	}
+
+	// One address family answering is enough
+	switch {
+	case len(first.ips) > 0 || len(second.ips) > 0:
+		ips := append(first.ips, second.ips...)
+		ttl := first.ttl
+		if len(second.ips) > 0 && second.ttl < ttl {
+			ttl = second.ttl
+		}
+		return ips, ttl, nil
+	case first.err != nil && !isNotFound(first.err):
+		return nil, 0, first.err
+	case second.err != nil && !isNotFound(second.err):
+		return nil, 0, second.err
+	}
+	ttl := first.ttl
+	if second.ttl > 0 && (ttl == 0 || second.ttl < ttl) {
+		ttl = second.ttl
+	}
+	return nil, ttl, &net.DNSError{Err: "no such host", Name: strings.TrimSuffix(name.String(), "."), IsNotFound: true}
+}
+
+// Sends one query over UDP. A name that does not exist, or has no records of
+// this type, is returned as a not found error along with the negative TTL
+// from the SOA record.
+func (client *dnsClient) exchange(parent context.Context, server string, name dnsmessage.Name, queryType dnsmessage.Type) ([]net.IP, time.Duration, error) {
+	context, cancel := context.WithTimeout(parent, client.timeout)
+	defer cancel()
+
+	id := uint16(rand.Intn(1 << 16))
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: queryType, Class: dnsmessage.ClassINET}},
+	}
+	packet, err := query.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var dialer net.Dialer
+	connection, err := dialer.DialContext(context, "udp", server)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer connection.Close()
+	if deadline, ok := context.Deadline(); ok {
+		connection.SetDeadline(deadline)
+	}
+	if _, err := connection.Write(packet); err != nil {
+		return nil, 0, err
+	}
+
+	buffer := make([]byte, maxPacketSize)
+	for {
+		length, err := connection.Read(buffer)
+		if err != nil {
+			return nil, 0, err
+		}
+		var parser dnsmessage.Parser
+		header, err := parser.Start(buffer[:length])
+		if err != nil || header.ID != id || !header.Response {
+			continue // Not the answer to our query
+		}
+		return parseAnswer(&parser, header, name)
+	}
+}
+
+// Collects the addresses and TTLs from a response
+func parseAnswer(parser *dnsmessage.Parser, header dnsmessage.Header, name dnsmessage.Name) ([]net.IP, time.Duration, error) {
+	if header.Truncated {
+		return nil, 0, errTruncated
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, 0, err
+	}
+	host := strings.TrimSuffix(name.String(), ".")
+	switch header.RCode {
+	case dnsmessage.RCodeSuccess, dnsmessage.RCodeNameError:
+	default:
+		return nil, 0, &net.DNSError{Err: "server misbehaving: " + header.RCode.String(), Name: host, IsTemporary: true}
+	}
+
+	var ips []net.IP
+	var ttl uint32
+	for {
+		answerHeader, err := parser.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		switch answerHeader.Type {
+		case dnsmessage.TypeA:
+			resource, err := parser.AResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			ips = append(ips, net.IP(resource.A[:]))
+		case dnsmessage.TypeAAAA:
+			resource, err := parser.AAAAResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			ips = append(ips, net.IP(resource.AAAA[:]))
+		default:
+			// CNAMEs lead to the addresses that follow them
+			if err := parser.SkipAnswer(); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		if ttl == 0 || answerHeader.TTL < ttl {
+			ttl = answerHeader.TTL
+		}
+	}
+	if len(ips) > 0 {
+		return ips, time.Duration(ttl) * time.Second, nil
+	}
+
+	// Negative answers are cached for the SOA's TTL or minimum, whichever is lower (RFC 2308)
+	var negativeTTL uint32
+	for {
+		authorityHeader, err := parser.AuthorityHeader()
+		if err != nil {
+			break
+		}
+		if authorityHeader.Type != dnsmessage.TypeSOA {
+			parser.SkipAuthority()
+			continue
+		}
+		soa, err := parser.SOAResource()
+		if err != nil {
+			break
+		}
+		negativeTTL = min(authorityHeader.TTL, soa.MinTTL)
+		break
+	}
+	return nil, time.Duration(negativeTTL) * time.Second, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// Creates a hosts file that is read on first use
+func newHostsFile(path string) *hostsFile {
+	return &hostsFile{path: path}
+}
+
+// Returns the addresses the hosts file pins a host to, IPv4 first
+func (hosts *hostsFile) lookup(host string) []net.IP {
+	if hosts == nil {
+		return nil
+	}
+	hosts.mutex.Lock()
+	defer hosts.mutex.Unlock()
+
+	if now := time.Now(); now.Sub(hosts.checked) >= hostsCheckInterval {
+		hosts.checked = now
+		hosts.reload()
+	}
+	return hosts.table[strings.ToLower(strings.TrimSuffix(host, "."))]
+}
+
+// Parses the file again if it changed since it was last read. Must be called
+// with the mutex held.
+func (hosts *hostsFile) reload() {
+	info, err := os.Stat(hosts.path)
+	if err != nil {
+		hosts.table, hosts.modTime, hosts.size = nil, time.Time{}, 0
+		return
+	}
+	if hosts.table != nil && info.ModTime().Equal(hosts.modTime) && info.Size() == hosts.size {
+		return
+	}
+	file, err := os.Open(hosts.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	table := make(map[string][]net.IP)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		ip := net.ParseIP(fields[0]) // Scoped addresses such as fe80::1%lo0 are skipped
+		if ip == nil {
+			continue
+		}
+		if ipv4 := ip.To4(); ipv4 != nil {
+			ip = ipv4
+		}
+		for _, name := range fields[1:] {
+			name = strings.ToLower(strings.TrimSuffix(name, "."))
+			table[name] = append(table[name], ip)
+		}
+	}
+	for _, ips := range table {
+		sort.SliceStable(ips, func(i, j int) bool { return ips[i].To4() != nil && ips[j].To4() == nil })
+	}
+	hosts.table, hosts.modTime, hosts.size = table, info.ModTime(), info.Size()
+}
+
+// Falls back to the system resolver, whose answers carry no TTL
+func systemLookup(context context.Context, host string) ([]net.IP, time.Duration, error) {
+	addresses, err := net.DefaultResolver.LookupIPAddr(context, host)
+	if err != nil {
+		return nil, 0, err
+	}
+	ips := make([]net.IP, 0, len(addresses))
+	for _, address := range addresses {
+		ips = append(ips, address.IP)
+	}
+	return ips, 0, nil
+}
+
+func isNotFound(err error) bool {
+	var dnsError *net.DNSError
+	return errors.As(err, &dnsError) && dnsError.IsNotFound
+}
@@ -0,0 +1,204 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Starts a UDP name server answering from a table of records. Names that are
+// not in the table get NXDOMAIN with an SOA record.
+func newTestNameServer(t *testing.T, records map[string][]dnsmessage.Resource) string {
+	connection, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { connection.Close() })
+
+	go func() {
+		buffer := make([]byte, maxPacketSize)
+		for {
+			length, address, err := connection.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buffer[:length]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			question := query.Questions[0]
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.Header.ID, Response: true, RecursionAvailable: true},
+				Questions: query.Questions,
+			}
+			resources, exists := records[question.Name.String()]
+			if !exists {
+				response.Header.RCode = dnsmessage.RCodeNameError
+				response.Authorities = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("example."), Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 3600},
+					Body: &dnsmessage.SOAResource{
+						NS:     dnsmessage.MustNewName("ns.example."),
+						MBox:   dnsmessage.MustNewName("admin.example."),
+						MinTTL: 120,
+					},
+				}}
+			}
+			for _, resource := range resources {
+				if resource.Header.Type == question.Type || resource.Header.Type == dnsmessage.TypeCNAME {
+					response.Answers = append(response.Answers, resource)
+				}
+			}
+			packet, err := response.Pack()
+			if err == nil {
+				connection.WriteTo(packet, address)
+			}
+		}
+	}()
+	return connection.LocalAddr().String()
+}
+
+func aRecord(name string, ttl uint32, ip [4]byte) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.AResource{A: ip},
+	}
+}
+
+func aaaaRecord(name string, ttl uint32, ip net.IP) dnsmessage.Resource {
+	var address [16]byte
+	copy(address[:], ip.To16())
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeAAAA, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.AAAAResource{AAAA: address},
+	}
+}
+
+// TestClientLookup tests that addresses and the lowest TTL are read from A and AAAA answers.
+func TestClientLookup(t *testing.T) {
+	cname := dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("www.example."), Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET, TTL: 900},
+		Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("web.example.")},
+	}
+	server := newTestNameServer(t, map[string][]dnsmessage.Resource{
+		"www.example.": {
+			cname,
+			aRecord("web.example.", 300, [4]byte{192, 0, 2, 1}),
+			aaaaRecord("web.example.", 600, net.ParseIP("2001:db8::1")),
+		},
+	})
+	client := &dnsClient{servers: []string{server}, timeout: time.Second}
+
+	ips, ttl, err := client.lookup(context.Background(), "www.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []net.IP{net.ParseIP("192.0.2.1").To4(), net.ParseIP("2001:db8::1")}
+	if len(ips) != 2 || !ips[0].Equal(expected[0]) || !ips[1].Equal(expected[1]) {
+		t.Errorf("Expected %v, got %v", expected, ips)
+	}
+	if ttl != 300 * time.Second {
+		t.Errorf("Expected the lowest TTL of 300s, got %v", ttl)
+	}
+}
+
+// TestClientNotFound tests that NXDOMAIN is reported as not found with the SOA's negative TTL.
+func TestClientNotFound(t *testing.T) {
+	server := newTestNameServer(t, nil)
+	client := &dnsClient{servers: []string{server}, timeout: time.Second}
+
+	_, ttl, err := client.lookup(context.Background(), "missing.example")
+	var dnsError *net.DNSError
+	if !errors.As(err, &dnsError) || !dnsError.IsNotFound {
+		t.Fatalf("Expected a not found error, got %v", err)
+	}
+	if ttl != 120 * time.Second {
+		t.Errorf("Expected the SOA minimum of 120s, got %v", ttl)
+	}
+}
+
+// TestClientServerFailover tests that an unresponsive server is skipped and errors are temporary.
+func TestClientServerFailover(t *testing.T) {
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	working := newTestNameServer(t, map[string][]dnsmessage.Resource{
+		"up.example.": {aRecord("up.example.", 60, [4]byte{192, 0, 2, 7})},
+	})
+
+	client := &dnsClient{servers: []string{silent.LocalAddr().String(), working}, timeout: 100 * time.Millisecond}
+	ips, _, err := client.lookup(context.Background(), "up.example")
+	if err != nil || len(ips) != 1 {
+		t.Errorf("Expected the second server to answer, got %v, %v", ips, err)
+	}
+
+	client.servers = client.servers[:1]
+	_, _, err = client.lookup(context.Background(), "up.example")
+	var dnsError *net.DNSError
+	if !errors.As(err, &dnsError) || !dnsError.IsTemporary || dnsError.IsNotFound {
+		t.Errorf("Expected a temporary error, got %v", err)
+	}
+}
+
+// TestClientHostsFile tests that hosts pinned in the hosts file are answered
+// from it rather than by the name servers.
+func TestClientHostsFile(t *testing.T) {
+	server := newTestNameServer(t, map[string][]dnsmessage.Resource{
+		"pinned.example.": {aRecord("pinned.example.", 60, [4]byte{192, 0, 2, 1})},
+		"other.example.":  {aRecord("other.example.", 60, [4]byte{192, 0, 2, 2})},
+	})
+	path := filepath.Join(t.TempDir(), "hosts")
+	content := "# comment\n127.0.0.1 localhost\n2001:db8::10 pinned.example\n192.0.2.10 Pinned.Example pinned # override\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	client := &dnsClient{servers: []string{server}, hosts: newHostsFile(path), timeout: time.Second}
+
+	ips, _, err := client.lookup(context.Background(), "pinned.example")
+	expected := []net.IP{net.ParseIP("192.0.2.10").To4(), net.ParseIP("2001:db8::10")}
+	if err != nil || len(ips) != 2 || !ips[0].Equal(expected[0]) || !ips[1].Equal(expected[1]) {
+		t.Errorf("Expected the pinned addresses %v, got %v, %v", expected, ips, err)
+	}
+	ips, _, err = client.lookup(context.Background(), "other.example")
+	if err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("192.0.2.2")) {
+		t.Errorf("Expected other hosts to be sent to the name server, got %v, %v", ips, err)
+	}
+}
+
+// TestSystemNameServers tests that name servers are read from resolv.conf.
+func TestSystemNameServers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	content := "# comment\nsearch example.com\nnameserver 10.0.0.53\nnameserver 2001:db8::53\nnameserver bogus\noptions ndots:1\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"10.0.0.53:53", "[2001:db8::53]:53"}
+	if servers := systemNameServers(path); !reflect.DeepEqual(servers, expected) {
+		t.Errorf("Expected %v, got %v", expected, servers)
+	}
+	if servers := systemNameServers(filepath.Join(t.TempDir(), "missing")); servers != nil {
+		t.Errorf("Expected no servers for a missing file, got %v", servers)
+	}
+}
+
+// TestSystemNdots tests that the ndots option is read from resolv.conf.
+func TestSystemNdots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	if err := os.WriteFile(path, []byte("search example.com\noptions timeout:2 ndots:3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ndots := systemNdots(path); ndots != 3 {
+		t.Errorf("Expected ndots of 3, got %d", ndots)
+	}
+	if ndots := systemNdots(filepath.Join(t.TempDir(), "missing")); ndots != 1 {
+		t.Errorf("Expected ndots of 1 for a missing file, got %d", ndots)
+	}
+}
@@ -0,0 +1,390 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Settings for the caching resolver
+type Config struct {
+	Servers           []string      // Name servers as host:port, read from /etc/resolv.conf when empty
+	MinTTL            time.Duration // Answers are cached for at least this long
+	MaxTTL            time.Duration // and at most this long, whatever their TTL says
+	NegativeTTL       time.Duration // Upper bound for caching hosts that do not exist
+	Timeout           time.Duration // Time allowed for one lookup, across all servers
+	MaxConcurrent     int           // Lookups sent to the name servers at once
+	MaxEntries        int           // Expired entries are dropped beyond this many
+	PrefetchWorkers   int           // Goroutines resolving prefetched hosts
+	PrefetchQueueSize int           // Prefetch requests beyond this many are dropped
+	Dialer            *net.Dialer   // Used by DialContext once a host is resolved
+}
+
+// Returns the configuration used by the crawler
+func DefaultConfig() Config {
+	return Config{
+		MinTTL:            30 * time.Second,
+		MaxTTL:            1 * time.Hour,
+		NegativeTTL:       5 * time.Minute,
+		Timeout:           5 * time.Second,
+		MaxConcurrent:     32,
+		MaxEntries:        100000,
+		PrefetchWorkers:   4,
+		PrefetchQueueSize: 1000,
+		Dialer:            &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second},
+	}
+}
+
+// The addresses of a host, with how long they may be used for
+type Result struct {
+	IPs     []net.IP
+	Expires time.Time
+	Cached  bool // Answered without waiting on a name server
+}
+
+// Counters describing how the resolver has been used
+type Stats struct {
+	Lookups      int64         // Queries answered, from the cache or not
+	Hits         int64         // Answered from a fresh cache entry
+	NegativeHits int64         // Answered from a cached "no such host"
+	Misses       int64         // Sent to the name servers
+	Failures     int64         // Lookups that failed, including hosts that do not exist
+	Prefetches   int64         // Hosts resolved ahead of time
+	LookupTime   time.Duration // Total time spent waiting on name servers
+}
+
+// Resolves a host with A and AAAA queries, returning how long the answer may be cached
+type lookupFunc func(context context.Context, host string) ([]net.IP, time.Duration, error)
+
+type cacheEntry struct {
+	ips     []net.IP
+	err     error // Set for hosts that do not exist
+	expires time.Time
+}
+
+// A lookup in progress that other callers for the same host wait on
+type pendingLookup struct {
+	done  chan struct{}
+	entry cacheEntry
+}
+
+// A DNS resolver that caches answers for as long as their TTL allows, shares
+// lookups between concurrent callers and limits how many queries are in flight.
+type Resolver struct {
+	config    Config
+	lookup    lookupFunc
+	mutex     sync.Mutex
+	cache     map[string]cacheEntry
+	pending   map[string]*pendingLookup
+	slots     chan struct{}
+	prefetch  chan string
+	cancel    context.CancelFunc
+	waitGroup sync.WaitGroup
+
+	lookups      atomic.Int64
+	hits         atomic.Int64
+	negativeHits atomic.Int64
+	misses       atomic.Int64
+	failures     atomic.Int64
+	prefetches   atomic.Int64
+	lookupNanos  atomic.Int64
+}
+
+// Creates a resolver and starts its prefetch workers. Close stops them.
+func NewResolver(config Config) *Resolver {
+	defaults := DefaultConfig()
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = defaults.MaxConcurrent
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.Dialer == nil {
+		config.Dialer = defaults.Dialer
+	}
+	if len(config.Servers) == 0 {
+		config.Servers = systemNameServers(resolvConfPath)
+	}
+
+	context, cancel := context.WithCancel(context.Background())
+	resolver := &Resolver{
+		config:   config,
+		cache:    make(map[string]cacheEntry),
+		pending:  make(map[string]*pendingLookup),
+		slots:    make(chan struct{}, config.MaxConcurrent),
+		prefetch: make(chan string, max(config.PrefetchQueueSize, 1)),
+		cancel:   cancel,
+	}
+	client := &dnsClient{
+		servers: config.Servers,
+		hosts:   newHostsFile(hostsPath),
+		ndots:   systemNdots(resolvConfPath),
+		timeout: config.Timeout,
+	}
+	resolver.lookup = client.lookup
+
+	for i := 0; i < config.PrefetchWorkers; i++ {
+		resolver.waitGroup.Add(1)
+		go resolver.prefetchWorker(context)
+	}
+	return resolver
+}
+
+// Stops the prefetch workers
+func (resolver *Resolver) Close() {
+	resolver.cancel()
+	resolver.waitGroup.Wait()
+}
+
+// Resolves a host to its addresses, from the cache when possible. Errors
+// are *net.DNSError, with IsNotFound set for hosts that do not exist.
+func (resolver *Resolver) Resolve(context context.Context, host string) (Result, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if ip := net.ParseIP(host); ip != nil {
+		return Result{IPs: []net.IP{ip}, Cached: true}, nil
+	}
+	resolver.lookups.Add(1)
+
+	resolver.mutex.Lock()
+	if entry, exists := resolver.cache[host]; exists && time.Now().Before(entry.expires) {
+		resolver.mutex.Unlock()
+		if entry.err != nil {
+			resolver.negativeHits.Add(1)
+			resolver.failures.Add(1)
+			return Result{}, entry.err
+		}
+		resolver.hits.Add(1)
+		return Result{IPs: entry.ips, Expires: entry.expires, Cached: true}, nil
+	}
+	if pending, exists := resolver.pending[host]; exists {
+		resolver.mutex.Unlock()
+		return resolver.wait(context, pending)
+	}
+	pending := &pendingLookup{done: make(chan struct{})}
+	resolver.pending[host] = pending
+	resolver.mutex.Unlock()
+
+	resolver.misses.Add(1)
+	go resolver.run(host, pending)
+	return resolver.wait(context, pending)
+}
+
+// Resolves a host to its addresses as strings, matching net.Resolver.LookupHost
+func (resolver *Resolver) LookupHost(context context.Context, host string) ([]string, error) {
+	result, err := resolver.Resolve(context, host)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, len(result.IPs))
+	for i, ip := range result.IPs {
+		addresses[i] = ip.String()
+	}
+	return addresses, nil
+}
+
+// Queues a host to be resolved in the background so a later lookup is
+// answered from the cache. Hosts already cached are skipped and requests
+// are dropped when the queue is full.
+func (resolver *Resolver) Prefetch(host string) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || net.ParseIP(host) != nil || resolver.isCached(host) {
+		return
+	}
+	select {
+	case resolver.prefetch <- host:
+	default:
+	}
+}
+
+// Caches addresses resolved elsewhere, e.g. by another process, unless a
+// longer lived answer is already cached
+func (resolver *Resolver) Seed(host string, addresses []string, expires time.Time) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if len(addresses) == 0 || !time.Now().Before(expires) {
+		return
+	}
+	ips := make([]net.IP, 0, len(addresses))
+	for _, address := range addresses {
+		if ip := net.ParseIP(address); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return
+	}
+
+	resolver.mutex.Lock()
+	defer resolver.mutex.Unlock()
+	if existing, exists := resolver.cache[host]; exists && existing.err == nil && existing.expires.After(expires) {
+		return
+	}
+	resolver.store(host, cacheEntry{ips: ips, expires: expires})
+}
+
+// Connects to an address, resolving its host through the cache. Each of the
+// host's addresses is tried in turn until one accepts the connection.
+func (resolver *Resolver) DialContext(context context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return resolver.config.Dialer.DialContext(context, network, address)
+	}
+
+	result, err := resolver.Resolve(context, host)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	var lastErr error
+	for _, ip := range result.IPs {
+		if (network == "tcp4" && ip.To4() == nil) || (network == "tcp6" && ip.To4() != nil) {
+			continue
+		}
+		connection, err := resolver.config.Dialer.DialContext(context, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return connection, nil
+		}
+		lastErr = err
+		if context.Err() != nil {
+			break
+		}
+	}
+	if lastErr == nil {
+		lastErr = &net.OpError{Op: "dial", Net: network, Err: fmt.Errorf("no %s address for %s", network, host)}
+	}
+	return nil, lastErr
+}
+
+// Returns the resolver's counters
+func (resolver *Resolver) Stats() Stats {
+	return Stats{
+		Lookups:      resolver.lookups.Load(),
+		Hits:         resolver.hits.Load(),
+		NegativeHits: resolver.negativeHits.Load(),
+		Misses:       resolver.misses.Load(),
+		Failures:     resolver.failures.Load(),
+		Prefetches:   resolver.prefetches.Load(),
+		LookupTime:   time.Duration(resolver.lookupNanos.Load()),
+	}
+}
+
+// Summarises the counters for logging
+func (stats Stats) String() string {
+	hitRate := 0.0
+	if stats.Lookups > 0 {
+		hitRate = float64(stats.Hits + stats.NegativeHits) / float64(stats.Lookups) * 100
+	}
+	average := time.Duration(0)
+	if stats.Misses > 0 {
+		average = stats.LookupTime / time.Duration(stats.Misses)
+	}
+	return fmt.Sprintf("%d lookups, %.1f%% cached, %d failed, %d prefetched, %v average lookup",
+		stats.Lookups, hitRate, stats.Failures, stats.Prefetches, average.Round(time.Millisecond))
+}
+
+// Waits for a pending lookup, or for the caller to give up
+func (resolver *Resolver) wait(context context.Context, pending *pendingLookup) (Result, error) {
+	select {
+	case <-pending.done:
+	case <-context.Done():
+		return Result{}, &net.DNSError{Err: context.Err().Error(), IsTimeout: true}
+	}
+	if pending.entry.err != nil {
+		resolver.failures.Add(1)
+		return Result{}, pending.entry.err
+	}
+	return Result{IPs: pending.entry.ips, Expires: pending.entry.expires}, nil
+}
+
+// Performs a lookup once a query slot is free and caches the answer. It runs
+// independently of the callers so that one giving up does not fail the others.
+func (resolver *Resolver) run(host string, pending *pendingLookup) {
+	resolver.slots <- struct{}{}
+	start := time.Now()
+	context, cancel := context.WithTimeout(context.Background(), resolver.config.Timeout)
+	ips, ttl, err := resolver.lookup(context, host)
+	cancel()
+	<-resolver.slots
+	resolver.lookupNanos.Add(int64(time.Since(start)))
+
+	entry := cacheEntry{ips: ips, err: err}
+	var dnsError *net.DNSError
+	switch {
+	case err == nil:
+		entry.expires = time.Now().Add(clamp(ttl, resolver.config.MinTTL, resolver.config.MaxTTL))
+	case errors.As(err, &dnsError) && dnsError.IsNotFound:
+		entry.expires = time.Now().Add(clamp(ttl, resolver.config.MinTTL, resolver.config.NegativeTTL))
+	}
+
+	resolver.mutex.Lock()
+	if !entry.expires.IsZero() {
+		resolver.store(host, entry)
+	}
+	delete(resolver.pending, host)
+	resolver.mutex.Unlock()
+
+	pending.entry = entry
+	close(pending.done)
+}
+
+// Adds an entry to the cache, dropping expired entries when it is full.
+// Must be called with the mutex held.
+func (resolver *Resolver) store(host string, entry cacheEntry) {
+	if resolver.config.MaxEntries > 0 && len(resolver.cache) >= resolver.config.MaxEntries {
+		now := time.Now()
+		for cachedHost, cached := range resolver.cache {
+			if now.After(cached.expires) {
+				delete(resolver.cache, cachedHost)
+			}
+		}
+		if len(resolver.cache) >= resolver.config.MaxEntries {
+			return
+		}
+	}
+	resolver.cache[host] = entry
+}
+
+// Reports whether a fresh answer for the host is cached or on its way
+func (resolver *Resolver) isCached(host string) bool {
+	resolver.mutex.Lock()
+	defer resolver.mutex.Unlock()
+	if _, exists := resolver.pending[host]; exists {
+		return true
+	}
+	entry, exists := resolver.cache[host]
+	return exists && time.Now().Before(entry.expires)
+}
+
+// Resolves queued hosts until the resolver is closed
+func (resolver *Resolver) prefetchWorker(context context.Context) {
+	defer resolver.waitGroup.Done()
+	for {
+		select {
+		case <-context.Done():
+			return
+		case host := <-resolver.prefetch:
+			if resolver.isCached(host) {
+				continue
+			}
+			resolver.prefetches.Add(1)
+			resolver.Resolve(context, host)
+		}
+	}
+}
+
+// Limits a TTL to the range [low, high]
+func clamp(ttl, low, high time.Duration) time.Duration {
+	if high > 0 && ttl > high {
+		ttl = high
+	}
+	if ttl < low {
+		ttl = low
+	}
+	return ttl
+}
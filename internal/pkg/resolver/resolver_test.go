@@ -0,0 +1,263 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Creates a resolver whose lookups are answered by the given function
+func newTestResolver(t *testing.T, config Config, lookup lookupFunc) *Resolver {
+	config.Servers = []string{"127.0.0.1:53"}
+	resolver := NewResolver(config)
+	resolver.lookup = lookup
+	t.Cleanup(resolver.Close)
+	return resolver
+}
+
+// TestResolveCachesForTTL tests that answers are reused until their TTL runs out.
+func TestResolveCachesForTTL(t *testing.T) {
+	var calls atomic.Int32
+	config := DefaultConfig()
+	config.MinTTL = 0
+	resolver := newTestResolver(t, config, func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		calls.Add(1)
+		return []net.IP{net.ParseIP("10.0.0.1")}, 50 * time.Millisecond, nil
+	})
+
+	first, err := resolver.Resolve(context.Background(), "Example.com.")
+	if err != nil || first.Cached || !first.IPs[0].Equal(net.ParseIP("10.0.0.1")) {
+		t.Fatalf("Unexpected first answer %+v, %v", first, err)
+	}
+	second, err := resolver.Resolve(context.Background(), "example.com")
+	if err != nil || !second.Cached {
+		t.Errorf("Expected a cached answer, got %+v, %v", second, err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 lookup, got %d", calls.Load())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := resolver.Resolve(context.Background(), "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected the expired answer to be looked up again, got %d lookups", calls.Load())
+	}
+
+	stats := resolver.Stats()
+	if stats.Lookups != 3 || stats.Hits != 1 || stats.Misses != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+// TestResolveClampsTTL tests that TTLs are kept between the minimum and maximum.
+func TestResolveClampsTTL(t *testing.T) {
+	config := DefaultConfig()
+	config.MinTTL = time.Minute
+	config.MaxTTL = time.Hour
+	ttl := time.Duration(0)
+	resolver := newTestResolver(t, config, func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		return []net.IP{net.ParseIP("10.0.0.1")}, ttl, nil
+	})
+
+	result, _ := resolver.Resolve(context.Background(), "short.example")
+	if remaining := time.Until(result.Expires); remaining < 59 * time.Second || remaining > time.Minute {
+		t.Errorf("Expected a TTL of 0 to be raised to a minute, expires in %v", remaining)
+	}
+	ttl = 48 * time.Hour
+	result, _ = resolver.Resolve(context.Background(), "long.example")
+	if remaining := time.Until(result.Expires); remaining > time.Hour {
+		t.Errorf("Expected a TTL of 2 days to be capped at an hour, expires in %v", remaining)
+	}
+}
+
+// TestResolveNegativeCache tests that missing hosts are cached but temporary failures are not.
+func TestResolveNegativeCache(t *testing.T) {
+	var calls atomic.Int32
+	resolver := newTestResolver(t, DefaultConfig(), func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		calls.Add(1)
+		if host == "missing.example" {
+			return nil, 10 * time.Minute, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return nil, 0, &net.DNSError{Err: "server misbehaving", Name: host, IsTemporary: true}
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := resolver.Resolve(context.Background(), "missing.example")
+		var dnsError *net.DNSError
+		if !errors.As(err, &dnsError) || !dnsError.IsNotFound {
+			t.Fatalf("Expected a not found error, got %v", err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected the missing host to be cached, got %d lookups", calls.Load())
+	}
+	entry := resolver.cache["missing.example"]
+	if remaining := time.Until(entry.expires); remaining > DefaultConfig().NegativeTTL {
+		t.Errorf("Expected the negative TTL to be capped, expires in %v", remaining)
+	}
+
+	resolver.Resolve(context.Background(), "flaky.example")
+	resolver.Resolve(context.Background(), "flaky.example")
+	if calls.Load() != 3 {
+		t.Errorf("Expected temporary failures not to be cached, got %d lookups", calls.Load())
+	}
+	if stats := resolver.Stats(); stats.NegativeHits != 1 || stats.Failures != 4 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+// TestResolveSharesLookups tests that concurrent callers for one host share a single lookup.
+func TestResolveSharesLookups(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	resolver := newTestResolver(t, DefaultConfig(), func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		calls.Add(1)
+		<-release
+		return []net.IP{net.ParseIP("10.0.0.1")}, time.Minute, nil
+	})
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if _, err := resolver.Resolve(context.Background(), "shared.example"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	waitGroup.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 lookup, got %d", calls.Load())
+	}
+}
+
+// TestResolveConcurrencyLimit tests that no more than MaxConcurrent lookups run at once.
+func TestResolveConcurrencyLimit(t *testing.T) {
+	var running, peak atomic.Int32
+	config := DefaultConfig()
+	config.MaxConcurrent = 2
+	resolver := newTestResolver(t, config, func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		current := running.Add(1)
+		for {
+			previous := peak.Load()
+			if current <= previous || peak.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return []net.IP{net.ParseIP("10.0.0.1")}, time.Minute, nil
+	})
+
+	var waitGroup sync.WaitGroup
+	for _, host := range []string{"a.example", "b.example", "c.example", "d.example", "e.example"} {
+		waitGroup.Add(1)
+		go func(host string) {
+			defer waitGroup.Done()
+			resolver.Resolve(context.Background(), host)
+		}(host)
+	}
+	waitGroup.Wait()
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 concurrent lookups, saw %d", peak.Load())
+	}
+}
+
+// TestResolveCallerTimeout tests that a caller giving up does not fail the lookup for others.
+func TestResolveCallerTimeout(t *testing.T) {
+	resolver := newTestResolver(t, DefaultConfig(), func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		time.Sleep(30 * time.Millisecond)
+		return []net.IP{net.ParseIP("10.0.0.1")}, time.Minute, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Millisecond)
+	defer cancel()
+	if _, err := resolver.Resolve(ctx, "slow.example"); err == nil {
+		t.Error("Expected the impatient caller to time out")
+	}
+	if _, err := resolver.Resolve(context.Background(), "slow.example"); err != nil {
+		t.Errorf("Expected the lookup to complete for a patient caller, got %v", err)
+	}
+}
+
+// TestPrefetch tests that prefetched hosts are answered from the cache.
+func TestPrefetch(t *testing.T) {
+	resolver := newTestResolver(t, DefaultConfig(), func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		return []net.IP{net.ParseIP("10.0.0.1")}, time.Minute, nil
+	})
+
+	resolver.Prefetch("queued.example")
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		resolver.mutex.Lock()
+		_, cached := resolver.cache["queued.example"]
+		resolver.mutex.Unlock()
+		if cached {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	result, err := resolver.Resolve(context.Background(), "queued.example")
+	if err != nil || !result.Cached {
+		t.Errorf("Expected a cached answer after prefetching, got %+v, %v", result, err)
+	}
+	if resolver.Stats().Prefetches != 1 {
+		t.Errorf("Expected 1 prefetch, got %+v", resolver.Stats())
+	}
+}
+
+// TestSeed tests that addresses resolved elsewhere are served until they expire.
+func TestSeed(t *testing.T) {
+	resolver := newTestResolver(t, DefaultConfig(), func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		return nil, 0, errors.New("should not be looked up")
+	})
+
+	resolver.Seed("seeded.example", []string{"10.0.0.2", "not an ip"}, time.Now().Add(time.Minute))
+	result, err := resolver.Resolve(context.Background(), "seeded.example")
+	if err != nil || len(result.IPs) != 1 || !result.IPs[0].Equal(net.ParseIP("10.0.0.2")) {
+		t.Errorf("Expected the seeded address, got %+v, %v", result, err)
+	}
+
+	resolver.Seed("stale.example", []string{"10.0.0.3"}, time.Now().Add(-time.Second))
+	if resolver.isCached("stale.example") {
+		t.Error("Expected already expired addresses to be ignored")
+	}
+}
+
+// TestDialContext tests that dialing a host name connects to its resolved address.
+func TestDialContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		if connection, err := listener.Accept(); err == nil {
+			connection.Close()
+		}
+	}()
+
+	resolver := newTestResolver(t, DefaultConfig(), func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		// Nothing listens on 127.0.0.2, so the second address is tried
+		return []net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.1")}, time.Minute, nil
+	})
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	connection, err := resolver.DialContext(context.Background(), "tcp", net.JoinHostPort("service.example", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	connection.Close()
+	if connection.RemoteAddr().(*net.TCPAddr).IP.String() != "127.0.0.1" {
+		t.Errorf("Expected to connect to 127.0.0.1, got %v", connection.RemoteAddr())
+	}
+}
@@ -0,0 +1,111 @@
+package retry
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"webcrawler/internal/pkg/types"
+)
+
+// A URL that failed permanently after exhausting its retries
+type DeadLetter struct {
+	URL        string
+	Category   types.ErrorCategory
+	StatusCode int
+	Attempts   int
+	FailedAt   time.Time
+	Error      string
+}
+
+// Append-only, tab separated file of dead letters
+type DeadLetterFile struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// Creates a dead-letter file handle. The file is created on first write.
+func NewDeadLetterFile(path string) *DeadLetterFile {
+	return &DeadLetterFile{path: path}
+}
+
+// Appends a dead letter to the file
+func (deadLetters *DeadLetterFile) Write(letter DeadLetter) error {
+	deadLetters.mutex.Lock()
+	defer deadLetters.mutex.Unlock()
+
+	file, err := os.OpenFile(deadLetters.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening dead-letter file: %v", err)
+	}
+	defer file.Close()
+
+	line := strings.Join([]string{
+		sanitizeField(letter.URL),
+		string(letter.Category),
+		strconv.Itoa(letter.StatusCode),
+		strconv.Itoa(letter.Attempts),
+		letter.FailedAt.UTC().Format(time.RFC3339),
+		sanitizeField(letter.Error),
+	}, "\t")
+	_, err = file.WriteString(line + "\n")
+	return err
+}
+
+// Reads every dead letter and empties the file so the URLs can be re-injected
+func (deadLetters *DeadLetterFile) Drain() ([]DeadLetter, error) {
+	deadLetters.mutex.Lock()
+	defer deadLetters.mutex.Unlock()
+
+	file, err := os.Open(deadLetters.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening dead-letter file: %v", err)
+	}
+
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if letter, ok := parseDeadLetter(scanner.Text()); ok {
+			letters = append(letters, letter)
+		}
+	}
+	file.Close()
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading dead-letter file: %v", err)
+	}
+
+	if err := os.Truncate(deadLetters.path, 0); err != nil {
+		return nil, fmt.Errorf("error truncating dead-letter file: %v", err)
+	}
+	return letters, nil
+}
+
+// Parses one line of the dead-letter file, skipping malformed lines
+func parseDeadLetter(line string) (DeadLetter, bool) {
+	fields := strings.Split(line, "\t")
+	if len(fields) < 6 || fields[0] == "" {
+		return DeadLetter{}, false
+	}
+	statusCode, _ := strconv.Atoi(fields[2])
+	attempts, _ := strconv.Atoi(fields[3])
+	failedAt, _ := time.Parse(time.RFC3339, fields[4])
+	return DeadLetter{
+		URL:        fields[0],
+		Category:   types.ErrorCategory(fields[1]),
+		StatusCode: statusCode,
+		Attempts:   attempts,
+		FailedAt:   failedAt,
+		Error:      fields[5],
+	}, true
+}
+
+// Keeps a field on a single line without breaking the column layout
+func sanitizeField(value string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(value)
+}
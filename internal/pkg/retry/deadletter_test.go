@@ -0,0 +1,54 @@
+package retry
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+	"webcrawler/internal/pkg/types"
+)
+
+// Dead letters should round-trip through the file and be removed on drain.
+func TestDeadLetterWriteAndDrain(t *testing.T) {
+	deadLetters := NewDeadLetterFile(filepath.Join(t.TempDir(), "dead_letters.tsv"))
+
+	letters, err := deadLetters.Drain()
+	if err != nil || len(letters) != 0 {
+		t.Fatalf("expected empty drain of missing file, got %v, %v", letters, err)
+	}
+
+	failedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	written := DeadLetter{
+		URL:        "example.com/page",
+		Category:   types.ErrorHTTP5xx,
+		StatusCode: 503,
+		Attempts:   4,
+		FailedAt:   failedAt,
+		Error:      "received response code:\t503\nagain",
+	}
+	if err := deadLetters.Write(written); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	if err := deadLetters.Write(DeadLetter{URL: "other.com", Category: types.ErrorTimeout}); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	letters, err = deadLetters.Drain()
+	if err != nil {
+		t.Fatalf("unexpected error draining: %v", err)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("expected 2 dead letters, got %d", len(letters))
+	}
+	got := letters[0]
+	if got.URL != written.URL || got.Category != written.Category || got.StatusCode != 503 ||
+		got.Attempts != 4 || !got.FailedAt.Equal(failedAt) {
+		t.Errorf("dead letter did not round-trip: %+v", got)
+	}
+	if got.Error != "received response code: 503 again" {
+		t.Errorf("expected sanitised error, got %q", got.Error)
+	}
+
+	if letters, _ := deadLetters.Drain(); len(letters) != 0 {
+		t.Errorf("expected file to be empty after drain, got %d letters", len(letters))
+	}
+}
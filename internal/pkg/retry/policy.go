@@ -0,0 +1,90 @@
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"webcrawler/internal/pkg/types"
+)
+
+// Controls how many times a URL is retried and how long to wait between attempts
+type Policy struct {
+	MaxAttempts    int           // Total attempts per URL, including the first
+	BaseDelay      time.Duration // Delay before the first retry
+	MaxDelay       time.Duration // Upper bound on the backoff delay
+	MaxRetryAfter  time.Duration // Upper bound on a server supplied Retry-After
+	JitterFraction float64       // Random spread applied to each delay, from 0 to 1
+}
+
+var randFloat = rand.Float64
+
+// Returns the policy used by the administrator
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:    4,
+		BaseDelay:      30 * time.Second,
+		MaxDelay:       30 * time.Minute,
+		MaxRetryAfter:  6 * time.Hour,
+		JitterFraction: 0.2,
+	}
+}
+
+// Reports whether a failed fetch is worth retrying, along with any
+// server requested delay from a Retry-After header. An unavailable robots.txt
+// carries the time until it is fetched again in the same header.
+func Classify(result types.FetchResult) (bool, time.Duration) {
+	switch result.ErrorCategory {
+	case types.ErrorTimeout, types.ErrorReset, types.ErrorProxy:
+		return true, 0
+	case types.ErrorRobotsUnavailable:
+		return true, ParseRetryAfter(http.Header(result.Header).Get("Retry-After"), time.Now())
+	case types.ErrorHTTP4xx, types.ErrorHTTP5xx:
+		switch result.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			return true, ParseRetryAfter(http.Header(result.Header).Get("Retry-After"), time.Now())
+		}
+	}
+	return false, 0
+}
+
+// Parses a Retry-After header given either as seconds or as an HTTP date.
+// Returns zero if the header is missing or invalid.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// Returns the delay before the given retry attempt (1 for the first retry).
+// The exponential backoff is jittered, and never shorter than retryAfter.
+func (policy Policy) Backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := float64(policy.BaseDelay) * math.Pow(2, float64(attempt - 1))
+	delay = math.Min(delay, float64(policy.MaxDelay))
+
+	if policy.JitterFraction > 0 {
+		spread := delay * policy.JitterFraction
+		delay += spread * (2 * randFloat() - 1)
+	}
+
+	if retryAfter > policy.MaxRetryAfter {
+		retryAfter = policy.MaxRetryAfter
+	}
+	return max(time.Duration(delay), retryAfter)
+}
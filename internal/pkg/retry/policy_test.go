@@ -0,0 +1,106 @@
+package retry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	"webcrawler/internal/pkg/types"
+)
+
+// Checks which failures are considered retryable.
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     types.FetchResult
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{"timeout", types.FetchResult{ErrorCategory: types.ErrorTimeout}, true, 0},
+		{"reset", types.FetchResult{ErrorCategory: types.ErrorReset}, true, 0},
+		{"robots unavailable", types.FetchResult{ErrorCategory: types.ErrorRobotsUnavailable}, true, 0},
+		{
+			"robots unavailable with retry-after",
+			types.FetchResult{
+				ErrorCategory: types.ErrorRobotsUnavailable,
+				Header:        http.Header{"Retry-After": []string{"600"}},
+			},
+			true, 10 * time.Minute,
+		},
+		{"proxy", types.FetchResult{ErrorCategory: types.ErrorProxy}, true, 0},
+		{"too many requests", types.FetchResult{ErrorCategory: types.ErrorHTTP4xx, StatusCode: 429}, true, 0},
+		{
+			"unavailable with retry-after",
+			types.FetchResult{
+				ErrorCategory: types.ErrorHTTP5xx,
+				StatusCode:    503,
+				Header:        http.Header{"Retry-After": []string{"120"}},
+			},
+			true, 2 * time.Minute,
+		},
+		{"not found", types.FetchResult{ErrorCategory: types.ErrorHTTP4xx, StatusCode: 404}, false, 0},
+		{"internal error", types.FetchResult{ErrorCategory: types.ErrorHTTP5xx, StatusCode: 500}, false, 0},
+		{"dns", types.FetchResult{ErrorCategory: types.ErrorDNS}, false, 0},
+		{"robots", types.FetchResult{ErrorCategory: types.ErrorRobots}, false, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			retryable, retryAfter := Classify(tc.result)
+			if retryable != tc.retryable {
+				t.Errorf("expected retryable=%v, got %v", tc.retryable, retryable)
+			}
+			if retryAfter != tc.retryAfter {
+				t.Errorf("expected retryAfter=%v, got %v", tc.retryAfter, retryAfter)
+			}
+		})
+	}
+}
+
+// Checks both forms of the Retry-After header.
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if got := ParseRetryAfter("30", now); got != 30 * time.Second {
+		t.Errorf("expected 30s, got %v", got)
+	}
+	date := now.Add(5 * time.Minute).Format(http.TimeFormat)
+	if got := ParseRetryAfter(date, now); got != 5 * time.Minute {
+		t.Errorf("expected 5m, got %v", got)
+	}
+	for _, invalid := range []string{"", "-5", "soon", now.Add(-time.Hour).Format(http.TimeFormat)} {
+		if got := ParseRetryAfter(invalid, now); got != 0 {
+			t.Errorf("expected 0 for %q, got %v", invalid, got)
+		}
+	}
+}
+
+// Checks exponential growth, the delay cap, jitter bounds and Retry-After.
+func TestBackoff(t *testing.T) {
+	original := randFloat
+	defer func() { randFloat = original }()
+	randFloat = func() float64 { return 0.5 } // No jitter
+
+	policy := Policy{BaseDelay: time.Second, MaxDelay: 10 * time.Second, MaxRetryAfter: time.Minute}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second}
+	for i, want := range expected {
+		if got := policy.Backoff(i + 1, 0); got != want {
+			t.Errorf("attempt %d: expected %v, got %v", i + 1, want, got)
+		}
+	}
+
+	if got := policy.Backoff(1, 30 * time.Second); got != 30 * time.Second {
+		t.Errorf("expected Retry-After to win, got %v", got)
+	}
+	if got := policy.Backoff(1, time.Hour); got != time.Minute {
+		t.Errorf("expected Retry-After capped at 1m, got %v", got)
+	}
+
+	policy.JitterFraction = 0.5
+	randFloat = func() float64 { return 0 }
+	if got := policy.Backoff(1, 0); got != 500 * time.Millisecond {
+		t.Errorf("expected lower jitter bound 500ms, got %v", got)
+	}
+	randFloat = func() float64 { return 1 }
+	if got := policy.Backoff(1, 0); got != 1500 * time.Millisecond {
+		t.Errorf("expected upper jitter bound 1.5s, got %v", got)
+	}
+}
@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// A URL waiting to be retried
+type Entry struct {
+	URL     string
+	Attempt int
+	ReadyAt time.Time
+}
+
+// Holds URLs until their backoff delay has elapsed
+type Scheduler struct {
+	mutex   sync.Mutex
+	entries entryHeap
+}
+
+// Creates an empty scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Schedules a URL to become ready after the given delay
+func (scheduler *Scheduler) Schedule(url string, attempt int, delay time.Duration) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	heap.Push(&scheduler.entries, Entry{URL: url, Attempt: attempt, ReadyAt: time.Now().Add(delay)})
+}
+
+// Removes and returns every entry that is ready at the given time, earliest first
+func (scheduler *Scheduler) Ready(now time.Time) []Entry {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	var ready []Entry
+	for len(scheduler.entries) > 0 && !scheduler.entries[0].ReadyAt.After(now) {
+		ready = append(ready, heap.Pop(&scheduler.entries).(Entry))
+	}
+	return ready
+}
+
+// Returns the number of URLs waiting to be retried
+func (scheduler *Scheduler) Len() int {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	return len(scheduler.entries)
+}
+
+// Min-heap of entries ordered by ReadyAt
+type entryHeap []Entry
+
+func (entries entryHeap) Len() int           { return len(entries) }
+func (entries entryHeap) Less(i, j int) bool { return entries[i].ReadyAt.Before(entries[j].ReadyAt) }
+func (entries entryHeap) Swap(i, j int)      { entries[i], entries[j] = entries[j], entries[i] }
+
+func (entries *entryHeap) Push(item any) {
+	*entries = append(*entries, item.(Entry))
+}
+
+func (entries *entryHeap) Pop() any {
+	old := *entries
+	item := old[len(old) - 1]
+	*entries = old[:len(old) - 1]
+	return item
+}
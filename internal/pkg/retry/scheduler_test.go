@@ -0,0 +1,29 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+// Entries should only be returned once ready, in order of readiness.
+func TestSchedulerReady(t *testing.T) {
+	scheduler := NewScheduler()
+	scheduler.Schedule("later.com", 2, time.Hour)
+	scheduler.Schedule("second.com", 1, 2 * time.Millisecond)
+	scheduler.Schedule("first.com", 1, time.Millisecond)
+
+	if ready := scheduler.Ready(time.Now().Add(-time.Minute)); len(ready) != 0 {
+		t.Fatalf("expected no ready entries, got %v", ready)
+	}
+
+	ready := scheduler.Ready(time.Now().Add(time.Second))
+	if len(ready) != 2 {
+		t.Fatalf("expected 2 ready entries, got %d", len(ready))
+	}
+	if ready[0].URL != "first.com" || ready[1].URL != "second.com" {
+		t.Errorf("expected entries in readiness order, got %v", ready)
+	}
+	if scheduler.Len() != 1 {
+		t.Errorf("expected 1 pending entry, got %d", scheduler.Len())
+	}
+}
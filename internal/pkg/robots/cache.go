@@ -0,0 +1,97 @@
+package robots
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// On-disk form of a cached robots.txt
+type persistedEntry struct {
+	Origin     string    `json:"origin"`
+	StatusCode int       `json:"status_code"`
+	Body       string    `json:"body"`
+	FetchedAt  time.Time `json:"fetched_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Loads persisted robots.txt entries, skipping any that have expired
+func (service *Service) load() error {
+	if service.config.CachePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(service.config.CachePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var persisted []persistedEntry
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return fmt.Errorf("error decoding robots cache: %v", err)
+	}
+
+	// Entries are saved most recently used first, so push them to the back in order
+	for _, saved := range persisted {
+		if time.Now().After(saved.ExpiresAt) {
+			continue
+		}
+		if _, exists := service.entries[saved.Origin]; exists {
+			continue
+		}
+		robotsEntry := &entry{origin: saved.Origin}
+		service.apply(robotsEntry, saved.StatusCode, []byte(saved.Body), saved.FetchedAt, saved.ExpiresAt)
+		service.entries[saved.Origin] = service.order.PushBack(robotsEntry)
+	}
+	service.evict()
+	return nil
+}
+
+// Writes the cache to disk, most recently used first. Entries that are
+// being fetched at the time are skipped.
+func (service *Service) save() error {
+	if service.config.CachePath == "" {
+		return nil
+	}
+
+	service.mutex.Lock()
+	snapshot := make([]*entry, 0, service.order.Len())
+	for element := service.order.Front(); element != nil; element = element.Next() {
+		snapshot = append(snapshot, element.Value.(*entry))
+	}
+	service.mutex.Unlock()
+
+	persisted := make([]persistedEntry, 0, len(snapshot))
+	for _, robotsEntry := range snapshot {
+		if !robotsEntry.mutex.TryLock() {
+			continue
+		}
+		if robotsEntry.loaded {
+			persisted = append(persisted, persistedEntry{
+				Origin:     robotsEntry.origin,
+				StatusCode: robotsEntry.statusCode,
+				Body:       string(robotsEntry.body),
+				FetchedAt:  robotsEntry.fetchedAt,
+				ExpiresAt:  robotsEntry.expiresAt,
+			})
+		}
+		robotsEntry.mutex.Unlock()
+	}
+
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial cache
+	service.saveMutex.Lock()
+	defer service.saveMutex.Unlock()
+	tempPath := service.config.CachePath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, service.config.CachePath)
+}
@@ -0,0 +1,112 @@
+package robots
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"webcrawler/internal/pkg/types"
+)
+
+// Date formats seen in unavailable_after values
+var unavailableAfterLayouts = []string{
+	time.RFC3339,
+	time.RFC1123,
+	time.RFC1123Z,
+	time.RFC850,
+	"2 Jan 2006 15:04:05 MST",
+	"02 Jan 2006 15:04:05 MST",
+	"2006-01-02",
+}
+
+// Applies a robots meta tag's content, or one X-Robots-Tag value without a
+// user agent, to the directives
+func ApplyDirectives(directives *types.RobotsDirectives, value string) {
+	tokens := strings.Split(value, ",")
+	for i := 0; i < len(tokens); i++ {
+		token := strings.TrimSpace(tokens[i])
+		name, argument, _ := strings.Cut(token, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		argument = strings.TrimSpace(argument)
+
+		switch name {
+		case "noindex":
+			directives.NoIndex = true
+		case "nofollow":
+			directives.NoFollow = true
+		case "none":
+			directives.NoIndex = true
+			directives.NoFollow = true
+		case "noarchive", "nocache":
+			directives.NoArchive = true
+		case "nosnippet":
+			directives.NoSnippet = true
+		case "max-snippet":
+			limit, err := strconv.Atoi(argument)
+			if err != nil || limit < -1 {
+				continue
+			}
+			if limit == 0 {
+				directives.NoSnippet = true
+			}
+			if directives.MaxSnippet == nil || limit != -1 && (*directives.MaxSnippet == -1 || limit < *directives.MaxSnippet) {
+				directives.MaxSnippet = &limit
+			}
+		case "unavailable_after":
+			// Some date formats contain commas, so try joining the following tokens
+			for j := i + 1; j <= len(tokens); j++ {
+				if date, ok := parseUnavailableAfter(strings.TrimSpace(strings.Join(append([]string{argument}, tokens[i + 1:j]...), ","))); ok {
+					if directives.UnavailableAfter.IsZero() || date.Before(directives.UnavailableAfter) {
+						directives.UnavailableAfter = date
+					}
+					i = j - 1
+					break
+				}
+			}
+		}
+	}
+}
+
+// Applies the X-Robots-Tag header values meant for every crawler or for the
+// given product token. A value may name its user agent, as in "otherbot: noindex".
+func ApplyHeader(directives *types.RobotsDirectives, values []string, productToken string) {
+	for _, value := range values {
+		agent, rest, found := strings.Cut(value, ":")
+		agent = strings.TrimSpace(agent)
+		if found && !strings.ContainsAny(agent, ", ") && !isDirectiveName(agent) {
+			if !strings.EqualFold(agent, productToken) && !strings.EqualFold(agent, "robots") {
+				continue
+			}
+			value = rest
+		}
+		ApplyDirectives(directives, value)
+	}
+}
+
+// Reports whether a robots meta tag with this name applies to the crawler
+func AppliesToCrawler(metaName, productToken string) bool {
+	metaName = strings.TrimSpace(metaName)
+	return strings.EqualFold(metaName, "robots") || productToken != "" && strings.EqualFold(metaName, productToken)
+}
+
+// Reports whether a page may no longer be shown, either because it says
+// noindex or because its unavailable_after date has passed
+func Unavailable(directives types.RobotsDirectives, now time.Time) bool {
+	return directives.NoIndex || !directives.UnavailableAfter.IsZero() && now.After(directives.UnavailableAfter)
+}
+
+func isDirectiveName(name string) bool {
+	switch strings.ToLower(name) {
+	case "max-snippet", "max-image-preview", "max-video-preview", "unavailable_after":
+		return true
+	}
+	return false
+}
+
+func parseUnavailableAfter(value string) (time.Time, bool) {
+	for _, layout := range unavailableAfterLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
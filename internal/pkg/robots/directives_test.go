@@ -0,0 +1,76 @@
+package robots
+
+import (
+	"testing"
+	"time"
+	"webcrawler/internal/pkg/types"
+)
+
+// TestApplyDirectives tests the supported directives and that the most restrictive values win.
+func TestApplyDirectives(t *testing.T) {
+	var directives types.RobotsDirectives
+	ApplyDirectives(&directives, "NOINDEX, max-snippet:50")
+	ApplyDirectives(&directives, "noarchive, max-snippet:-1, max-snippet:20, max-image-preview:large")
+	if !directives.NoIndex || directives.NoFollow || !directives.NoArchive || directives.NoSnippet {
+		t.Errorf("Unexpected directives %+v", directives)
+	}
+	if directives.MaxSnippet == nil || *directives.MaxSnippet != 20 {
+		t.Errorf("Expected the smallest max-snippet of 20, got %v", directives.MaxSnippet)
+	}
+
+	directives = types.RobotsDirectives{}
+	ApplyDirectives(&directives, "none")
+	ApplyDirectives(&directives, "max-snippet:0")
+	if !directives.NoIndex || !directives.NoFollow || !directives.NoSnippet {
+		t.Errorf("Expected none and max-snippet:0 to set noindex, nofollow and nosnippet, got %+v", directives)
+	}
+}
+
+// TestApplyDirectivesUnavailableAfter tests dates in several formats, including ones containing commas.
+func TestApplyDirectivesUnavailableAfter(t *testing.T) {
+	expected := time.Date(2010, time.June, 25, 15, 0, 0, 0, time.UTC)
+	for _, value := range []string{
+		"unavailable_after: 2010-06-25T15:00:00Z",
+		"noindex, unavailable_after: Friday, 25-Jun-10 15:00:00 UTC, nofollow",
+		"unavailable_after: 25 Jun 2010 15:00:00 UTC",
+	} {
+		var directives types.RobotsDirectives
+		ApplyDirectives(&directives, value)
+		if !directives.UnavailableAfter.Equal(expected) {
+			t.Errorf("%q: expected %v, got %v", value, expected, directives.UnavailableAfter)
+		}
+	}
+
+	var directives types.RobotsDirectives
+	ApplyDirectives(&directives, "unavailable_after: 25 Jun 2010 15:00:00 UTC, nofollow")
+	if !directives.NoFollow {
+		t.Error("Expected the directive after the date to be applied")
+	}
+	if !Unavailable(directives, expected.Add(time.Second)) || Unavailable(directives, expected.Add(-time.Second)) {
+		t.Error("Expected the page to become unavailable after the date")
+	}
+}
+
+// TestApplyHeader tests that X-Robots-Tag values for other crawlers are ignored.
+func TestApplyHeader(t *testing.T) {
+	var directives types.RobotsDirectives
+	ApplyHeader(&directives, []string{
+		"otherbot: noindex",
+		"MyBot: nofollow",
+		"noarchive",
+		"max-snippet: 10",
+	}, "mybot")
+	if directives.NoIndex || !directives.NoFollow || !directives.NoArchive || directives.MaxSnippet == nil || *directives.MaxSnippet != 10 {
+		t.Errorf("Unexpected directives %+v", directives)
+	}
+}
+
+// TestAppliesToCrawler tests which robots meta tag names apply.
+func TestAppliesToCrawler(t *testing.T) {
+	if !AppliesToCrawler("Robots", "mybot") || !AppliesToCrawler("MYBOT", "mybot") {
+		t.Error("Expected robots and the product token to apply")
+	}
+	if AppliesToCrawler("googlebot", "mybot") || AppliesToCrawler("", "") {
+		t.Error("Expected other crawlers' tags not to apply")
+	}
+}
@@ -0,0 +1,82 @@
+package robots
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reads at most limit bytes of robots.txt. If the file is longer, the last
+// partial line is dropped so a cut-off rule is never applied.
+func readLimited(reader io.Reader, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(reader, limit + 1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) <= limit {
+		return body, nil
+	}
+	body = body[:limit]
+	if lastNewline := bytes.LastIndexByte(body, '\n'); lastNewline >= 0 {
+		body = body[:lastNewline + 1]
+	}
+	return body, nil
+}
+
+// Splits a robots.txt line into its lower-cased key and its value, without comments
+func splitLine(line string) (string, string, bool) {
+	if comment := strings.IndexByte(line, '#'); comment >= 0 {
+		line = line[:comment]
+	}
+	key, value, found := strings.Cut(line, ":")
+	if !found {
+		return "", "", false
+	}
+	return strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value), true
+}
+
+// Drops lines that would make the parser reject the whole file, such as
+// rules before any User-agent line or a malformed Crawl-delay. RFC 9309
+// asks crawlers to ignore invalid lines rather than the entire file.
+func sanitize(body []byte) []byte {
+	var cleaned bytes.Buffer
+	seenAgent := false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64 * 1024), len(body) + 1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		key, value, ok := splitLine(line)
+		if ok {
+			switch key {
+			case "user-agent":
+				seenAgent = true
+			case "allow", "disallow":
+				if !seenAgent {
+					continue
+				}
+			case "crawl-delay":
+				if _, err := strconv.ParseFloat(value, 64); !seenAgent || err != nil {
+					continue
+				}
+			}
+		}
+		cleaned.WriteString(line)
+		cleaned.WriteByte('\n')
+	}
+	return cleaned.Bytes()
+}
+
+// Reports whether robots.txt has a User-agent line naming the token exactly
+func declaresAgent(body []byte, token string) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64 * 1024), len(body) + 1)
+	for scanner.Scan() {
+		if key, value, ok := splitLine(scanner.Text()); ok && key == "user-agent" && strings.EqualFold(value, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,374 @@
+package robots
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"webcrawler/internal/pkg/identity"
+
+	"github.com/temoto/robotstxt"
+)
+
+var (
+	ErrCrawlingDisallowed = errors.New("crawling disallowed by robots.txt")
+	ErrRobotsUnavailable  = errors.New("robots.txt unreachable, assuming full disallow")
+	errTooManyRedirects   = errors.New("too many robots.txt redirects")
+)
+
+var (
+	DefaultUserAgent    = identity.Default().UserAgent()
+	DefaultProductToken = identity.DefaultProductToken
+)
+
+// Settings for the robots.txt service
+type Config struct {
+	UserAgent        string        // User-Agent header sent when fetching robots.txt
+	ProductToken     string        // Token matched against User-agent lines (RFC 9309 section 2.2.1)
+	CacheSize        int           // Maximum number of hosts kept in memory
+	CachePath        string        // File the cache is persisted to, empty to disable
+	SaveEvery        int           // Persist after this many robots.txt fetches
+	RefreshAfter     time.Duration // Longest a robots.txt is cached for
+	MinRefresh       time.Duration // Shortest a robots.txt is cached for, whatever its headers say
+	UnavailableRetry time.Duration // How soon an unreachable robots.txt is fetched again
+	MaxStaleServe    time.Duration // How long a good copy is used while robots.txt is unreachable
+	MaxRedirects     int           // Redirect hops followed before robots.txt is treated as unavailable
+	MaxBodySize      int64         // Bytes of robots.txt parsed, the rest is ignored
+	MaxCrawlDelay    time.Duration // Upper bound on a host's Crawl-delay
+	Client           *http.Client  // Client used to fetch robots.txt
+}
+
+// Returns the configuration used by the administrator
+func DefaultConfig() Config {
+	return Config{
+		UserAgent:        DefaultUserAgent,
+		ProductToken:     DefaultProductToken,
+		CacheSize:        50000,
+		SaveEvery:        500,
+		RefreshAfter:     24 * time.Hour,
+		MinRefresh:       5 * time.Minute,
+		UnavailableRetry: 10 * time.Minute,
+		MaxStaleServe:    30 * 24 * time.Hour,
+		MaxRedirects:     5,
+		MaxBodySize:      500 * 1024,
+		MaxCrawlDelay:    30 * time.Second,
+	}
+}
+
+// Cached robots.txt for a single origin
+type entry struct {
+	origin      string
+	mutex       sync.Mutex
+	loaded      bool
+	unavailable bool // Server error or unreachable, so everything is disallowed
+	group       *robotstxt.Group
+	body        []byte
+	statusCode  int
+	crawlDelay  time.Duration
+	fetchedAt   time.Time
+	expiresAt   time.Time
+}
+
+// Fetches, caches and enforces robots.txt for every host the crawler visits.
+// A single service is owned by the administrator, whose host limiter takes
+// each host's Crawl-delay as its floor across all fetcher processes.
+type Service struct {
+	config       Config
+	mutex        sync.Mutex
+	entries      map[string]*list.Element
+	order        *list.List // Most recently used at the front
+	fetchCounter int
+	saveMutex    sync.Mutex
+}
+
+// Creates the service, loading any persisted cache from disk
+func NewService(config Config) (*Service, error) {
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = DefaultUserAgent
+	}
+	if config.ProductToken == "" {
+		config.ProductToken = DefaultProductToken
+	}
+
+	// Work on a copy so the caller's client keeps its own redirect policy
+	client := *config.Client
+	maxRedirects := config.MaxRedirects
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return errTooManyRedirects
+		}
+		return nil
+	}
+	config.Client = &client
+
+	service := &Service{
+		config:  config,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+	if err := service.load(); err != nil {
+		return nil, fmt.Errorf("error while loading robots cache: %v", err)
+	}
+	return service, nil
+}
+
+// Checks whether the URL may be crawled, returning the host's Crawl-delay
+func (service *Service) Allowed(context context.Context, targetURL string) (bool, time.Duration, error) {
+	parsedURL, robotsEntry, err := service.lookup(context, targetURL)
+	if err != nil {
+		return false, 0, err
+	}
+	defer robotsEntry.mutex.Unlock()
+	return !robotsEntry.unavailable && robotsEntry.allows(parsedURL), robotsEntry.crawlDelay, nil
+}
+
+// Checks if crawling is permitted for the given URL without waiting,
+// returning the host's Crawl-delay for the caller to enforce. While robots.txt
+// is unavailable it returns how long until it is fetched again instead.
+func (service *Service) Check(context context.Context, targetURL string) (time.Duration, error) {
+	parsedURL, robotsEntry, err := service.lookup(context, targetURL)
+	if err != nil {
+		return 0, err
+	}
+	defer robotsEntry.mutex.Unlock()
+	err = robotsEntry.permits(parsedURL)
+	if errors.Is(err, ErrRobotsUnavailable) {
+		return max(time.Until(robotsEntry.expiresAt), 0), err
+	}
+	return robotsEntry.crawlDelay, err
+}
+
+// Returns the number of hosts currently cached
+func (service *Service) Len() int {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	return service.order.Len()
+}
+
+// Persists the cache to disk
+func (service *Service) Close() error {
+	return service.save()
+}
+
+// Finds the entry for the URL's origin, fetching robots.txt if it is missing
+// or stale. The entry is returned locked.
+func (service *Service) lookup(context context.Context, targetURL string) (*url.URL, *entry, error) {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" || parsedURL.Host == "" {
+		return nil, nil, fmt.Errorf("invalid URL for robots.txt lookup: %q", targetURL)
+	}
+
+	robotsEntry := service.getEntry(parsedURL.Scheme + "://" + parsedURL.Host)
+	robotsEntry.mutex.Lock()
+
+	if !robotsEntry.loaded || time.Now().After(robotsEntry.expiresAt) {
+		if err := service.fetch(context, robotsEntry); err != nil {
+			robotsEntry.mutex.Unlock()
+			return nil, nil, err
+		}
+		service.countFetch()
+	}
+	return parsedURL, robotsEntry, nil
+}
+
+// Gets or creates the entry for an origin, evicting the least recently used
+func (service *Service) getEntry(origin string) *entry {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	if element, exists := service.entries[origin]; exists {
+		service.order.MoveToFront(element)
+		return element.Value.(*entry)
+	}
+
+	robotsEntry := &entry{origin: origin}
+	service.entries[origin] = service.order.PushFront(robotsEntry)
+	service.evict()
+	return robotsEntry
+}
+
+// Drops least recently used entries beyond the cache size. Must be called with the mutex held.
+func (service *Service) evict() {
+	for service.config.CacheSize > 0 && service.order.Len() > service.config.CacheSize {
+		oldest := service.order.Back()
+		service.order.Remove(oldest)
+		delete(service.entries, oldest.Value.(*entry).origin)
+	}
+}
+
+// Persists the cache every SaveEvery fetches
+func (service *Service) countFetch() {
+	service.mutex.Lock()
+	service.fetchCounter++
+	shouldSave := service.config.SaveEvery > 0 && service.fetchCounter >= service.config.SaveEvery
+	if shouldSave {
+		service.fetchCounter = 0
+	}
+	service.mutex.Unlock()
+
+	if shouldSave {
+		go func() {
+			if err := service.save(); err != nil {
+				fmt.Printf("Error saving robots cache: %v\n", err)
+			}
+		}()
+	}
+}
+
+// Fetches and parses the robots.txt file for the entry's origin following
+// RFC 9309: 4xx means no restrictions, while server errors and network
+// failures mean full disallow until robots.txt can be fetched again.
+// Must be called with the entry locked.
+func (service *Service) fetch(context context.Context, robotsEntry *entry) error {
+	robotsURL := robotsEntry.origin + "/robots.txt"
+
+	req, err := http.NewRequestWithContext(context, "GET", robotsURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", service.config.UserAgent)
+
+	now := time.Now()
+	resp, err := service.config.Client.Do(req)
+	if err != nil {
+		if errors.Is(err, errTooManyRedirects) {
+			// Too many redirects means robots.txt is unavailable, which allows everything
+			service.apply(robotsEntry, http.StatusNotFound, nil, now, now.Add(service.config.RefreshAfter))
+			return nil
+		}
+		service.markUnreachable(robotsEntry, 0, now)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		body, err := readLimited(resp.Body, service.config.MaxBodySize)
+		if err != nil {
+			service.markUnreachable(robotsEntry, 0, now)
+			return nil
+		}
+		service.apply(robotsEntry, resp.StatusCode, body, now, now.Add(service.cacheLifetime(resp.Header, now)))
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+		service.apply(robotsEntry, resp.StatusCode, nil, now, now.Add(service.cacheLifetime(resp.Header, now)))
+	default:
+		// 5xx, 429 and anything unexpected are treated as the server being unreachable
+		service.markUnreachable(robotsEntry, resp.StatusCode, now)
+	}
+	return nil
+}
+
+// Records a failed fetch. A previous good copy keeps being used for a while,
+// otherwise the origin is fully disallowed. Either way it is retried soon.
+func (service *Service) markUnreachable(robotsEntry *entry, statusCode int, now time.Time) {
+	retryAt := now.Add(service.config.UnavailableRetry)
+	if robotsEntry.loaded && !robotsEntry.unavailable && now.Sub(robotsEntry.fetchedAt) < service.config.MaxStaleServe {
+		robotsEntry.expiresAt = retryAt
+		return
+	}
+	service.apply(robotsEntry, statusCode, nil, now, retryAt)
+}
+
+// Parses a robots.txt response into the entry, selecting the group
+// matching our product token and its Crawl-delay
+func (service *Service) apply(robotsEntry *entry, statusCode int, body []byte, fetchedAt, expiresAt time.Time) {
+	var group *robotstxt.Group
+	unavailable := false
+
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		if robots, err := robotstxt.FromBytes(sanitize(body)); err == nil {
+			group = service.findGroup(robots, body)
+		}
+	case statusCode >= 400 && statusCode < 500 && statusCode != http.StatusTooManyRequests:
+		// Unavailable, crawling is unrestricted
+	default:
+		unavailable = true
+	}
+
+	var crawlDelay time.Duration
+	if group != nil && group.CrawlDelay >= 0 {
+		crawlDelay = min(group.CrawlDelay, service.config.MaxCrawlDelay)
+	}
+	robotsEntry.loaded = true
+	robotsEntry.unavailable = unavailable
+	robotsEntry.group = group
+	robotsEntry.body = body
+	robotsEntry.statusCode = statusCode
+	robotsEntry.crawlDelay = crawlDelay
+	robotsEntry.fetchedAt = fetchedAt
+	robotsEntry.expiresAt = expiresAt
+}
+
+// Picks the group whose User-agent matches our product token exactly
+// (case-insensitively), falling back to the "*" group. The library matches
+// by prefix, so only ask it for our token if the file names it.
+func (service *Service) findGroup(robots *robotstxt.RobotsData, body []byte) *robotstxt.Group {
+	token := strings.ToLower(service.config.ProductToken)
+	if declaresAgent(body, token) {
+		return robots.FindGroup(token)
+	}
+	return robots.FindGroup("*")
+}
+
+// Works out how long a robots.txt may be cached from its response headers,
+// bounded by MinRefresh and RefreshAfter
+func (service *Service) cacheLifetime(header http.Header, now time.Time) time.Duration {
+	lifetime := service.config.RefreshAfter
+	cacheControl := strings.ToLower(header.Get("Cache-Control"))
+	maxAgeFound := false
+
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			lifetime, maxAgeFound = 0, true
+		case strings.HasPrefix(directive, "max-age="):
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+				lifetime, maxAgeFound = time.Duration(seconds) * time.Second, true
+			}
+		}
+	}
+	if !maxAgeFound {
+		if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+			lifetime = expires.Sub(now)
+		}
+	}
+	return min(max(lifetime, service.config.MinRefresh), service.config.RefreshAfter)
+}
+
+// Returns why the URL may not be crawled, or nil if it may. Must be called
+// with the entry locked.
+func (robotsEntry *entry) permits(parsedURL *url.URL) error {
+	if robotsEntry.unavailable {
+		return ErrRobotsUnavailable
+	}
+	if !robotsEntry.allows(parsedURL) {
+		return ErrCrawlingDisallowed
+	}
+	return nil
+}
+
+// Reports whether the entry's rules allow the URL's path
+func (robotsEntry *entry) allows(parsedURL *url.URL) bool {
+	if robotsEntry.group == nil {
+		return true
+	}
+	path := parsedURL.EscapedPath()
+	if parsedURL.RawQuery != "" {
+		path += "?" + parsedURL.RawQuery
+	}
+	return robotsEntry.group.Test(path)
+}
@@ -0,0 +1,457 @@
+package robots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestService(t *testing.T, config Config) *Service {
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	return service
+}
+
+// TestInvalidURL tests handling of invalid URLs.
+func TestInvalidURL(t *testing.T) {
+	service := newTestService(t, DefaultConfig())
+	if _, err := service.Check(context.Background(), "invalid url"); err == nil {
+		t.Error("Expected error for invalid URL, got nil")
+	}
+}
+
+// TestNewDomainAddedToCache tests if a new domain is added to the cache.
+func TestNewDomainAddedToCache(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer testServer.Close()
+
+	service := newTestService(t, DefaultConfig())
+	if _, err := service.Check(context.Background(), testServer.URL + "/path"); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := service.entries[testServer.URL]; !exists {
+		t.Errorf("Origin %s not found in cache", testServer.URL)
+	}
+}
+
+// TestDisallowedPath tests that rules for our agent are applied.
+func TestDisallowedPath(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: webcrawler\nDisallow: /private\n\nUser-agent: *\nDisallow: /"))
+	}))
+	defer testServer.Close()
+
+	service := newTestService(t, DefaultConfig())
+	if _, err := service.Check(context.Background(), testServer.URL + "/private/page"); !errors.Is(err, ErrCrawlingDisallowed) {
+		t.Errorf("Expected ErrCrawlingDisallowed, got %v", err)
+	}
+	if _, err := service.Check(context.Background(), testServer.URL + "/public"); err != nil {
+		t.Errorf("Expected /public to be allowed, got %v", err)
+	}
+}
+
+// TestRobotsRefreshAfterExpiry tests robots.txt is fetched again once expired.
+func TestRobotsRefreshAfterExpiry(t *testing.T) {
+	var requestCount atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount.Add(1)
+		w.Write([]byte("User-agent: webcrawler"))
+	}))
+	defer testServer.Close()
+
+	service := newTestService(t, DefaultConfig())
+	testURL := testServer.URL + "/path"
+	service.Check(context.Background(), testURL) // Initial fetch
+	service.Check(context.Background(), testURL) // Cached
+
+	robotsEntry := service.getEntry(testServer.URL)
+	robotsEntry.mutex.Lock()
+	if lifetime := time.Until(robotsEntry.expiresAt); lifetime < 23 * time.Hour || lifetime > 24 * time.Hour {
+		t.Errorf("Expected default 24h lifetime, got %v", lifetime)
+	}
+	robotsEntry.expiresAt = time.Now().Add(-time.Second)
+	robotsEntry.mutex.Unlock()
+
+	service.Check(context.Background(), testURL)
+	if count := requestCount.Load(); count != 2 {
+		t.Errorf("Expected 2 robots.txt fetches, got %d", count)
+	}
+}
+
+// TestRobotsUnreachableDisallows tests that an unreachable robots.txt disallows everything.
+func TestRobotsUnreachableDisallows(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL := testServer.URL
+	testServer.Close()
+
+	service := newTestService(t, DefaultConfig())
+	if _, err := service.Check(context.Background(), closedURL + "/anypath"); !errors.Is(err, ErrRobotsUnavailable) {
+		t.Errorf("Expected ErrRobotsUnavailable, got %v", err)
+	}
+}
+
+// TestRobotsClientErrorAllowsAll tests that any 4xx means no restrictions.
+func TestRobotsClientErrorAllowsAll(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusForbidden, http.StatusUnauthorized, http.StatusGone} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			w.Write([]byte("User-agent: *\nDisallow: /"))
+		}))
+
+		service := newTestService(t, DefaultConfig())
+		if _, err := service.Check(context.Background(), testServer.URL + "/page"); err != nil {
+			t.Errorf("Status %d: expected access to be allowed, got %v", status, err)
+		}
+		testServer.Close()
+	}
+}
+
+// TestRobotsServerErrorDisallowsAndRetries tests that a 5xx disallows everything
+// until a later fetch succeeds.
+func TestRobotsServerErrorDisallowsAndRetries(t *testing.T) {
+	var healthy atomic.Bool
+	var requestCount atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("User-agent: *\nDisallow: /private"))
+	}))
+	defer testServer.Close()
+
+	config := DefaultConfig()
+	config.UnavailableRetry = 50 * time.Millisecond
+	service := newTestService(t, config)
+
+	retryIn, err := service.Check(context.Background(), testServer.URL + "/page")
+	if !errors.Is(err, ErrRobotsUnavailable) {
+		t.Fatalf("Expected ErrRobotsUnavailable, got %v", err)
+	}
+	if retryIn <= 0 || retryIn > config.UnavailableRetry {
+		t.Errorf("Expected the time until robots.txt is fetched again, got %v", retryIn)
+	}
+	if allowed, _, _ := service.Allowed(context.Background(), testServer.URL + "/page"); allowed {
+		t.Error("Expected page to be disallowed while robots.txt is unavailable")
+	}
+	if count := requestCount.Load(); count != 1 {
+		t.Errorf("Expected the failure to be cached until the retry, got %d fetches", count)
+	}
+
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := service.Check(context.Background(), testServer.URL + "/page"); err != nil {
+		t.Errorf("Expected access after robots.txt recovered, got %v", err)
+	}
+	if _, err := service.Check(context.Background(), testServer.URL + "/private"); !errors.Is(err, ErrCrawlingDisallowed) {
+		t.Errorf("Expected recovered rules to apply, got %v", err)
+	}
+}
+
+// TestRobotsServerErrorKeepsGoodCopy tests that a previously fetched robots.txt
+// is still used when the server starts failing.
+func TestRobotsServerErrorKeepsGoodCopy(t *testing.T) {
+	var failing atomic.Bool
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("User-agent: *\nDisallow: /private"))
+	}))
+	defer testServer.Close()
+
+	service := newTestService(t, DefaultConfig())
+	service.Check(context.Background(), testServer.URL + "/")
+
+	robotsEntry := service.getEntry(testServer.URL)
+	robotsEntry.mutex.Lock()
+	robotsEntry.expiresAt = time.Now().Add(-time.Second)
+	robotsEntry.mutex.Unlock()
+	failing.Store(true)
+
+	if _, err := service.Check(context.Background(), testServer.URL + "/page"); err != nil {
+		t.Errorf("Expected cached copy to allow /page, got %v", err)
+	}
+	if _, err := service.Check(context.Background(), testServer.URL + "/private"); !errors.Is(err, ErrCrawlingDisallowed) {
+		t.Errorf("Expected cached copy to disallow /private, got %v", err)
+	}
+}
+
+// TestRobotsRedirects tests that up to five redirects are followed and more
+// are treated as an unavailable robots.txt.
+func TestRobotsRedirects(t *testing.T) {
+	for _, hops := range []int{5, 6} {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/hop/1", http.StatusMovedPermanently)
+		})
+		mux.HandleFunc("/hop/", func(w http.ResponseWriter, r *http.Request) {
+			var hop int
+			fmt.Sscanf(r.URL.Path, "/hop/%d", &hop)
+			if hop < hops {
+				http.Redirect(w, r, fmt.Sprintf("/hop/%d", hop + 1), http.StatusFound)
+				return
+			}
+			w.Write([]byte("User-agent: *\nDisallow: /"))
+		})
+		testServer := httptest.NewServer(mux)
+
+		service := newTestService(t, DefaultConfig())
+		_, err := service.Check(context.Background(), testServer.URL + "/page")
+		if hops == 5 && !errors.Is(err, ErrCrawlingDisallowed) {
+			t.Errorf("Expected rules reached after %d redirects to apply, got %v", hops, err)
+		}
+		if hops == 6 && err != nil {
+			t.Errorf("Expected %d redirects to mean unavailable (allow all), got %v", hops, err)
+		}
+		testServer.Close()
+	}
+}
+
+// TestRobotsParseLimit tests that rules after the first 500 KiB are ignored.
+func TestRobotsParseLimit(t *testing.T) {
+	body := "User-agent: *\nDisallow: /early\n" +
+		strings.Repeat("# padding padding padding padding padding padding\n", 11000) +
+		"Disallow: /late\n"
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer testServer.Close()
+
+	service := newTestService(t, DefaultConfig())
+	if _, err := service.Check(context.Background(), testServer.URL + "/early"); !errors.Is(err, ErrCrawlingDisallowed) {
+		t.Errorf("Expected /early to be disallowed, got %v", err)
+	}
+	if _, err := service.Check(context.Background(), testServer.URL + "/late"); err != nil {
+		t.Errorf("Expected rule beyond 500 KiB to be ignored, got %v", err)
+	}
+}
+
+// TestRobotsCacheHeaders tests that cache headers set the expiry within bounds.
+func TestRobotsCacheHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected time.Duration
+	}{
+		{"max-age", http.Header{"Cache-Control": {"public, max-age=3600"}}, time.Hour},
+		{"max-age below minimum", http.Header{"Cache-Control": {"max-age=0"}}, 5 * time.Minute},
+		{"no-cache", http.Header{"Cache-Control": {"no-cache"}}, 5 * time.Minute},
+		{"max-age above maximum", http.Header{"Cache-Control": {"max-age=604800"}}, 24 * time.Hour},
+		{"expires", http.Header{"Expires": {time.Now().Add(2 * time.Hour).UTC().Format(http.TimeFormat)}}, 2 * time.Hour},
+		{"none", http.Header{}, 24 * time.Hour},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for key, values := range tc.header {
+					w.Header()[key] = values
+				}
+				w.Write([]byte("User-agent: *\nAllow: /"))
+			}))
+			defer testServer.Close()
+
+			service := newTestService(t, DefaultConfig())
+			service.Check(context.Background(), testServer.URL + "/")
+			robotsEntry := service.getEntry(testServer.URL)
+			lifetime := robotsEntry.expiresAt.Sub(robotsEntry.fetchedAt)
+			if diff := lifetime - tc.expected; diff < -2 * time.Second || diff > 2 * time.Second {
+				t.Errorf("Expected lifetime %v, got %v", tc.expected, lifetime)
+			}
+		})
+	}
+}
+
+// TestRobotsProductTokenMatching tests exact, case-insensitive group matching
+// on the product token, independent of the User-Agent header.
+func TestRobotsProductTokenMatching(t *testing.T) {
+	var userAgent atomic.Value
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent.Store(r.UserAgent())
+		w.Write([]byte("User-agent: web\nDisallow: /web-only\n\n" +
+			"User-agent: MyBot\nDisallow: /mybot-only\n\n" +
+			"User-agent: *\nDisallow: /everyone"))
+	}))
+	defer testServer.Close()
+
+	config := DefaultConfig()
+	config.UserAgent = "Mozilla/5.0 (compatible; MyBot/1.0; +https://example.com/bot)"
+	config.ProductToken = "mybot"
+	service := newTestService(t, config)
+
+	if _, err := service.Check(context.Background(), testServer.URL + "/mybot-only"); !errors.Is(err, ErrCrawlingDisallowed) {
+		t.Errorf("Expected MyBot group to apply, got %v", err)
+	}
+	if _, err := service.Check(context.Background(), testServer.URL + "/everyone"); err != nil {
+		t.Errorf("Expected * group to be ignored when MyBot matches, got %v", err)
+	}
+	if got := userAgent.Load(); got != config.UserAgent {
+		t.Errorf("Expected User-Agent header %q, got %q", config.UserAgent, got)
+	}
+
+	// A group that is only a prefix of the token must not match
+	config.ProductToken = "webcrawler"
+	prefixService := newTestService(t, config)
+	if _, err := prefixService.Check(context.Background(), testServer.URL + "/web-only"); err != nil {
+		t.Errorf("Expected prefix group not to match, got %v", err)
+	}
+	if _, err := prefixService.Check(context.Background(), testServer.URL + "/everyone"); !errors.Is(err, ErrCrawlingDisallowed) {
+		t.Errorf("Expected fallback to * group, got %v", err)
+	}
+}
+
+// TestRobotsInvalidLinesIgnored tests that stray lines do not discard the file.
+func TestRobotsInvalidLinesIgnored(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Disallow: /orphan\nUser-agent: *\nCrawl-delay: soon\nDisallow: /private"))
+	}))
+	defer testServer.Close()
+
+	service := newTestService(t, DefaultConfig())
+	if _, err := service.Check(context.Background(), testServer.URL + "/private"); !errors.Is(err, ErrCrawlingDisallowed) {
+		t.Errorf("Expected valid rules to apply despite invalid lines, got %v", err)
+	}
+	if _, err := service.Check(context.Background(), testServer.URL + "/orphan"); err != nil {
+		t.Errorf("Expected rule before any User-agent to be ignored, got %v", err)
+	}
+}
+
+// TestCrawlDelayParsed tests that fractional Crawl-delay values are read. The
+// delay itself is enforced by the administrator's host limiter.
+func TestCrawlDelayParsed(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nCrawl-delay: 0.1"))
+	}))
+	defer testServer.Close()
+
+	service := newTestService(t, DefaultConfig())
+	allowed, crawlDelay, err := service.Allowed(context.Background(), testServer.URL + "/")
+	if err != nil || !allowed || crawlDelay != 100 * time.Millisecond {
+		t.Fatalf("Expected allowed with 100ms delay, got %v %v %v", allowed, crawlDelay, err)
+	}
+}
+
+// TestCheck tests that Check reports the Crawl-delay and refusals without waiting.
+func TestCheck(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nCrawl-delay: 2\nDisallow: /private"))
+	}))
+	defer testServer.Close()
+
+	service := newTestService(t, DefaultConfig())
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if crawlDelay, err := service.Check(context.Background(), testServer.URL + "/page"); err != nil || crawlDelay != 2 * time.Second {
+			t.Fatalf("Expected a 2s Crawl-delay, got %v %v", crawlDelay, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Check not to wait, took %v", elapsed)
+	}
+	if _, err := service.Check(context.Background(), testServer.URL + "/private"); !errors.Is(err, ErrCrawlingDisallowed) {
+		t.Errorf("Expected ErrCrawlingDisallowed, got %v", err)
+	}
+}
+
+// TestCrawlDelayCapped tests that excessive Crawl-delay values are capped.
+func TestCrawlDelayCapped(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nCrawl-delay: 3600"))
+	}))
+	defer testServer.Close()
+
+	service := newTestService(t, DefaultConfig())
+	_, crawlDelay, _ := service.Allowed(context.Background(), testServer.URL + "/")
+	if crawlDelay != DefaultConfig().MaxCrawlDelay {
+		t.Errorf("Expected crawl delay capped at %v, got %v", DefaultConfig().MaxCrawlDelay, crawlDelay)
+	}
+}
+
+// TestConcurrentAccess tests concurrent access to the same domain only fetches once.
+func TestConcurrentAccess(t *testing.T) {
+	var requestCount atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount.Add(1)
+		w.Write([]byte("User-agent: *"))
+	}))
+	defer testServer.Close()
+
+	service := newTestService(t, DefaultConfig())
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			service.Check(context.Background(), testServer.URL + "/path")
+		}()
+	}
+	wg.Wait()
+	if count := requestCount.Load(); count != 1 {
+		t.Errorf("Expected 1 robots.txt fetch, got %d", count)
+	}
+}
+
+// TestLRUEviction tests that the least recently used origin is evicted.
+func TestLRUEviction(t *testing.T) {
+	config := DefaultConfig()
+	config.CacheSize = 2
+	service := newTestService(t, config)
+
+	service.getEntry("http://a.com")
+	service.getEntry("http://b.com")
+	service.getEntry("http://a.com") // a is now most recent
+	service.getEntry("http://c.com")
+
+	if service.Len() != 2 {
+		t.Fatalf("Expected 2 cached origins, got %d", service.Len())
+	}
+	if _, exists := service.entries["http://b.com"]; exists {
+		t.Error("Expected b.com to be evicted")
+	}
+	if _, exists := service.entries["http://a.com"]; !exists {
+		t.Error("Expected a.com to be kept")
+	}
+}
+
+// TestPersistence tests that the cache survives a restart without refetching.
+func TestPersistence(t *testing.T) {
+	var requestCount atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount.Add(1)
+		w.Write([]byte("User-agent: *\nDisallow: /secret"))
+	}))
+	defer testServer.Close()
+
+	config := DefaultConfig()
+	config.CachePath = filepath.Join(t.TempDir(), "robots_cache.json")
+
+	service := newTestService(t, config)
+	service.Check(context.Background(), testServer.URL + "/")
+	if err := service.Close(); err != nil {
+		t.Fatalf("Failed to save cache: %v", err)
+	}
+
+	restored := newTestService(t, config)
+	if restored.Len() != 1 {
+		t.Fatalf("Expected 1 restored origin, got %d", restored.Len())
+	}
+	if _, err := restored.Check(context.Background(), testServer.URL + "/secret"); !errors.Is(err, ErrCrawlingDisallowed) {
+		t.Errorf("Expected restored rules to disallow /secret, got %v", err)
+	}
+	if count := requestCount.Load(); count != 1 {
+		t.Errorf("Expected restored cache to avoid refetching, got %d fetches", count)
+	}
+}
@@ -0,0 +1,168 @@
+package structured
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"webcrawler/internal/pkg/types"
+)
+
+const (
+	SourceJSONLD    = "json-ld"
+	SourceMicrodata = "microdata"
+	SourceRDFa      = "rdfa"
+)
+
+// Reported for a JSON-LD block that is valid JSON but describes nothing
+var ErrNoEntities = errors.New("no typed entities in JSON-LD")
+
+// Parses a JSON-LD block into entities. A block may hold one object, an
+// array of them or a @graph, and objects without a @type are skipped unless
+// they hold a @graph. Blocks wrapped in HTML comments or CDATA sections, as
+// older pages do, are unwrapped first.
+func ParseJSONLD(content string) ([]types.Entity, error) {
+	content = strings.TrimSpace(content)
+	for _, wrapper := range [][2]string{{"<!--", "-->"}, {"<![CDATA[", "]]>"}, {"//<![CDATA[", "//]]>"}} {
+		if strings.HasPrefix(content, wrapper[0]) && strings.HasSuffix(content, wrapper[1]) {
+			content = strings.TrimSpace(content[len(wrapper[0]) : len(content) - len(wrapper[1])])
+		}
+	}
+
+	var document interface{}
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid JSON-LD: %w", err)
+	}
+
+	var entities []types.Entity
+	var collect func(value interface{})
+	collect = func(value interface{}) {
+		switch value := value.(type) {
+		case []interface{}:
+			for _, item := range value {
+				collect(item)
+			}
+		case map[string]interface{}:
+			if graph, exists := value["@graph"]; exists {
+				collect(graph)
+			}
+			if _, typed := value["@type"]; typed {
+				entities = append(entities, jsonLDEntity(value))
+			}
+		}
+	}
+	collect(document)
+
+	if len(entities) == 0 {
+		return nil, ErrNoEntities
+	}
+	return entities, nil
+}
+
+// Converts a JSON-LD node object into an entity
+func jsonLDEntity(object map[string]interface{}) types.Entity {
+	entity := types.Entity{Source: SourceJSONLD}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch key {
+		case "@type":
+			for _, value := range jsonLDStrings(object[key]) {
+				entity.Types = append(entity.Types, LocalName(value))
+			}
+		case "@id":
+			if id, ok := object[key].(string); ok {
+				entity.ID = id
+			}
+		case "@context", "@graph", "@language", "@vocab", "@base":
+		default:
+			if strings.HasPrefix(key, "@") {
+				continue
+			}
+			for _, value := range jsonLDValues(object[key]) {
+				addProperty(&entity, LocalName(key), value)
+			}
+		}
+	}
+	return entity
+}
+
+// Converts a JSON-LD value into property values. Value objects give their
+// @value, objects holding only an @id are references to entities described
+// elsewhere, and other objects are nested entities.
+func jsonLDValues(value interface{}) []types.Property {
+	switch value := value.(type) {
+	case []interface{}:
+		var properties []types.Property
+		for _, item := range value {
+			properties = append(properties, jsonLDValues(item)...)
+		}
+		return properties
+	case map[string]interface{}:
+		if inner, exists := value["@value"]; exists {
+			return jsonLDValues(inner)
+		}
+		if list, exists := value["@list"]; exists {
+			return jsonLDValues(list)
+		}
+		entity := jsonLDEntity(value)
+		return []types.Property{{Entity: &entity}}
+	case string:
+		return []types.Property{{Text: strings.TrimSpace(value)}}
+	case json.Number:
+		return []types.Property{{Text: value.String()}}
+	case bool:
+		return []types.Property{{Text: strconv.FormatBool(value)}}
+	}
+	return nil
+}
+
+// Reads a value that may be a single string or an array of them
+func jsonLDStrings(value interface{}) []string {
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Adds a property value to an entity, skipping empty text
+func addProperty(entity *types.Entity, name string, value types.Property) {
+	if name == "" || value.Entity == nil && value.Text == "" {
+		return
+	}
+	if entity.Properties == nil {
+		entity.Properties = make(map[string][]types.Property)
+	}
+	entity.Properties[name] = append(entity.Properties[name], value)
+}
+
+// Strips the vocabulary from a type or property name, whether it is given as
+// a URL such as "https://schema.org/Article" or a prefixed name such as
+// "schema:Article"
+func LocalName(name string) string {
+	name = strings.TrimSpace(name)
+	if index := strings.LastIndexAny(name, "/#"); index >= 0 {
+		return name[index + 1:]
+	}
+	if index := strings.LastIndexByte(name, ':'); index >= 0 {
+		return name[index + 1:]
+	}
+	return name
+}
@@ -0,0 +1,70 @@
+package structured
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestParseJSONLDGraph tests that entities in a @graph are found, with nested objects and references kept.
+func TestParseJSONLDGraph(t *testing.T) {
+	entities, err := ParseJSONLD(`<!-- {
+		"@context": "https://schema.org",
+		"@graph": [
+			{"@type": "NewsArticle", "@id": "#article", "headline": "Bridge opens", "wordCount": 450,
+			 "author": [{"@type": "Person", "name": "Jane Doe"}, "John Smith"],
+			 "publisher": {"@id": "#org"}},
+			{"@type": ["Organization", "schema:NewsMediaOrganization"], "@id": "#org", "name": {"@value": "Daily News"}}
+		]
+	} -->`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entities) != 2 {
+		t.Fatalf("Expected 2 entities, got %d: %+v", len(entities), entities)
+	}
+
+	article := entities[0]
+	if article.Source != SourceJSONLD || article.ID != "#article" || len(article.Types) != 1 || article.Types[0] != "NewsArticle" {
+		t.Errorf("Unexpected article %+v", article)
+	}
+	if article.Properties["wordCount"][0].Text != "450" {
+		t.Errorf("Expected the word count as text, got %+v", article.Properties["wordCount"])
+	}
+	authors := article.Properties["author"]
+	if len(authors) != 2 || authors[0].Entity == nil || authors[0].Entity.Properties["name"][0].Text != "Jane Doe" || authors[1].Text != "John Smith" {
+		t.Errorf("Unexpected authors %+v", authors)
+	}
+	if publisher := article.Properties["publisher"]; len(publisher) != 1 || publisher[0].Entity == nil || publisher[0].Entity.ID != "#org" {
+		t.Errorf("Expected a reference to the publisher, got %+v", publisher)
+	}
+
+	organization := entities[1]
+	if len(organization.Types) != 2 || organization.Types[1] != "NewsMediaOrganization" || organization.Properties["name"][0].Text != "Daily News" {
+		t.Errorf("Unexpected organization %+v", organization)
+	}
+}
+
+// TestParseJSONLDInvalid tests that malformed blocks and blocks without typed entities are rejected.
+func TestParseJSONLDInvalid(t *testing.T) {
+	if _, err := ParseJSONLD(`{"@type": "Article",}`); err == nil {
+		t.Error("Expected an error for malformed JSON")
+	}
+	if _, err := ParseJSONLD(`{"@context": "https://schema.org"}`); !errors.Is(err, ErrNoEntities) {
+		t.Errorf("Expected ErrNoEntities, got %v", err)
+	}
+}
+
+// TestLocalName tests that vocabularies are stripped from URLs and prefixed names.
+func TestLocalName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"https://schema.org/Article": "Article",
+		"http://schema.org/Product":  "Product",
+		"schema:Person":              "Person",
+		"og:title":                   "title",
+		"name":                       "name",
+	} {
+		if got := LocalName(name); got != expected {
+			t.Errorf("%q: expected %q, got %q", name, expected, got)
+		}
+	}
+}
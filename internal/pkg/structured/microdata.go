@@ -0,0 +1,178 @@
+package structured
+
+import (
+	"net/url"
+	"strings"
+	"webcrawler/internal/pkg/types"
+	"golang.org/x/net/html"
+)
+
+// How one HTML syntax for structured data marks up entities and properties
+type syntax struct {
+	source     string
+	isScope    func(node *html.Node) bool
+	types      func(node *html.Node) []string
+	id         func(node *html.Node) string
+	properties func(node *html.Node) []string
+}
+
+// Microdata: itemscope starts an entity, itemtype and itemid describe it and
+// itemprop names its properties
+var microdata = syntax{
+	source:     SourceMicrodata,
+	isScope:    func(node *html.Node) bool { return hasAttribute(node, "itemscope") },
+	types:      func(node *html.Node) []string { return localNames(attribute(node, "itemtype")) },
+	id:         func(node *html.Node) string { return attribute(node, "itemid") },
+	properties: func(node *html.Node) []string { return localNames(attribute(node, "itemprop")) },
+}
+
+// RDFa Lite: typeof starts an entity, resource names it and property names
+// its properties. Prefixes are dropped rather than expanded, which is enough
+// for schema.org.
+var rdfaLite = syntax{
+	source:     SourceRDFa,
+	isScope:    func(node *html.Node) bool { return hasAttribute(node, "typeof") },
+	types:      func(node *html.Node) []string { return localNames(attribute(node, "typeof")) },
+	id:         func(node *html.Node) string { return attribute(node, "resource") },
+	properties: func(node *html.Node) []string { return localNames(attribute(node, "property")) },
+}
+
+// Extracts the Microdata and RDFa Lite entities of a document. URLs in
+// property values are resolved against base.
+func ExtractHTML(doc *html.Node, base *url.URL) []types.Entity {
+	entities := microdata.extract(doc, base)
+	return append(entities, rdfaLite.extract(doc, base)...)
+}
+
+// Collects the top-level entities of a document in one syntax
+func (markup syntax) extract(doc *html.Node, base *url.URL) []types.Entity {
+	var entities []types.Entity
+	markup.walk(doc, nil, base, &entities)
+	return entities
+}
+
+// Walks the children of a node, adding the properties found to the current
+// entity. An element starting an entity collects the properties beneath it
+// and becomes a property of the current entity, or a top-level entity when
+// it is not one.
+func (markup syntax) walk(node *html.Node, current *types.Entity, base *url.URL, entities *[]types.Entity) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		switch child.Data {
+		case "script", "style", "template":
+			continue
+		}
+
+		names := markup.properties(child)
+		if markup.isScope(child) {
+			entity := types.Entity{Source: markup.source, Types: markup.types(child), ID: resolve(base, markup.id(child))}
+			markup.walk(child, &entity, base, entities)
+			if current != nil && len(names) > 0 {
+				for _, name := range names {
+					nested := entity
+					addProperty(current, name, types.Property{Entity: &nested})
+				}
+			} else {
+				*entities = append(*entities, entity)
+			}
+			continue
+		}
+
+		if current != nil {
+			for _, name := range names {
+				addProperty(current, name, types.Property{Text: propertyValue(child, base)})
+			}
+		}
+		markup.walk(child, current, base, entities)
+	}
+}
+
+// Reads the value of a property element the way Microdata defines it, which
+// also covers RDFa Lite's content and resource attributes
+func propertyValue(node *html.Node, base *url.URL) string {
+	if hasAttribute(node, "content") {
+		return strings.TrimSpace(attribute(node, "content"))
+	}
+	switch node.Data {
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return resolve(base, attribute(node, "src"))
+	case "a", "area", "link":
+		return resolve(base, attribute(node, "href"))
+	case "object":
+		return resolve(base, attribute(node, "data"))
+	case "data", "meter":
+		return strings.TrimSpace(attribute(node, "value"))
+	case "time":
+		if hasAttribute(node, "datetime") {
+			return strings.TrimSpace(attribute(node, "datetime"))
+		}
+	}
+	if resource := attribute(node, "resource"); resource != "" {
+		return resolve(base, resource)
+	}
+	return textContent(node)
+}
+
+// Resolves a possibly relative URL, leaving it as it is if it does not parse
+func resolve(base *url.URL, reference string) string {
+	reference = strings.TrimSpace(reference)
+	if reference == "" || base == nil {
+		return reference
+	}
+	parsed, err := url.Parse(reference)
+	if err != nil {
+		return reference
+	}
+	return base.ResolveReference(parsed).String()
+}
+
+// Splits a space separated attribute into local names
+func localNames(value string) []string {
+	var names []string
+	for _, field := range strings.Fields(value) {
+		if name := LocalName(field); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Collects the normalized text of an element
+func textContent(node *html.Node) string {
+	var builder strings.Builder
+	var visit func(node *html.Node)
+	visit = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			builder.WriteString(node.Data)
+			builder.WriteByte(' ')
+			return
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(node)
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
+// Retrieves an attribute value case-insensitively
+func attribute(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if strings.EqualFold(attr.Key, name) {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// Reports whether an element carries an attribute, whatever its value
+func hasAttribute(node *html.Node, name string) bool {
+	for _, attr := range node.Attr {
+		if strings.EqualFold(attr.Key, name) {
+			return true
+		}
+	}
+	return false
+}
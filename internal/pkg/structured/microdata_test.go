@@ -0,0 +1,64 @@
+package structured
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"golang.org/x/net/html"
+)
+
+// TestExtractHTML tests Microdata and RDFa Lite entities, including nested ones and URL values.
+func TestExtractHTML(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><body>
+		<div itemscope itemtype="https://schema.org/Product" itemid="/p/42">
+			<h1 itemprop="name">Desk lamp</h1>
+			<img itemprop="image" src="/lamp.jpg">
+			<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+				<meta itemprop="priceCurrency" content="EUR">
+				<span itemprop="price">19.99</span>
+				<link itemprop="availability" href="https://schema.org/InStock">
+			</div>
+		</div>
+		<div vocab="https://schema.org/" typeof="Person" resource="#jane">
+			<span property="name">Jane <b>Doe</b></span>
+			<a property="url" href="/jane">Profile</a>
+			<time property="birthDate" datetime="1980-04-01">1 April</time>
+		</div>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://shop.example.com/lamps/")
+
+	entities := ExtractHTML(doc, base)
+	if len(entities) != 2 {
+		t.Fatalf("Expected 2 entities, got %d: %+v", len(entities), entities)
+	}
+
+	product := entities[0]
+	if product.Source != SourceMicrodata || product.Types[0] != "Product" || product.ID != "https://shop.example.com/p/42" {
+		t.Errorf("Unexpected product %+v", product)
+	}
+	if product.Properties["name"][0].Text != "Desk lamp" || product.Properties["image"][0].Text != "https://shop.example.com/lamp.jpg" {
+		t.Errorf("Unexpected product properties %+v", product.Properties)
+	}
+	offers := product.Properties["offers"]
+	if len(offers) != 1 || offers[0].Entity == nil {
+		t.Fatalf("Expected a nested offer, got %+v", offers)
+	}
+	offer := offers[0].Entity.Properties
+	if offer["price"][0].Text != "19.99" || offer["priceCurrency"][0].Text != "EUR" || offer["availability"][0].Text != "https://schema.org/InStock" {
+		t.Errorf("Unexpected offer %+v", offer)
+	}
+	if _, leaked := product.Properties["price"]; leaked {
+		t.Error("Expected the offer's properties to stay on the offer")
+	}
+
+	person := entities[1]
+	if person.Source != SourceRDFa || person.Types[0] != "Person" || person.ID != "https://shop.example.com/lamps/#jane" {
+		t.Errorf("Unexpected person %+v", person)
+	}
+	if person.Properties["name"][0].Text != "Jane Doe" || person.Properties["url"][0].Text != "https://shop.example.com/jane" || person.Properties["birthDate"][0].Text != "1980-04-01" {
+		t.Errorf("Unexpected person properties %+v", person.Properties)
+	}
+}
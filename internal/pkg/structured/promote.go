@@ -0,0 +1,244 @@
+package structured
+
+import (
+	"sort"
+	"strings"
+	"webcrawler/internal/pkg/dates"
+	"webcrawler/internal/pkg/types"
+)
+
+// Types whose dates and authors describe the page itself
+var articleTypes = map[string]struct{}{
+	"Article": {}, "NewsArticle": {}, "BlogPosting": {}, "TechArticle": {}, "ScholarlyArticle": {},
+	"Report": {}, "LiveBlogPosting": {}, "OpinionNewsArticle": {}, "ReportageNewsArticle": {},
+	"AnalysisNewsArticle": {}, "SocialMediaPosting": {}, "DiscussionForumPosting": {},
+}
+
+// Organization and its most common subtypes
+var organizationTypes = map[string]struct{}{
+	"Organization": {}, "Corporation": {}, "NewsMediaOrganization": {}, "LocalBusiness": {},
+	"OnlineBusiness": {}, "OnlineStore": {}, "EducationalOrganization": {}, "GovernmentOrganization": {},
+	"NGO": {}, "Store": {},
+}
+
+// Lifts well-known schema.org fields out of the page's entities: the first
+// article's dates and authors, the page's organization and its product.
+// Dates found elsewhere on the page are kept if they are trusted more.
+func Promote(pageData *types.PageData) {
+	index := indexEntities(pageData.Entities)
+
+	if article := findEntity(pageData.Entities, articleTypes); article != nil {
+		confidence := 0.9
+		if article.Source == SourceJSONLD {
+			confidence = 0.95 // Written for machines, so rarely wrong
+		}
+		if published, ok := dates.Parse(text(article, "datePublished")); ok {
+			dates.Offer(pageData, dates.Published, dates.Candidate{Time: published, Source: article.Source, Confidence: confidence})
+		}
+		if modified, ok := dates.Parse(text(article, "dateModified")); ok {
+			dates.Offer(pageData, dates.Modified, dates.Candidate{Time: modified, Source: article.Source, Confidence: confidence})
+		}
+		for _, author := range article.Properties["author"] {
+			if name := nameOf(author, index); name != "" {
+				pageData.Authors = append(pageData.Authors, name)
+			}
+		}
+		if publisher := entityOf(article, "publisher", index); publisher != nil {
+			pageData.Organization = organization(publisher, index)
+		}
+	}
+
+	// An organization the page describes on its own wins over an article's publisher
+	if found := findTopLevel(pageData.Entities, organizationTypes); found != nil {
+		pageData.Organization = organization(found, index)
+	}
+
+	if product := findEntity(pageData.Entities, map[string]struct{}{"Product": {}}); product != nil {
+		pageData.Product = productOf(product, index)
+	}
+}
+
+// Builds the organization record of an Organization entity
+func organization(entity *types.Entity, index map[string]*types.Entity) *types.Organization {
+	found := &types.Organization{
+		Name: text(entity, "name"),
+		URL:  text(entity, "url"),
+	}
+	if logo := entityOf(entity, "logo", index); logo != nil {
+		found.Logo = text(logo, "url")
+		if found.Logo == "" {
+			found.Logo = text(logo, "contentUrl")
+		}
+	} else {
+		found.Logo = text(entity, "logo")
+	}
+	for _, profile := range entity.Properties["sameAs"] {
+		if link := valueText(profile); link != "" {
+			found.SameAs = append(found.SameAs, link)
+		}
+	}
+	return found
+}
+
+// Builds the product record of a Product entity, taking the price from its
+// first offer or the lowest price of an aggregate offer
+func productOf(entity *types.Entity, index map[string]*types.Entity) *types.Product {
+	product := &types.Product{
+		Name: text(entity, "name"),
+		SKU:  text(entity, "sku"),
+	}
+	if len(entity.Properties["brand"]) > 0 {
+		product.Brand = nameOf(entity.Properties["brand"][0], index)
+	}
+	if offer := entityOf(entity, "offers", index); offer != nil {
+		product.Price = text(offer, "price")
+		if product.Price == "" {
+			product.Price = text(offer, "lowPrice")
+		}
+		if specification := entityOf(offer, "priceSpecification", index); specification != nil && product.Price == "" {
+			product.Price = text(specification, "price")
+			product.Currency = text(specification, "priceCurrency")
+		}
+		if currency := text(offer, "priceCurrency"); currency != "" {
+			product.Currency = currency
+		}
+		product.Availability = LocalName(text(offer, "availability"))
+	}
+	return product
+}
+
+// Maps the @id of every entity, nested ones included, to its entity so
+// references can be followed. Entities that are only references are left out.
+func indexEntities(entities []types.Entity) map[string]*types.Entity {
+	index := make(map[string]*types.Entity)
+	var add func(entity *types.Entity)
+	add = func(entity *types.Entity) {
+		if entity.ID != "" && (len(entity.Types) > 0 || len(entity.Properties) > 0) {
+			if _, exists := index[entity.ID]; !exists {
+				index[entity.ID] = entity
+			}
+		}
+		for _, values := range entity.Properties {
+			for _, value := range values {
+				if value.Entity != nil {
+					add(value.Entity)
+				}
+			}
+		}
+	}
+	for i := range entities {
+		add(&entities[i])
+	}
+	return index
+}
+
+// Finds the first entity, nested ones included, of one of the given types
+func findEntity(entities []types.Entity, wanted map[string]struct{}) *types.Entity {
+	var visit func(entity *types.Entity) *types.Entity
+	visit = func(entity *types.Entity) *types.Entity {
+		for _, entityType := range entity.Types {
+			if _, match := wanted[entityType]; match {
+				return entity
+			}
+		}
+		for _, name := range propertyNames(entity) {
+			for _, value := range entity.Properties[name] {
+				if value.Entity == nil {
+					continue
+				}
+				if found := visit(value.Entity); found != nil {
+					return found
+				}
+			}
+		}
+		return nil
+	}
+	// Top-level entities come first so a page's own article beats one it links to
+	if found := findTopLevel(entities, wanted); found != nil {
+		return found
+	}
+	for i := range entities {
+		if found := visit(&entities[i]); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Lists an entity's property names in order, so searches are repeatable
+func propertyNames(entity *types.Entity) []string {
+	names := make([]string, 0, len(entity.Properties))
+	for name := range entity.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Finds the first top-level entity of one of the given types
+func findTopLevel(entities []types.Entity, wanted map[string]struct{}) *types.Entity {
+	for i := range entities {
+		for _, entityType := range entities[i].Types {
+			if _, match := wanted[entityType]; match {
+				return &entities[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the entity a property holds, following a reference by @id
+func entityOf(entity *types.Entity, name string, index map[string]*types.Entity) *types.Entity {
+	for _, value := range entity.Properties[name] {
+		if value.Entity != nil {
+			return dereference(value.Entity, index)
+		}
+	}
+	return nil
+}
+
+// Follows a reference to the entity it names, if the page describes it
+func dereference(entity *types.Entity, index map[string]*types.Entity) *types.Entity {
+	if len(entity.Types) == 0 && len(entity.Properties) == 0 {
+		if described, exists := index[entity.ID]; exists {
+			return described
+		}
+	}
+	return entity
+}
+
+// Returns the name of a person or organization given as text or an entity
+func nameOf(value types.Property, index map[string]*types.Entity) string {
+	if value.Entity == nil {
+		return value.Text
+	}
+	entity := dereference(value.Entity, index)
+	if name := text(entity, "name"); name != "" {
+		return name
+	}
+	if given, family := text(entity, "givenName"), text(entity, "familyName"); given != "" || family != "" {
+		return strings.TrimSpace(given + " " + family)
+	}
+	return ""
+}
+
+// Returns the first text value of a property
+func text(entity *types.Entity, name string) string {
+	for _, value := range entity.Properties[name] {
+		if found := valueText(value); found != "" {
+			return found
+		}
+	}
+	return ""
+}
+
+// Returns a property's text, or the @id of an entity given where a URL was expected
+func valueText(value types.Property) string {
+	if value.Entity != nil {
+		if len(value.Entity.Types) == 0 && len(value.Entity.Properties) == 0 {
+			return value.Entity.ID
+		}
+		return ""
+	}
+	return value.Text
+}
@@ -0,0 +1,74 @@
+package structured
+
+import (
+	"testing"
+	"time"
+	"webcrawler/internal/pkg/types"
+)
+
+// TestPromote tests that article dates, authors, the publisher and a product are lifted out of the entities.
+func TestPromote(t *testing.T) {
+	article, err := ParseJSONLD(`{"@context": "https://schema.org", "@graph": [
+		{"@type": "BlogPosting", "datePublished": "2024-03-05", "dateModified": "2024-03-06T10:30:00+01:00",
+		 "author": [{"@id": "#jane"}, {"@type": "Person", "givenName": "John", "familyName": "Smith"}],
+		 "publisher": {"@id": "#org"}},
+		{"@type": "Person", "@id": "#jane", "name": "Jane Doe"},
+		{"@type": "Organization", "@id": "#org", "name": "Daily News", "url": "https://news.example.com/",
+		 "logo": {"@type": "ImageObject", "url": "https://news.example.com/logo.png"},
+		 "sameAs": ["https://twitter.com/dailynews", "https://www.facebook.com/dailynews"]}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product, err := ParseJSONLD(`{"@type": "Product", "name": "Desk lamp", "sku": "L-42", "brand": {"@type": "Brand", "name": "Lumo"},
+		"offers": {"@type": "AggregateOffer", "lowPrice": 19.99, "priceCurrency": "EUR", "availability": "https://schema.org/InStock"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pageData := types.PageData{Entities: append(article, product...)}
+	Promote(&pageData)
+
+	if expected := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC); !pageData.DatePublished.Equal(expected) {
+		t.Errorf("Expected published date %v, got %v", expected, pageData.DatePublished)
+	}
+	if expected := time.Date(2024, time.March, 6, 9, 30, 0, 0, time.UTC); !pageData.DateModified.Equal(expected) {
+		t.Errorf("Expected modified date %v, got %v", expected, pageData.DateModified)
+	}
+	if len(pageData.Authors) != 2 || pageData.Authors[0] != "Jane Doe" || pageData.Authors[1] != "John Smith" {
+		t.Errorf("Unexpected authors %v", pageData.Authors)
+	}
+
+	organization := pageData.Organization
+	if organization == nil || organization.Name != "Daily News" || organization.Logo != "https://news.example.com/logo.png" || len(organization.SameAs) != 2 {
+		t.Errorf("Unexpected organization %+v", organization)
+	}
+
+	expected := types.Product{Name: "Desk lamp", SKU: "L-42", Brand: "Lumo", Price: "19.99", Currency: "EUR", Availability: "InStock"}
+	if pageData.Product == nil || *pageData.Product != expected {
+		t.Errorf("Expected product %+v, got %+v", expected, pageData.Product)
+	}
+}
+
+// TestPromoteKeepsTrustedDates tests that only dates trusted less than the article's are replaced.
+func TestPromoteKeepsTrustedDates(t *testing.T) {
+	published := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	entities, err := ParseJSONLD(`{"@type": "Article", "datePublished": "2024-03-05", "dateModified": "2024-03-06"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageData := types.PageData{
+		Entities:      entities,
+		DatePublished: published,
+		PublishedFrom: types.DateSource{Source: "meta", Confidence: 0.99},
+		DateModified:  published,
+		ModifiedFrom:  types.DateSource{Source: "url", Confidence: 0.6},
+	}
+	Promote(&pageData)
+	if !pageData.DatePublished.Equal(published) || pageData.PublishedFrom.Source != "meta" {
+		t.Errorf("Expected the more trusted date to be kept, got %v from %+v", pageData.DatePublished, pageData.PublishedFrom)
+	}
+	if pageData.DateModified.Equal(published) || pageData.ModifiedFrom.Source != SourceJSONLD {
+		t.Errorf("Expected the less trusted date to be replaced, got %v from %+v", pageData.DateModified, pageData.ModifiedFrom)
+	}
+}
@@ -0,0 +1,68 @@
+package types
+
+import (
+	"net/http"
+	"time"
+)
+
+// Broad class of failure for a fetch, so callers can act on each class differently
+type ErrorCategory string
+
+const (
+	ErrorNone              ErrorCategory = ""
+	ErrorDNS               ErrorCategory = "dns"
+	ErrorConnect           ErrorCategory = "connect"
+	ErrorReset             ErrorCategory = "connection_reset"
+	ErrorTLS               ErrorCategory = "tls"
+	ErrorTimeout           ErrorCategory = "timeout"
+	ErrorRedirect          ErrorCategory = "redirect"
+	ErrorPendingRedirect   ErrorCategory = "pending_redirect" // Left for the administrator to follow, see FetchResult.RedirectTarget
+	ErrorHTTP4xx           ErrorCategory = "http_4xx"
+	ErrorHTTP5xx           ErrorCategory = "http_5xx"
+	ErrorRobots            ErrorCategory = "robots"
+	ErrorRobotsUnavailable ErrorCategory = "robots_unavailable" // robots.txt could not be fetched, so nothing may be crawled yet
+	ErrorProxy             ErrorCategory = "proxy"              // The proxy, not the site, failed
+	ErrorFiltered          ErrorCategory = "filtered"
+	ErrorParse             ErrorCategory = "parse"
+	ErrorUnknown           ErrorCategory = "unknown"
+)
+
+// A single hop in a redirect chain. For a meta refresh the status code is
+// that of the page carrying it.
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// Structured outcome of a single fetch, successful or not
+type FetchResult struct {
+	RequestedURL    string        `json:"requested_url"`
+	FinalURL        string        `json:"final_url"`
+	StatusCode      int           `json:"status_code"`
+	Header          http.Header   `json:"header,omitempty"`
+	RedirectChain   []Redirect    `json:"redirect_chain,omitempty"`
+	RedirectTarget  string        `json:"redirect_target,omitempty"` // Where a pending redirect or meta refresh points
+	ContentType     string        `json:"content_type"`
+	MediaType       string        `json:"media_type,omitempty"`     // Content type used to pick a handler, sniffed if the header was missing
+	Charset         string        `json:"charset,omitempty"`        // Encoding the body was decoded from
+	CharsetSource   string        `json:"charset_source,omitempty"` // bom, header, meta, statistical or default
+	BytesRead       int64         `json:"bytes_read"`               // Decoded body size
+	WireBytes       int64         `json:"wire_bytes"`               // Body size as received, before decompression
+	ContentEncoding string        `json:"content_encoding,omitempty"`
+	Truncated       bool          `json:"truncated"`
+	Rendered        bool          `json:"rendered,omitempty"` // Content came from headless Chrome
+	ErrorCategory   ErrorCategory `json:"error_category,omitempty"`
+	Error           string        `json:"error,omitempty"`
+	PolicyRule      string        `json:"policy_rule,omitempty"` // The content policy rule that rejected the page
+	Duration        time.Duration `json:"duration"`
+	DNSDuration     time.Duration `json:"dns_duration"`         // Time spent resolving the host, part of Duration
+	DNSCached       bool          `json:"dns_cached,omitempty"` // The host's addresses were already known
+	Protocol        string        `json:"protocol,omitempty"`   // e.g. HTTP/1.1 or HTTP/2.0
+	ConnReused      bool          `json:"conn_reused"`          // The final request went over an existing connection
+}
+
+// Reports whether the fetch completed without error
+func (result FetchResult) OK() bool {
+	return result.ErrorCategory == ErrorNone
+}
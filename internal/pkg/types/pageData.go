@@ -4,25 +4,175 @@ import "time"
 
 // Data structure to organize and store relevant information from the page
 type PageData struct {
-    URL             string              `json:"url"`
+    URL             string              `json:"url"`                         // Where the page was fetched from, after redirects
+    RequestedURL    string              `json:"requested_url"`
+    RedirectChain   []Redirect          `json:"redirect_chain,omitempty"`
     CanonicalURL    string              `json:"canonical_url"`
+    RefreshURL      string              `json:"refresh_url,omitempty"`       // Target of a <meta http-equiv="refresh">
+    RefreshDelay    time.Duration       `json:"refresh_delay,omitempty"`
+    Alternates      []Alternate         `json:"alternates,omitempty"`        // Translations declared with hreflang
+    Robots          RobotsDirectives    `json:"robots"`                      // From robots meta tags and X-Robots-Tag
     Title           string              `json:"title"`
     Charset         string              `json:"charset"`
+    MediaType       string              `json:"media_type"`
     MetaDescription string              `json:"meta_description"`
     MetaKeywords    string              `json:"meta_keywords"`
-    Language        string              `json:"language"`
+    Ratings         []string            `json:"ratings,omitempty"`           // From <meta name="rating">
+    Language        string              `json:"language"`                    // Identified from the text and declarations, e.g. "en"
+    LangConfidence  float64             `json:"language_confidence"`
+    DeclaredLang    string              `json:"declared_language,omitempty"` // As given by <html lang> or the document's metadata
     Headings        map[string][]string `json:"headings"`
     AltTexts        []string            `json:"alt_texts"`
     AnchorTexts     []string            `json:"anchor_texts"`
     InternalLinks   []string            `json:"internal_links"`
     ExternalLinks   []string            `json:"external_links"`
-    StructuredData  []string            `json:"structured_data"`
+    Outlinks        []Outlink           `json:"outlinks,omitempty"`          // Every link with the context it appeared in
+    Assets          []Asset             `json:"assets,omitempty"`            // Images, media, icons, feeds, stylesheets and scripts the page uses
+    StructuredData  []string            `json:"structured_data"`             // Raw JSON-LD blocks
+    Entities        []Entity            `json:"entities,omitempty"`          // From JSON-LD, Microdata and RDFa Lite
+    JSONLDErrors    []string            `json:"json_ld_errors,omitempty"`    // Why JSON-LD blocks gave no entities
+    Authors         []string            `json:"authors,omitempty"`           // Promoted from the entities, as are Organization and Product
+    Organization    *Organization       `json:"organization,omitempty"`
+    Product         *Product            `json:"product,omitempty"`
     OpenGraph       map[string]string   `json:"open_graph"`
     DatePublished   time.Time           `json:"date_published"`
+    PublishedFrom   DateSource          `json:"date_published_source"`       // Where the date was found and how far it is trusted
     DateModified    time.Time           `json:"date_modified"`
-    SocialLinks     []string            `json:"social_links"`
+    ModifiedFrom    DateSource          `json:"date_modified_source"`
+    SocialLinks     []string            `json:"social_links"`                // Links to any platform of the social catalogue
+    Contacts        Contacts            `json:"contacts"`
     VisibleText     string              `json:"visible_text"`
+    MainText        string              `json:"main_text"`                   // The article without navigation, banners and footers
+    Byline          string              `json:"byline,omitempty"`
+    LeadImage       string              `json:"lead_image,omitempty"`
+    PolicyTags      []string            `json:"policy_tags,omitempty"`       // Added by tag rules of the content policy
+    PolicyRules     []string            `json:"policy_rules,omitempty"`      // Every content policy rule that matched
+    PolicyNoFollow  bool                `json:"policy_nofollow,omitempty"`   // A nofollow rule matched, so links are not queued
     LoadTime        time.Duration       `json:"load_time"`
     IsSecure        bool                `json:"is_secure"`
-    FetchError      string              `json:"fetch_error"`
+}
+
+// Where one of a page's dates was found: json-ld, microdata, rdfa, meta,
+// feed, time, url or header. Confidence runs from 0 to 1.
+type DateSource struct {
+    Source     string  `json:"source,omitempty"`
+    Confidence float64 `json:"confidence,omitempty"`
+}
+
+// A language or regional version of a page, from <link rel="alternate" hreflang>
+type Alternate struct {
+    Language string `json:"hreflang"` // Lower cased, e.g. "en-gb" or "x-default"
+    URL      string `json:"url"`
+}
+
+// A link from a page to another document, with the context it appeared in.
+// Links come from <a>, <area>, <iframe>, <link rel="next"> and <link rel="prev">,
+// and the candidates of srcset attributes.
+type Outlink struct {
+    URL      string   `json:"url"`
+    Kind     string   `json:"kind"`                // a, area, iframe, link or srcset
+    Text     string   `json:"text,omitempty"`      // Anchor text, or the alt text of an <area>
+    Title    string   `json:"title,omitempty"`
+    Rels     []string `json:"rels,omitempty"`      // Lower cased
+    Position string   `json:"position"`            // head, nav, header, main, aside, footer or body
+    ImageAlt string   `json:"image_alt,omitempty"` // Alt text of an image the anchor wraps
+    Internal bool     `json:"internal"`            // On the same host as the page
+}
+
+// Reports whether the link is marked nofollow, ugc or sponsored, so it should
+// not be followed or credited
+func (outlink Outlink) NoFollow() bool {
+    for _, rel := range outlink.Rels {
+        switch rel {
+        case "nofollow", "ugc", "sponsored":
+            return true
+        }
+    }
+    return false
+}
+
+// Kinds of asset a page uses
+const (
+    AssetImage      = "image"
+    AssetVideo      = "video"
+    AssetAudio      = "audio"
+    AssetIcon       = "icon"
+    AssetFeed       = "feed"
+    AssetStylesheet = "stylesheet"
+    AssetScript     = "script"
+)
+
+// A file a page uses or points to besides its links: an image, video or
+// audio source, icon, feed, stylesheet or script. Fetched assets are
+// described by a single Asset of their own.
+type Asset struct {
+    URL       string   `json:"url"`
+    Type      string   `json:"type"`                 // One of the Asset constants
+    MediaType string   `json:"media_type,omitempty"` // As declared by the page, or as served when fetched
+    Alt       string   `json:"alt,omitempty"`
+    Title     string   `json:"title,omitempty"`      // Of a feed
+    Width     int      `json:"width,omitempty"`      // In pixels, from attributes, icon sizes or the image itself
+    Height    int      `json:"height,omitempty"`
+    Srcset    []string `json:"srcset,omitempty"`     // The other candidates of a responsive image
+}
+
+// Ways to reach the people or organization behind a page, from its text,
+// mailto: and tel: links, links to social platforms and structured data
+type Contacts struct {
+    Emails    []string        `json:"emails,omitempty"`    // Lower cased
+    Phones    []string        `json:"phones,omitempty"`    // E.164, e.g. "+442079460000"
+    Addresses []string        `json:"addresses,omitempty"` // PostalAddress entities, one line each
+    Profiles  []SocialProfile `json:"profiles,omitempty"`
+}
+
+// An account on a social platform
+type SocialProfile struct {
+    Platform string `json:"platform"` // As named by the social catalogue, e.g. "github" or "mastodon"
+    Handle   string `json:"handle"`
+    URL      string `json:"url"`
+}
+
+// Indexing directives from robots meta tags and the X-Robots-Tag header. When
+// several sources disagree the most restrictive wins.
+type RobotsDirectives struct {
+    NoIndex          bool      `json:"noindex,omitempty"`
+    NoFollow         bool      `json:"nofollow,omitempty"`
+    NoArchive        bool      `json:"noarchive,omitempty"`
+    NoSnippet        bool      `json:"nosnippet,omitempty"`
+    MaxSnippet       *int      `json:"max_snippet,omitempty"` // Characters, -1 for no limit
+    UnavailableAfter time.Time `json:"unavailable_after,omitempty"`
+}
+
+// A thing described by structured data, such as an Article or a Person.
+// Types and property names are given without their vocabulary, so
+// "https://schema.org/Article" becomes "Article".
+type Entity struct {
+    Types      []string              `json:"types,omitempty"`
+    ID         string                `json:"id,omitempty"`     // @id, itemid or resource
+    Source     string                `json:"source"`           // json-ld, microdata or rdfa
+    Properties map[string][]Property `json:"properties,omitempty"`
+}
+
+// A property value, either text or another entity
+type Property struct {
+    Text   string  `json:"text,omitempty"`
+    Entity *Entity `json:"entity,omitempty"`
+}
+
+// The organization behind a page, from an Organization entity or an article's publisher
+type Organization struct {
+    Name   string   `json:"name"`
+    URL    string   `json:"url,omitempty"`
+    Logo   string   `json:"logo,omitempty"`
+    SameAs []string `json:"same_as,omitempty"` // Its profiles elsewhere
+}
+
+// The product a page offers, with the price of its first offer
+type Product struct {
+    Name         string `json:"name"`
+    SKU          string `json:"sku,omitempty"`
+    Brand        string `json:"brand,omitempty"`
+    Price        string `json:"price,omitempty"`    // As written, e.g. "19.99"
+    Currency     string `json:"currency,omitempty"` // ISO 4217, e.g. "EUR"
+    Availability string `json:"availability,omitempty"`
 }
@@ -19,6 +19,18 @@ func GetDomainFromURL(inputURL string) (string, error) {
 	return strings.TrimPrefix(parsedURL.Hostname(), "www."), nil
 }
 
+// Extracts the hostname from a URL, keeping any www prefix.
+func GetHostnameFromURL(inputURL string) (string, error) {
+	if !strings.HasPrefix(inputURL, "http://") && !strings.HasPrefix(inputURL, "https://") {
+        inputURL = "https://" + inputURL
+    }
+	parsedURL, err := url.Parse(inputURL)
+	if err != nil {
+		return "", errors.New("error parsing URL")
+	}
+	return parsedURL.Hostname(), nil
+}
+
 // Constructs the full URL from a short URL.
 func BuildFullUrl(shortUrl string) (string, error) {
     // Prepend scheme if missing
@@ -15,6 +15,16 @@ func TestGetDomainFromURL(t *testing.T) {
     }
 }
 
+func TestGetHostnameFromURL(t *testing.T) {
+    hostname, err := GetHostnameFromURL("www.example.com:8080/test")
+    if err != nil {
+        t.Fatalf("GetHostnameFromURL returned error: %v", err)
+    }
+    if hostname != "www.example.com" {
+        t.Errorf("Expected hostname 'www.example.com', got '%s'", hostname)
+    }
+}
+
 func TestBuildFullUrl(t *testing.T) {
     shortUrl := "example.com/test"
     fullUrl, err := BuildFullUrl(shortUrl)